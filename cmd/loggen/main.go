@@ -2,9 +2,9 @@ package main
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"os"
-	"path"
 	"strings"
 	"time"
 
@@ -24,22 +24,22 @@ func main() {
 		return
 	}
 
-	// Setting up the logger
-	log, logFlushFn := logging.New("loggen", zerolog.TraceLevel)
-	defer logFlushFn()
-
+	// Setting up the logger writing to the output file, which is truncated first
 	outPath := cliParams.OutputPath
-	if err := os.MkdirAll(path.Dir(outPath), os.ModePerm); err != nil {
-		fmt.Printf("log path directory %q creation failed", outPath)
+	if err := os.Remove(outPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("Log file truncation on path %q failed: %s", outPath, err.Error())
 		return
 	}
-	outFile, err := os.Create(outPath)
+	log, logFlushFn, err := logging.NewFile("loggen", zerolog.TraceLevel, logging.RotatingFileConfig{
+		Path:       outPath,
+		MaxSize:    cliParams.MaxSize,
+		MaxBackups: cliParams.MaxBackups,
+	})
 	if err != nil {
 		fmt.Printf("Log file creation on path %q failed: %s", outPath, err.Error())
 		return
 	}
-	defer outFile.Close()
-	log = log.Output(outFile)
+	defer logFlushFn()
 
 	// Generate the logs
 	loremIpsumSentences := strings.Split(loremIpsum, "\n")
@@ -10,4 +10,6 @@ type params struct {
 	LogCount      uint          `flag:"n|Number of lines that should be generated||required"`
 	OutputPath    string        `flag:"o|Path to the output file|./output.log||required"`
 	SleepDuration time.Duration `flag:"i|Sleep interval between the individual logs"`
+	MaxSize       int64         `flag:"maxsize|Size in bytes over which the output file is rotated"`
+	MaxBackups    int           `flag:"maxbackups|Number of the rotated output files kept"`
 }
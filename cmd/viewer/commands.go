@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/matusvla/logviewer/internal/config"
+)
+
+// commands are the known subcommands, the words following them are their arguments
+var commands = []string{"config dump", "cat", "tail", "grep", "serve", "attach", "listen"}
+
+// splitArgs separates the CLI arguments into the subcommand, its arguments and the flags, which can be mixed with the arguments.
+// The boolFlags are the names of the flags which take no value. All the arguments following "--" are considered to be the arguments of the subcommand.
+// If the words do not start with a known subcommand, all of them are returned as the arguments as well.
+func splitArgs(args []string, boolFlags map[string]bool) (command string, cmdArgs []string, flags []string) {
+	var words []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			words = append(words, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			flags = append(flags, arg)
+			name := strings.TrimLeft(arg, "-")
+			if !strings.Contains(name, "=") && !boolFlags[name] && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		default:
+			words = append(words, arg)
+		}
+	}
+	for _, cmd := range commands {
+		cmdWords := strings.Fields(cmd)
+		if len(words) >= len(cmdWords) && strings.Join(words[:len(cmdWords)], " ") == cmd {
+			return cmd, words[len(cmdWords):], flags
+		}
+	}
+	return strings.Join(words, " "), words, flags
+}
+
+// boolFlags returns the names of the boolean flags of the params (see the easyflag field tags) and of the help flags.
+func boolFlags(params interface{}) map[string]bool {
+	names := map[string]bool{"h": true, "help": true}
+	t := reflect.TypeOf(params)
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if tag, ok := fld.Tag.Lookup("flag"); ok && fld.Type.Kind() == reflect.Bool {
+			names[strings.SplitN(tag, "|", 2)[0]] = true
+		}
+	}
+	return names
+}
+
+func runConfigDump(cfg *config.Config) {
+	fmt.Printf("# effective configuration, config file: %s\n", cfg.Path())
+	if err := cfg.Dump(os.Stdout); err != nil {
+		fmt.Printf("configuration dumping failed: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantCommand string
+		wantCmdArgs []string
+		wantFlags   []string
+	}{
+		{
+			name:      "no command",
+			args:      []string{"-theme", "light"},
+			wantFlags: []string{"-theme", "light"},
+		},
+		{
+			name:        "two word command",
+			args:        []string{"config", "dump", "-config", "x.yaml"},
+			wantCommand: "config dump",
+			wantCmdArgs: []string{},
+			wantFlags:   []string{"-config", "x.yaml"},
+		},
+		{
+			name:        "flags mixed with the arguments",
+			args:        []string{"tail", "-f", "app.log", "-n", "5", "--level=warn"},
+			wantCommand: "tail",
+			wantCmdArgs: []string{"app.log"},
+			wantFlags:   []string{"-f", "-n", "5", "--level=warn"},
+		},
+		{
+			name:        "stdin and double dash",
+			args:        []string{"grep", "-field", "a=b", "--", "-pattern", "-"},
+			wantCommand: "grep",
+			wantCmdArgs: []string{"-pattern", "-"},
+			wantFlags:   []string{"-field", "a=b"},
+		},
+		{
+			name:        "unknown command",
+			args:        []string{"show", "app.log"},
+			wantCommand: "show app.log",
+			wantCmdArgs: []string{"show", "app.log"},
+		},
+		{
+			name:        "files of the web UI",
+			args:        []string{"-web", "a.log", "-addr", ":8080", "b.log"},
+			wantCommand: "a.log b.log",
+			wantCmdArgs: []string{"a.log", "b.log"},
+			wantFlags:   []string{"-web", "-addr", ":8080"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, cmdArgs, flags := splitArgs(tt.args, boolFlags(params{}))
+			assert.Equal(t, tt.wantCommand, command)
+			assert.Equal(t, tt.wantCmdArgs, cmdArgs)
+			assert.Equal(t, tt.wantFlags, flags)
+		})
+	}
+}
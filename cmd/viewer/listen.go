@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/matusvla/logviewer/internal/collector"
+	"github.com/matusvla/logviewer/internal/config"
+	"github.com/rs/zerolog"
+)
+
+const defaultSpoolName = "logviewer-spool.log"
+
+// startListening starts receiving the log records on the addresses given by the tcp, udp and http flags and appending
+// them to the spool file. It returns the path of the spool file and the function stopping the listening.
+func startListening(log zerolog.Logger, cliParams params, cfg *config.Config) (string, func(), error) {
+	if cliParams.TCP == "" && cliParams.UDP == "" && cliParams.HTTP == "" {
+		return "", nil, errors.New("no address to listen on, usage: viewer listen [-tcp :5170] [-udp :5171] [-http :5172] [-spool FILE]")
+	}
+	spoolPath := cliParams.Spool
+	if spoolPath == "" {
+		spoolPath = filepath.Join(os.TempDir(), defaultSpoolName)
+	}
+	spool, err := os.OpenFile(spoolPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return "", nil, err
+	}
+	c := collector.New(log.With().Str("component", "collector").Logger(), spool, cfg.FieldMapping)
+
+	var servers []func(ctx context.Context) error
+	closers := []io.Closer{spool}
+	fail := func(err error) (string, func(), error) {
+		for _, closer := range closers {
+			_ = closer.Close()
+		}
+		return "", nil, err
+	}
+	if cliParams.TCP != "" {
+		ln, err := net.Listen("tcp", cliParams.TCP)
+		if err != nil {
+			return fail(fmt.Errorf("tcp: %w", err))
+		}
+		closers = append(closers, ln)
+		servers = append(servers, func(ctx context.Context) error { return c.ServeTCP(ctx, ln) })
+	}
+	if cliParams.UDP != "" {
+		conn, err := net.ListenPacket("udp", cliParams.UDP)
+		if err != nil {
+			return fail(fmt.Errorf("udp: %w", err))
+		}
+		closers = append(closers, conn)
+		servers = append(servers, func(ctx context.Context) error { return c.ServeUDP(ctx, conn) })
+	}
+	if cliParams.HTTP != "" {
+		ln, err := net.Listen("tcp", cliParams.HTTP)
+		if err != nil {
+			return fail(fmt.Errorf("http: %w", err))
+		}
+		srv := &http.Server{Handler: c}
+		servers = append(servers, func(ctx context.Context) error {
+			go func() {
+				<-ctx.Done()
+				_ = srv.Close()
+			}()
+			if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, serve := range servers {
+		serve := serve
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := serve(ctx); err != nil {
+				log.Error().Err(err).Msg("receiving the log records failed")
+			}
+		}()
+	}
+	return spoolPath, func() {
+		cancelFn()
+		wg.Wait()
+		_ = spool.Close()
+	}, nil
+}
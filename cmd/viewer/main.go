@@ -4,52 +4,153 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/matusvla/easyflag"
+	"github.com/matusvla/logviewer/internal/config"
 	"github.com/matusvla/logviewer/internal/viewer"
 	"github.com/matusvla/logviewer/pkg/logging"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
 	"github.com/rs/zerolog"
 )
 
 func main() {
 	// CLI flags loading and processing
+	command, cmdArgs, flagArgs := splitArgs(os.Args[1:], boolFlags(params{}))
+	os.Args = append(os.Args[:1], flagArgs...) // easyflag parses the os.Args
 	var cliParams params
 	if err := easyflag.ParseAndLoad(&cliParams); err != nil {
-		fmt.Printf("CLI flags parsing failed: %s", err.Error())
+		fmt.Printf("CLI flags parsing failed: %s\n", err.Error())
 		os.Exit(1)
 	}
 
-	// Setting up the viewer's logger
-	logLevel, err := zerolog.ParseLevel(cliParams.LogLevel)
-	if err != nil {
-		fmt.Printf("invalid log level %q", cliParams.LogLevel)
+	// Loading the configuration - defaults, config file, environment variables and CLI flags
+	cfg := loadConfig(cliParams)
+
+	if cliParams.Web {
+		for _, cmd := range commands {
+			if command == cmd {
+				fmt.Printf("the web flag cannot be used with the %s command\n", command)
+				os.Exit(1)
+			}
+		}
+		runWeb(cmdArgs, cliParams, cfg)
+		return
+	}
+
+	var attachURL string
+	switch command {
+	case "":
+	case "attach":
+		if len(cmdArgs) != 1 {
+			fmt.Println("usage: viewer attach [-token TOKEN] http://HOST:7070")
+			os.Exit(1)
+		}
+		attachURL = cmdArgs[0]
+	case "listen":
+		if len(cmdArgs) != 0 {
+			fmt.Println("usage: viewer listen [-tcp :5170] [-udp :5171] [-http :5172] [-spool FILE]")
+			os.Exit(1)
+		}
+	case "serve":
+		runServe(cmdArgs, cliParams, cfg)
+		return
+	case "config dump":
+		runConfigDump(cfg)
+		return
+	case "cat", "tail", "grep":
+		runPrint(command, cmdArgs, cliParams, cfg)
+		return
+	default:
+		fmt.Printf("unknown command %q, available commands are: %s\n", command, strings.Join(commands, ", "))
 		os.Exit(1)
 	}
-	log, logFlushFn := logging.New("viewer", logLevel)
-	defer logFlushFn()
+
+	// Setting up the viewer's logger writing to the log file, the terminal is used by the viewer itself
+	logLevel, _ := zerolog.ParseLevel(cfg.LogLevel) // validated by the config
+	logOpts := []logging.Option{logging.WithExitCode(2)}
 	if logLevel != zerolog.NoLevel {
-		if err := os.MkdirAll(path.Dir(cliParams.LogPath), os.ModePerm); err != nil {
-			log.Fatal().Err(err).Msg("log path directory creation failed")
+		if err := os.MkdirAll(path.Dir(cfg.LogPath), os.ModePerm); err != nil {
+			fmt.Printf("log path directory creation failed: %s\n", err.Error())
 			os.Exit(1)
 		}
-		logFile, err := os.Create(cliParams.LogPath)
+		logFile, err := os.Create(cfg.LogPath)
 		if err != nil {
-			log.Fatal().Err(err).Msg("log file creation failed")
+			fmt.Printf("log file creation failed: %s\n", err.Error())
 			os.Exit(1)
 		}
-		defer logFile.Close()
-		log = log.Output(logFile)
+		logOpts = append(logOpts, logging.WithWriteCloser(logFile))
 	}
+	log, logFlushFn := logging.New("viewer", logLevel, logOpts...)
+	defer logFlushFn()
+
+	// Setting up the color theme
+	th, err := cfg.ResolveTheme()
+	if err != nil {
+		fmt.Printf("invalid theme: %s\n", err.Error())
+		os.Exit(1)
+	}
+	th.ColorMode = theme.DetectColorMode()
 
 	// Running the log viewer
-	v, err := viewer.New(log, cliParams.LogPath)
+	v, err := viewer.New(log, cfg.LogPath, th, cfg)
 	if err != nil {
-		log.Fatal().Err(err).Msg("viewer setup failed")
+		fmt.Printf("viewer setup failed: %s\n", err.Error())
 		os.Exit(1)
 	}
+	if attachURL != "" {
+		v.Attach(attachURL, resolveToken(cliParams))
+	}
+	if command == "listen" {
+		spoolPath, stopFn, err := startListening(log, cliParams, cfg)
+		if err != nil {
+			fmt.Printf("viewer listen: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer stopFn()
+		v.Follow(spoolPath)
+	}
 	if err := v.Run(); err != nil {
-		log.Fatal().Err(err).Msg("viewer running failed")
-		os.Exit(2)
+		log.Fatal().Err(err).Msg("viewer running failed") // exits with the code 2
+		os.Exit(2)                                        // if the logging is disabled
 	}
 	log.Info().Msg("viewer ended")
 }
+
+func loadConfig(cliParams params) *config.Config {
+	cfgPath := cliParams.Config
+	if cfgPath == "" {
+		cfgPath = os.Getenv(config.EnvPath)
+	}
+	if cfgPath == "" {
+		var err error
+		if cfgPath, err = config.DefaultPath(); err != nil {
+			fmt.Printf("config file path resolution failed: %s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		fmt.Printf("configuration loading failed: %s\n", err.Error())
+		os.Exit(1)
+	}
+	envOverrides, err := config.EnvOverrides()
+	if err != nil {
+		fmt.Printf("invalid environment variable %s\n", err.Error())
+		os.Exit(1)
+	}
+	cfg.Override(envOverrides)
+	cfg.Override(cliParams.overrides())
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("invalid configuration (%s):\n", cfgPath)
+		if validationErr, ok := err.(*config.ValidationError); ok {
+			for _, problem := range validationErr.Problems {
+				fmt.Printf("  %s\n", problem)
+			}
+		} else {
+			fmt.Printf("  %s\n", err.Error())
+		}
+		os.Exit(1)
+	}
+	return cfg
+}
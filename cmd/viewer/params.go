@@ -1,7 +1,57 @@
 package main
 
+import (
+	"time"
+
+	"github.com/matusvla/logviewer/internal/config"
+)
+
+// The flags have no default values, so that they override the values from the configuration file and the environment only when set.
 type params struct {
 	// cli.BuildVersionFlag
-	LogLevel string `flag:"loglevel|path to a log file of the viewer - for debugging purposes|"`
-	LogPath  string `flag:"logpath|path to log file|./viewer.log"` // todo this is probably not needed at startup
+	LogLevel       string        `flag:"loglevel|level of the viewer's own logs - for debugging purposes|"`
+	LogPath        string        `flag:"logpath|path to log file (default ./viewer.log)|"` // todo this is probably not needed at startup
+	Theme          string        `flag:"theme|color theme (dark, light, solarized, monochrome, a theme from the config file) or path to a theme file (default dark)|"`
+	Keymap         string        `flag:"keymap|keymap preset - default, vim or less (default default)|"`
+	Config         string        `flag:"config|path to the configuration file (default $LOGVIEWER_CONFIG or ~/.config/logviewer/config.yaml)|"`
+	CallerWidth    int           `flag:"callerwidth|number of characters to which the caller is shortened (default 30)|"`
+	FollowInterval time.Duration `flag:"followinterval|period of checking a followed file for new records (default 500ms)|"`
+	Level          string        `flag:"level|level filter set when a file is opened, minimal level of the records printed by cat, tail and grep (default trace)|"`
+	FieldOrder     string        `flag:"fieldorder|order of the extra fields - original or alphabetical (default original)|"`
+	TimeFormat     string        `flag:"timeformat|layout of the record times - time, datetime, rfc3339 or epoch (default time)|"`
+	TimeZone       string        `flag:"timezone|time zone of the record times - local, UTC, offset (e.g. +02:00) or IANA name (default the records' own)|"`
+
+	// the flags of the cat, tail and grep commands
+	Follow bool   `flag:"f|keep printing the records appended to the file (tail)"`
+	Lines  int    `flag:"n|number of the last records printed (tail, default 10)|"`
+	Since  string `flag:"since|print only the records logged since the time - RFC 3339 time, date (2006-01-02) or duration before now (e.g. 1h)|"`
+	Until  string `flag:"until|print only the records logged before the time - same format as since|"`
+	Field  string `flag:"field|print only the records with the field values, e.g. user=bob,status=500|"`
+	Color  string `flag:"color|colors of the printed records - auto, always or never (default auto - only if printing to a terminal)|"`
+
+	// the flags of the serve and attach commands and of the web UI
+	Web   bool   `flag:"web|serve the files given as the arguments with the web UI instead of starting the terminal UI"`
+	Addr  string `flag:"addr|address the log files are served on (serve and web, default :7070)|"`
+	Token string `flag:"token|token authorizing the access to the served log files (serve, web and attach, default $LOGVIEWER_TOKEN, serve generates one if not set)|"`
+
+	// the flags of the listen command
+	TCP   string `flag:"tcp|address the log records are received on over TCP (listen), e.g. :5170|"`
+	UDP   string `flag:"udp|address the log records are received on over UDP (listen), e.g. :5171|"`
+	HTTP  string `flag:"http|address the log records are received on over HTTP POST requests (listen), e.g. :5172|"`
+	Spool string `flag:"spool|file the received records are appended to (listen, default logviewer-spool.log in the temporary directory)|"`
+}
+
+func (p params) overrides() config.Overrides {
+	return config.Overrides{
+		LogLevel:        p.LogLevel,
+		LogPath:         p.LogPath,
+		Theme:           p.Theme,
+		Keymap:          p.Keymap,
+		CallerWidth:     p.CallerWidth,
+		FollowInterval:  p.FollowInterval,
+		DefaultLevel:    p.Level,
+		FieldOrder:      p.FieldOrder,
+		TimestampFormat: p.TimeFormat,
+		TimeZone:        p.TimeZone,
+	}
 }
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/matusvla/logviewer/internal/config"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+	"github.com/matusvla/logviewer/pkg/logstore"
+	"github.com/rs/zerolog"
+)
+
+// exit codes of the cat, tail and grep commands
+const (
+	exitNoMatch = 1 // grep found no matching record
+	exitError   = 2
+)
+
+const defaultTailLines = 10
+
+// stdinPath is the file argument meaning the standard input
+const stdinPath = "-"
+
+// printer pretty-prints the log records to the stdout without starting the TUI.
+// The lines, which are not log records, are printed as they are unless they are filtered out.
+type printer struct {
+	out    prettyprint.Output
+	th     theme.Theme
+	buf    *bytes.Buffer // rendered record
+	filter prettyprint.RecordFilter
+	level  zerolog.Level
+	w      *bufio.Writer
+
+	followInterval time.Duration
+	printed        bool
+}
+
+func runPrint(command string, args []string, cliParams params, cfg *config.Config) {
+	p, err := newPrinter(cliParams, cfg)
+	if err == nil && command == "grep" {
+		if len(args) == 0 {
+			err = errors.New("missing pattern, usage: viewer grep PATTERN [FILE...]")
+		} else {
+			p.filter.Pattern, err = regexp.Compile(args[0])
+			args = args[1:]
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "viewer %s: %s\n", command, err.Error())
+		os.Exit(exitError)
+	}
+	p.out = cfg.NewOutput(p.buf, p.level, p.th).
+		WithHighlightRules(cfg.HighlightRules()...).
+		WithFilter(p.filter)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	switch command {
+	case "tail":
+		if len(args) > 1 {
+			err = errors.New("tail accepts at most one file")
+			break
+		}
+		lines := cliParams.Lines
+		if lines <= 0 {
+			lines = defaultTailLines
+		}
+		err = p.tail(ctx, append(args, stdinPath)[0], lines, cliParams.Follow)
+	default: // cat and grep
+		err = p.cat(args)
+	}
+	if flushErr := p.w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "viewer %s: %s\n", command, err.Error())
+		os.Exit(exitError)
+	}
+	if command == "grep" && !p.printed {
+		os.Exit(exitNoMatch)
+	}
+}
+
+func newPrinter(cliParams params, cfg *config.Config) (*printer, error) {
+	th, err := cfg.ResolveTheme()
+	if err != nil {
+		return nil, fmt.Errorf("invalid theme: %w", err)
+	}
+	switch cliParams.Color {
+	case "", "auto":
+		th.ColorMode = theme.DetectColorMode()
+		if !theme.IsTerminal(os.Stdout) {
+			th.ColorMode = theme.ColorModeNone
+		}
+	case "always":
+		if th.ColorMode = theme.DetectColorMode(); th.ColorMode == theme.ColorModeNone {
+			th.ColorMode = theme.ColorMode16
+		}
+	case "never":
+		th.ColorMode = theme.ColorModeNone
+	default:
+		return nil, fmt.Errorf("invalid color %q, expected auto, always or never", cliParams.Color)
+	}
+
+	var filter prettyprint.RecordFilter
+	now := time.Now()
+	if filter.Since, err = prettyprint.ParseFilterTime(cliParams.Since, now); err != nil {
+		return nil, fmt.Errorf("since: %w", err)
+	}
+	if filter.Until, err = prettyprint.ParseFilterTime(cliParams.Until, now); err != nil {
+		return nil, fmt.Errorf("until: %w", err)
+	}
+	if filter.Fields, err = prettyprint.ParseFilterFields(cliParams.Field); err != nil {
+		return nil, fmt.Errorf("field: %w", err)
+	}
+
+	return &printer{
+		th:             th,
+		buf:            &bytes.Buffer{},
+		filter:         filter,
+		level:          cfg.DefaultLevel(),
+		w:              bufio.NewWriter(os.Stdout),
+		followInterval: cfg.Rendering.FollowInterval,
+	}, nil
+}
+
+// cat prints the records of all the files, the standard input is read if there are none.
+// The files which cannot be read are reported and skipped.
+func (p *printer) cat(paths []string) error {
+	if len(paths) == 0 {
+		paths = []string{stdinPath}
+	}
+	var failed int
+	for _, path := range paths {
+		if err := p.catFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be read", failed, len(paths))
+	}
+	return nil
+}
+
+func (p *printer) catFile(path string) error {
+	f, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if len(line) > 0 {
+			if _, wErr := p.w.Write(p.render(line)); wErr != nil {
+				return wErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+}
+
+// tail prints the last lineCount records of the file and then (if follow is set) the records appended to it until the context is done.
+func (p *printer) tail(ctx context.Context, path string, lineCount int, follow bool) error {
+	f, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }() // f is replaced when the file is rotated
+
+	last := make([][]byte, 0, lineCount)
+	r := bufio.NewReader(f)
+	var offset int64
+	var partial string // last line of the file without the newline yet
+	rotated := false   // the end marker of the rotation was read, the path is reopened once it is a new file
+	for {
+		line, err := r.ReadString('\n')
+		offset += int64(len(line))
+		if err == io.EOF {
+			partial = line
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		rotated = logstore.IsRotationEnd([]byte(line))
+		if record := p.render(line); len(record) > 0 {
+			if len(last) == lineCount {
+				last = append(last[:0], last[1:]...)
+			}
+			last = append(last, append([]byte(nil), record...))
+		}
+	}
+	for _, record := range last {
+		if _, err := p.w.Write(record); err != nil {
+			return err
+		}
+	}
+	if !follow || path == stdinPath {
+		if partial != "" {
+			_, err = p.w.Write(p.render(partial))
+		}
+		return err
+	}
+
+	ticker := time.NewTicker(p.followInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.w.Flush(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if rotated {
+			newFile, err := reopenRotated(f, path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if newFile == nil {
+				continue
+			}
+			_ = f.Close()
+			f, offset, partial, rotated = newFile, 0, "", false
+			r.Reset(f)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if info.Size() < offset { // the file was truncated
+			offset, partial = 0, ""
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			r.Reset(f)
+		}
+		for {
+			line, err := r.ReadString('\n')
+			offset += int64(len(line))
+			partial += line
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if _, err := p.w.Write(p.render(partial)); err != nil {
+				return err
+			}
+			rotated = rotated || logstore.IsRotationEnd([]byte(partial))
+			partial = ""
+		}
+	}
+}
+
+// reopenRotated opens the new file on the path of the rotated file f, it returns nil if the new file does not exist yet.
+func reopenRotated(f *os.File, path string) (*os.File, error) {
+	newFile, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	oldInfo, err := f.Stat()
+	if err != nil {
+		_ = newFile.Close()
+		return nil, err
+	}
+	newInfo, err := newFile.Stat()
+	if err != nil || os.SameFile(oldInfo, newInfo) {
+		_ = newFile.Close()
+		return nil, err
+	}
+	return newFile, nil
+}
+
+// render returns the pretty-printed line or nothing if it is filtered out.
+func (p *printer) render(line string) []byte {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	p.buf.Reset()
+	if err := p.out.ProcessLine(line); err != nil {
+		// not a log record - it has no level, so it is printed only if the records are not filtered by the level
+		if p.level > zerolog.TraceLevel || !p.filter.MatchRaw(line) {
+			return nil
+		}
+		p.buf.WriteString(line)
+		p.buf.WriteByte('\n')
+	}
+	if p.buf.Len() > 0 {
+		p.printed = true
+	}
+	return p.buf.Bytes()
+}
+
+func openInput(path string) (*os.File, error) {
+	if path == stdinPath {
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
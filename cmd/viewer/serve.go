@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/matusvla/logviewer/internal/config"
+	"github.com/matusvla/logviewer/internal/remote"
+	"github.com/matusvla/logviewer/internal/web"
+	"github.com/matusvla/logviewer/pkg/logging"
+	"github.com/matusvla/logviewer/pkg/logstore"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultServeAddr = ":7070"
+	shutdownTimeout  = 5 * time.Second
+)
+
+// envToken is the environment variable with the token of the serve and attach commands, the token flag takes precedence
+const envToken = "LOGVIEWER_TOKEN"
+
+func runServe(args []string, cliParams params, cfg *config.Config) {
+	if err := serve(args, false, cliParams, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "viewer serve: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func runWeb(args []string, cliParams params, cfg *config.Config) {
+	if err := serve(args, true, cliParams, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "viewer -web: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// serve serves the log files over HTTP, together with the web UI if withUI is set.
+func serve(args []string, withUI bool, cliParams params, cfg *config.Config) error {
+	if len(args) == 0 {
+		if withUI {
+			return errors.New("usage: viewer -web [-addr :7070] [-token TOKEN] FILE...")
+		}
+		return errors.New("usage: viewer serve [-addr :7070] [-token TOKEN] FILE...")
+	}
+	addr := cliParams.Addr
+	if addr == "" {
+		addr = defaultServeAddr
+	}
+	token := resolveToken(cliParams)
+	if token == "" {
+		var err error
+		if token, err = generateToken(); err != nil {
+			return fmt.Errorf("token generation failed: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "generated token: %s\n", token)
+	}
+
+	logLevel, _ := zerolog.ParseLevel(cfg.LogLevel) // validated by the config
+	log, logFlushFn := logging.New("viewer", logLevel)
+	defer logFlushFn()
+
+	var srv *remote.Server
+	for _, path := range args {
+		store, err := logstore.Open(path, logstore.Options{LevelKey: cfg.FieldMapping.LevelKey()})
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if srv == nil {
+			srv = remote.NewServer(log, store, token, cfg.Rendering.FollowInterval).WithFieldMapping(cfg.FieldMapping)
+		} else {
+			srv.WithStore(store)
+		}
+	}
+	var handler http.Handler = srv
+	if withUI {
+		mux := http.NewServeMux()
+		mux.Handle("/api/", srv)
+		mux.Handle("/", web.Handler())
+		handler = mux
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- srv.Run(ctx)
+	}()
+	httpSrv := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		errCh <- httpSrv.ListenAndServe()
+	}()
+	if withUI {
+		fmt.Fprintf(os.Stderr, "serving %s, open http://%s/?token=%s in the browser\n", strings.Join(args, ", "), attachHost(addr), token)
+	} else {
+		fmt.Fprintf(os.Stderr, "serving %s on %s, attach by: viewer attach http://%s\n", strings.Join(args, ", "), addr, attachHost(addr))
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errCh:
+	}
+	shutdownCtx, cancelFn := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelFn()
+	if shutdownErr := httpSrv.Shutdown(shutdownCtx); err == nil {
+		err = shutdownErr
+	}
+	return err
+}
+
+// resolveToken returns the token from the token flag or the environment, an empty token means that none is set.
+func resolveToken(cliParams params) string {
+	if cliParams.Token != "" {
+		return cliParams.Token
+	}
+	return os.Getenv(envToken)
+}
+
+// attachHost returns the host:port to attach to, the placeholder HOST is used if the address has no host.
+func attachHost(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || host == "0.0.0.0" || host == "::" {
+		host = "HOST"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
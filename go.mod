@@ -3,10 +3,12 @@ module github.com/matusvla/logviewer
 go 1.18
 
 require (
+	github.com/gorilla/websocket v1.5.0
 	github.com/jroimartin/gocui v0.5.0
 	github.com/matusvla/easyflag v0.0.0-20220519053219-a24fb78e13c0
 	github.com/rs/zerolog v1.26.1
 	github.com/stretchr/testify v1.7.1
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
 
 require (
@@ -15,5 +17,4 @@ require (
 	github.com/nsf/termbox-go v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
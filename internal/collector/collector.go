@@ -0,0 +1,326 @@
+// Package collector receives the log records over the network (TCP, UDP and HTTP) and appends them to a spool file,
+// which can be followed by the viewer.
+//
+// The records are newline delimited. JSON records get the source field with the label of their sender, unless they have one.
+// Syslog messages (RFC 5424 and RFC 3164) and the plain text lines are converted to the JSON records, so that they
+// are indexed by their level as well.
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// SourceKey is the key of the field with the label of the sender of the record.
+	SourceKey = "source"
+	// SourceHeader is the HTTP header with the label of the sender, the source query parameter can be used as well.
+	SourceHeader = "X-Log-Source"
+
+	maxLineSize    = 1 << 20
+	maxDatagram    = 64 << 10
+	lookupTimeout  = time.Second
+	defaultLevel   = "info" // level of the plain text lines
+	timeFormat     = time.RFC3339Nano
+	maxHTTPRequest = 32 << 20
+)
+
+// Collector converts the received records and appends them to the spool. It is safe for concurrent use.
+type Collector struct {
+	log     zerolog.Logger
+	mapping prettyprint.FieldMapping
+	now     func() time.Time
+
+	mu    sync.Mutex
+	spool io.Writer
+
+	namesMu sync.Mutex
+	names   map[string]string // labels of the senders by their IP addresses
+}
+
+// New returns the Collector appending the records to the spool. The records converted from syslog and plain text
+// use the keys of the mapping for the level, time and message fields.
+func New(log zerolog.Logger, spool io.Writer, mapping prettyprint.FieldMapping) *Collector {
+	return &Collector{
+		log:     log,
+		mapping: mapping,
+		now:     time.Now,
+		spool:   spool,
+		names:   make(map[string]string),
+	}
+}
+
+// Ingest converts the line received from the source and appends it to the spool. Empty lines are skipped.
+func (c *Collector) Ingest(source string, line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	rec := c.convert(source, line)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.spool.Write(append(rec, '\n'))
+	return err
+}
+
+// ServeTCP accepts the connections and ingests the lines received over them until the context is done.
+func (c *Collector) ServeTCP(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.serveConn(ctx, conn)
+		}()
+	}
+}
+
+func (c *Collector) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	connCtx, cancelFn := context.WithCancel(ctx)
+	defer cancelFn()
+	go func() {
+		<-connCtx.Done()
+		_ = conn.Close()
+	}()
+	source := c.sourceOf(ctx, conn.RemoteAddr())
+	log := c.log.With().Str("source", source).Logger()
+	log.Debug().Msg("tcp connection accepted")
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	for scanner.Scan() {
+		if err := c.Ingest(source, scanner.Bytes()); err != nil {
+			log.Error().Err(err).Msg("spool writing failed")
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		log.Warn().Err(err).Msg("tcp connection reading failed")
+	}
+	log.Debug().Msg("tcp connection closed")
+}
+
+// ServeUDP ingests the lines of the received datagrams until the context is done.
+func (c *Collector) ServeUDP(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+	buf := make([]byte, maxDatagram)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		source := c.sourceOf(ctx, addr)
+		for _, line := range bytes.Split(buf[:n], []byte{'\n'}) {
+			if err := c.Ingest(source, line); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ServeHTTP ingests the lines of the body of a POST request, which can be gzip compressed. The sender is labeled by the X-Log-Source header
+// or the source query parameter, by its address otherwise.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST requests with newline delimited records are accepted", http.StatusMethodNotAllowed)
+		return
+	}
+	source := r.Header.Get(SourceHeader)
+	if source == "" {
+		source = r.URL.Query().Get("source")
+	}
+	if source == "" {
+		if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+			source = c.sourceOf(r.Context(), addr)
+		}
+	}
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxHTTPRequest)
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		http.Error(w, "only the gzip content encoding is supported", http.StatusUnsupportedMediaType)
+		return
+	}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	for scanner.Scan() {
+		if err := c.Ingest(source, scanner.Bytes()); err != nil {
+			c.log.Error().Err(err).Msg("spool writing failed")
+			http.Error(w, "spool writing failed", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sourceOf returns the label of the sender - its host name if the address resolves to one (e.g. the name
+// of the container in a docker network) or its IP address.
+func (c *Collector) sourceOf(ctx context.Context, addr net.Addr) string {
+	var ip string
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		ip = addr.IP.String()
+	case *net.UDPAddr:
+		ip = addr.IP.String()
+	default:
+		return addr.String()
+	}
+	c.namesMu.Lock()
+	name, ok := c.names[ip]
+	c.namesMu.Unlock()
+	if ok {
+		return name
+	}
+	name = ip
+	lookupCtx, cancelFn := context.WithTimeout(ctx, lookupTimeout)
+	defer cancelFn()
+	if names, err := net.DefaultResolver.LookupAddr(lookupCtx, ip); err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	}
+	c.namesMu.Lock()
+	c.names[ip] = name
+	c.namesMu.Unlock()
+	return name
+}
+
+// convert returns the JSON record of the line labeled with the source.
+func (c *Collector) convert(source string, line []byte) []byte {
+	if line[0] == '{' && json.Valid(line) {
+		return withSource(line, source)
+	}
+	rec, err := parseSyslog(line, c.now())
+	if err != nil {
+		rec = syslogRecord{Level: defaultLevel, Time: c.now(), Message: string(line)}
+	}
+	if rec.Time.IsZero() {
+		rec.Time = c.now()
+	}
+	fields := []field{
+		{c.mapping.LevelKey(), rec.Level},
+		{SourceKey, source},
+	}
+	if rec.Host != "" {
+		fields = append(fields, field{"host", rec.Host})
+	}
+	if rec.App != "" {
+		fields = append(fields, field{"app", rec.App})
+	}
+	fields = append(fields,
+		field{c.mapping.TimeKey(), rec.Time.Format(timeFormat)},
+		field{c.mapping.MessageKey(), rec.Message},
+	)
+	return marshalFields(fields)
+}
+
+type field struct {
+	key, value string
+}
+
+// marshalFields encodes the fields as a JSON object keeping their order. The HTML characters are not escaped,
+// so that the records can be searched for them.
+func marshalFields(fields []field) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		_ = enc.Encode(f.key)       // encoding a string never fails
+		buf.Truncate(buf.Len() - 1) // the newline written by the encoder
+		buf.WriteByte(':')
+		_ = enc.Encode(f.value)
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// withSource adds the source field to the JSON object after its first field, so that the level field written
+// first by zerolog stays first. The records with the source field are kept as they are.
+func withSource(obj []byte, source string) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(obj, &fields); err != nil {
+		return obj
+	}
+	if len(fields) == 0 {
+		return marshalFields([]field{{SourceKey, source}})
+	}
+	if _, ok := fields[SourceKey]; ok {
+		return obj
+	}
+	end, err := firstFieldEnd(obj)
+	if err != nil {
+		return obj
+	}
+	added := marshalFields([]field{{SourceKey, source}})
+	rec := make([]byte, 0, len(obj)+len(added))
+	rec = append(rec, obj[:end]...)
+	rec = append(rec, ',')
+	rec = append(rec, added[1:len(added)-1]...)
+	return append(rec, obj[end:]...)
+}
+
+// firstFieldEnd returns the offset of the end of the value of the first field of the JSON object.
+func firstFieldEnd(obj []byte) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(obj))
+	if _, err := dec.Token(); err != nil { // {
+		return 0, err
+	}
+	if _, err := dec.Token(); err != nil { // the key
+		return 0, err
+	}
+	var value json.RawMessage
+	if err := dec.Decode(&value); err != nil {
+		return 0, err
+	}
+	if off := int(dec.InputOffset()); off < len(obj) {
+		return off, nil
+	}
+	return 0, errors.New("unexpected end of the object")
+}
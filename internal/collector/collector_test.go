@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2022, 5, 22, 12, 0, 0, 0, time.UTC)
+
+// syncBuffer is the spool of the tests
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTestCollector(spool *syncBuffer, mapping prettyprint.FieldMapping) *Collector {
+	c := New(zerolog.Nop(), spool, mapping)
+	c.now = func() time.Time { return testNow }
+	return c
+}
+
+func TestCollector_Ingest(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		mapping prettyprint.FieldMapping
+		want    string
+	}{
+		{
+			name: "zerolog record",
+			line: `{"level":"warn","user":{"name":"bob"},"message":"slow request"}`,
+			want: `{"level":"warn","source":"api","user":{"name":"bob"},"message":"slow request"}`,
+		},
+		{
+			name: "record with its own source",
+			line: `{"level":"info","source":"worker-2","message":"done"}`,
+			want: `{"level":"info","source":"worker-2","message":"done"}`,
+		},
+		{
+			name: "empty object",
+			line: `{}`,
+			want: `{"source":"api"}`,
+		},
+		{
+			name: "plain text",
+			line: "  panic: runtime error\t",
+			want: `{"level":"info","source":"api","time":"2022-05-22T12:00:00Z","message":"panic: runtime error"}`,
+		},
+		{
+			name:    "plain text with mapped keys",
+			line:    "starting",
+			mapping: prettyprint.FieldMapping{Level: "severity", Time: "ts", Message: "msg"},
+			want:    `{"severity":"info","source":"api","ts":"2022-05-22T12:00:00Z","msg":"starting"}`,
+		},
+		{
+			name: "RFC 5424 syslog",
+			line: `<165>1 2022-05-22T10:15:03.003Z db.local postgres 123 ID47 [meta x="a]\"b"] checkpoint complete`,
+			want: `{"level":"info","source":"api","host":"db.local","app":"postgres","time":"2022-05-22T10:15:03.003Z","message":"checkpoint complete"}`,
+		},
+		{
+			name: "RFC 5424 syslog without structured data and time",
+			line: `<11>1 - - app - - - disk full`,
+			want: `{"level":"error","source":"api","app":"app","time":"2022-05-22T12:00:00Z","message":"disk full"}`,
+		},
+		{
+			name: "RFC 3164 syslog",
+			line: `<28>May  2 08:01:02 web nginx[42]: upstream timed out`,
+			want: `{"level":"warn","source":"api","host":"web","app":"nginx","time":"2022-05-02T08:01:02Z","message":"upstream timed out"}`,
+		},
+		{
+			name: "RFC 3164 syslog of the last year",
+			line: `<31>Dec 31 23:59:59 web cron: tick`,
+			want: `{"level":"debug","source":"api","host":"web","app":"cron","time":"2021-12-31T23:59:59Z","message":"tick"}`,
+		},
+		{
+			name: "Go log/syslog",
+			line: `<8>2022-05-22T11:59:00+02:00 build-host worker[7]: crashed`,
+			want: `{"level":"panic","source":"api","host":"build-host","app":"worker","time":"2022-05-22T11:59:00+02:00","message":"crashed"}`,
+		},
+		{
+			name: "invalid priority",
+			line: `<300>oops`,
+			want: `{"level":"info","source":"api","time":"2022-05-22T12:00:00Z","message":"<300>oops"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var spool syncBuffer
+			c := newTestCollector(&spool, tt.mapping)
+			require.NoError(t, c.Ingest("api", []byte(tt.line)))
+			require.NoError(t, c.Ingest("api", []byte("  \n")))
+			assert.Equal(t, tt.want+"\n", spool.String())
+		})
+	}
+}
+
+func TestCollector_serve(t *testing.T) {
+	var spool syncBuffer
+	c := newTestCollector(&spool, prettyprint.FieldMapping{})
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, c.ServeTCP(ctx, ln))
+	}()
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, c.ServeUDP(ctx, udpConn))
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(`{"level":"info","message":"tcp 1"}` + "\n" + `{"level":"info","message":"tcp 2"}` + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	conn, err = net.Dial("udp", udpConn.LocalAddr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(`{"level":"error","message":"udp"}`))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+	resp, err := http.Post(ts.URL+"?source=billing", "application/x-ndjson", strings.NewReader(`{"level":"debug","message":"http"}`+"\n"))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	_, _ = zw.Write([]byte(`{"level":"info","message":"gzip"}` + "\n"))
+	require.NoError(t, zw.Close())
+	req, err := http.NewRequest(http.MethodPost, ts.URL, &gzipped)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set(SourceHeader, "billing")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, err = http.Get(ts.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	require.Eventually(t, func() bool {
+		return strings.Count(spool.String(), "\n") == 5
+	}, time.Second, 10*time.Millisecond)
+	cancelFn()
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(spool.String()), "\n")
+	assert.ElementsMatch(t, []string{
+		`{"level":"info","source":"` + c.names["127.0.0.1"] + `","message":"tcp 1"}`,
+		`{"level":"info","source":"` + c.names["127.0.0.1"] + `","message":"tcp 2"}`,
+		`{"level":"error","source":"` + c.names["127.0.0.1"] + `","message":"udp"}`,
+		`{"level":"debug","source":"billing","message":"http"}`,
+		`{"level":"info","source":"billing","message":"gzip"}`,
+	}, lines)
+}
@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errNotSyslog = errors.New("not a syslog message")
+
+// syslogLevels are the zerolog levels of the syslog severities
+var syslogLevels = [8]string{"panic", "fatal", "fatal", "error", "warn", "info", "info", "debug"}
+
+// rfc3164Stamp is the timestamp of the RFC 3164 messages, they have no year
+const rfc3164Stamp = "Jan _2 15:04:05"
+
+type syslogRecord struct {
+	Level   string
+	Time    time.Time // zero if the message has none
+	Host    string
+	App     string
+	Message string
+}
+
+// parseSyslog parses the RFC 5424 or RFC 3164 message, the year of the RFC 3164 timestamps is taken from now.
+// The parsing is lenient as the senders often do not follow the RFCs exactly, only the priority is required.
+func parseSyslog(line []byte, now time.Time) (syslogRecord, error) {
+	if len(line) < 3 || line[0] != '<' {
+		return syslogRecord{}, errNotSyslog
+	}
+	end := bytes.IndexByte(line[:min(len(line), 5)], '>')
+	if end < 2 {
+		return syslogRecord{}, errNotSyslog
+	}
+	pri, err := strconv.Atoi(string(line[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return syslogRecord{}, errNotSyslog
+	}
+	rec := syslogRecord{Level: syslogLevels[pri%8]}
+	msg := string(line[end+1:])
+
+	// RFC 5424: VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	if version, rest, ok := strings.Cut(msg, " "); ok && version != "" && isDigits(version) {
+		var fields [5]string
+		for i := range fields {
+			fields[i], rest, _ = strings.Cut(rest, " ")
+		}
+		rec.Time, _ = time.Parse(time.RFC3339Nano, fields[0])
+		rec.Host, rec.App = nilValue(fields[1]), nilValue(fields[2])
+		rec.Message = strings.TrimPrefix(skipStructuredData(rest), "\ufeff")
+		return rec, nil
+	}
+
+	// RFC 3164: TIMESTAMP HOSTNAME TAG: MSG, the timestamp is RFC 3339 in some implementations (e.g. Go's log/syslog)
+	if len(msg) > len(rfc3164Stamp) {
+		if t, err := time.ParseInLocation(rfc3164Stamp, msg[:len(rfc3164Stamp)], now.Location()); err == nil {
+			rec.Time = t.AddDate(now.Year(), 0, 0)
+			if rec.Time.After(now.AddDate(0, 1, 0)) { // the message of the last year received in January
+				rec.Time = rec.Time.AddDate(-1, 0, 0)
+			}
+			msg = msg[len(rfc3164Stamp)+1:]
+		}
+	}
+	if rec.Time.IsZero() {
+		if stamp, rest, ok := strings.Cut(msg, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+				rec.Time, msg = t, rest
+			}
+		}
+	}
+	if !rec.Time.IsZero() {
+		rec.Host, msg, _ = strings.Cut(msg, " ")
+	}
+	if tag, rest, ok := strings.Cut(msg, ": "); ok && tag != "" && !strings.Contains(tag, " ") {
+		if i := strings.IndexByte(tag, '['); i > 0 {
+			tag = tag[:i] // without the PID
+		}
+		rec.App, msg = tag, rest
+	}
+	rec.Message = msg
+	return rec, nil
+}
+
+// skipStructuredData returns the message following the structured data of the RFC 5424 message.
+func skipStructuredData(s string) string {
+	if s == "-" || strings.HasPrefix(s, "- ") {
+		return strings.TrimPrefix(s[1:], " ")
+	}
+	if !strings.HasPrefix(s, "[") {
+		return s // no structured data
+	}
+	inElement, escaped, inString := false, false, false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '[':
+			inElement = true
+		case r == ']':
+			inElement = false
+		case r == ' ' && !inElement:
+			return s[i+1:]
+		}
+	}
+	return ""
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,521 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+	"github.com/matusvla/logviewer/pkg/logging/redact"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+const maxRecentFiles = 10
+
+// Config holds the settings of the viewer. The effective configuration is built in layers - the defaults
+// are overridden by the configuration file, which is overridden by the environment variables and the CLI flags.
+type Config struct {
+	// LogLevel is the level of the viewer's own logs, empty means no logging.
+	LogLevel string `yaml:"log_level,omitempty"`
+	// LogPath is the path of the viewer's own log file. It is also the file opened when the viewer starts.
+	LogPath string `yaml:"log_path,omitempty"`
+	// Theme is the name of a built-in theme, a theme defined in Themes or a path to a theme file.
+	Theme string `yaml:"theme,omitempty"`
+	// Themes are the custom themes defined directly in the configuration file. See theme.Parse for their format.
+	Themes map[string]yaml.Node `yaml:"themes,omitempty"`
+
+	Rendering    Rendering                `yaml:"rendering,omitempty"`
+	FieldMapping prettyprint.FieldMapping `yaml:"field_mapping,omitempty"`
+	// Keymap is the name of the keymap preset - default, vim or less.
+	Keymap string `yaml:"keymap,omitempty"`
+	// Keybindings override the keys of the actions in the keymap preset.
+	Keybindings map[string]Keys             `yaml:"keybindings,omitempty"`
+	Filters     []Filter                    `yaml:"filters,omitempty"`
+	Highlights  []prettyprint.HighlightRule `yaml:"highlights,omitempty"`
+	Redaction   Redaction                   `yaml:"redaction,omitempty"`
+	Recent      []string                    `yaml:"recent_files,omitempty"`
+
+	path string
+	mu   sync.RWMutex
+}
+
+// Rendering holds the settings of the log records presentation.
+type Rendering struct {
+	// CallerWidth is the number of characters to which the caller field is shortened.
+	CallerWidth int `yaml:"caller_width,omitempty"`
+	// FollowInterval is the period in which a followed file is checked for new records.
+	FollowInterval time.Duration `yaml:"follow_interval,omitempty"`
+	// DefaultLevel is the level filter set when a file is opened.
+	DefaultLevel string `yaml:"default_level,omitempty"`
+	// CallerBasename shortens the callers to the base names of their files.
+	CallerBasename bool `yaml:"caller_basename,omitempty"`
+	// TimestampFormat is the layout of the times - time, datetime, rfc3339 or epoch.
+	TimestampFormat string `yaml:"timestamp_format,omitempty"`
+	// TimeZone is the time zone the times are shown in - local, UTC, a fixed offset (e.g. +02:00) or a name
+	// of the IANA Time Zone database, the time zones of the records if empty.
+	TimeZone string `yaml:"time_zone,omitempty"`
+	// RelativeTime shows the times relative to the previous record - off or previous.
+	RelativeTime string `yaml:"relative_time,omitempty"`
+	// FieldOrder is the order of the extra fields - original or alphabetical.
+	FieldOrder     string   `yaml:"field_order,omitempty"`
+	PriorityFields []string `yaml:"priority_fields,omitempty"`
+	HiddenFields   []string `yaml:"hidden_fields,omitempty"`
+	// StackModules are the module paths whose frames are highlighted in the stack traces, the module
+	// of the innermost frame if empty.
+	StackModules []string `yaml:"stack_modules,omitempty"`
+}
+
+// Redaction holds the masking of the secrets and the personal data in the displayed records.
+type Redaction struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Fields are the names of the fields whose values are masked, redact.DefaultFields if empty.
+	Fields []string `yaml:"fields,omitempty"`
+	// Builtin are the names of the built-in patterns (jwt, bearer, credit_card, email), all of them if empty.
+	Builtin []string `yaml:"builtin,omitempty"`
+	// Patterns are the regular expressions of the custom patterns.
+	Patterns []string `yaml:"patterns,omitempty"`
+	// AllowReveal permits revealing the masked values in the viewer by a key.
+	AllowReveal bool `yaml:"allow_reveal,omitempty"`
+}
+
+// Keys are the names of the keys bound to an action. In the configuration file it is either a single key or a list of keys.
+type Keys []string
+
+func (k *Keys) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*k = Keys{value.Value}
+		return nil
+	}
+	var keys []string
+	if err := value.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// Filter is a named level filter that can be applied in the logs window by a single key.
+type Filter struct {
+	Name  string `yaml:"name"`
+	Level string `yaml:"level"`
+}
+
+// Default returns the configuration used when no other configuration layer is present.
+func Default() *Config {
+	return &Config{
+		LogPath: "./viewer.log",
+		Theme:   theme.DarkName,
+		Keymap:  "default",
+		Rendering: Rendering{
+			CallerWidth:     30,
+			FollowInterval:  500 * time.Millisecond,
+			DefaultLevel:    zerolog.TraceLevel.String(),
+			TimestampFormat: prettyprint.TimestampTime.String(),
+			RelativeTime:    prettyprint.RelativeOff.String(),
+			FieldOrder:      prettyprint.FieldOrderOriginal.String(),
+		},
+	}
+}
+
+// DefaultPath returns the path of the configuration file - $XDG_CONFIG_HOME/logviewer/config.yaml or ~/.config/logviewer/config.yaml.
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "logviewer", "config.yaml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "logviewer", "config.yaml"), nil
+}
+
+// Load reads the configuration file on top of the defaults. If the file does not exist, the defaults are returned
+// and the file is created at the path when the configuration is saved.
+// The configuration is not validated, so that further layers can be applied first, see Validate.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	cfg.path = path
+	if err := readFile(path, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func readFile(path string, cfg *Config) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && err != io.EOF {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Path returns the path of the configuration file.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Validate checks the effective configuration and reports all the problems found at once.
+func (c *Config) Validate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var problems []string
+	addProblem := func(key string, err error) {
+		problems = append(problems, fmt.Sprintf("%s: %s", key, err.Error()))
+	}
+
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil {
+		addProblem("log_level", fmt.Errorf("unknown level %q", c.LogLevel))
+	}
+	if c.LogPath == "" {
+		addProblem("log_path", errors.New("must not be empty"))
+	}
+	for name := range c.Themes {
+		if _, err := c.customTheme(name); err != nil {
+			addProblem("themes."+name, err)
+		}
+	}
+	if _, ok := c.Themes[c.Theme]; !ok {
+		if _, err := theme.Resolve(c.Theme); err != nil {
+			addProblem("theme", err)
+		}
+	}
+	if c.Rendering.CallerWidth < 1 {
+		addProblem("rendering.caller_width", errors.New("must be positive"))
+	}
+	if c.Rendering.FollowInterval < 10*time.Millisecond {
+		addProblem("rendering.follow_interval", errors.New("must be at least 10ms"))
+	}
+	if _, err := parseFilterLevel(c.Rendering.DefaultLevel); err != nil {
+		addProblem("rendering.default_level", err)
+	}
+	if _, err := prettyprint.ParseTimestampFormat(c.Rendering.TimestampFormat); err != nil {
+		addProblem("rendering.timestamp_format", err)
+	}
+	if _, err := prettyprint.ParseTimeZone(c.Rendering.TimeZone); err != nil {
+		addProblem("rendering.time_zone", err)
+	}
+	if rt, err := prettyprint.ParseRelativeTime(c.Rendering.RelativeTime); err != nil || rt == prettyprint.RelativeToAnchor {
+		addProblem("rendering.relative_time", fmt.Errorf("unknown relative time %q, expected off or previous", c.Rendering.RelativeTime))
+	}
+	if _, err := prettyprint.ParseFieldOrder(c.Rendering.FieldOrder); err != nil {
+		addProblem("rendering.field_order", err)
+	}
+	for action, keys := range c.Keybindings {
+		for _, key := range keys {
+			if strings.TrimSpace(key) == "" {
+				addProblem("keybindings."+action, errors.New("key must not be empty"))
+			}
+		}
+	}
+	if len(c.Filters) > 9 {
+		addProblem("filters", errors.New("at most 9 filters can be saved"))
+	}
+	for i, filter := range c.Filters {
+		if filter.Name == "" {
+			addProblem(fmt.Sprintf("filters[%d].name", i), errors.New("must not be empty"))
+		}
+		if _, err := parseFilterLevel(filter.Level); err != nil {
+			addProblem(fmt.Sprintf("filters[%d].level", i), err)
+		}
+	}
+	for i, rule := range c.Highlights {
+		if err := rule.Validate(); err != nil {
+			addProblem(fmt.Sprintf("highlights[%d]", i), err)
+		}
+	}
+	for i, name := range c.Redaction.Builtin {
+		if _, err := redact.Builtin(name); err != nil {
+			addProblem(fmt.Sprintf("redaction.builtin[%d]", i), err)
+		}
+	}
+	for i, expr := range c.Redaction.Patterns {
+		if _, err := redact.NewPattern(expr); err != nil {
+			addProblem(fmt.Sprintf("redaction.patterns[%d]", i), err)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// ValidationError lists all the problems found in the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+func parseFilterLevel(level string) (zerolog.Level, error) {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil || lvl < zerolog.TraceLevel || lvl > zerolog.PanicLevel {
+		return zerolog.NoLevel, fmt.Errorf("unknown level %q, expected one of trace, debug, info, warn, error, fatal, panic", level)
+	}
+	return lvl, nil
+}
+
+// ResolveTheme returns the configured theme.
+func (c *Config) ResolveTheme() (theme.Theme, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, ok := c.Themes[c.Theme]; ok {
+		return c.customTheme(c.Theme)
+	}
+	return theme.Resolve(c.Theme)
+}
+
+// customTheme is expected to be called with c.mu locked
+func (c *Config) customTheme(name string) (theme.Theme, error) {
+	node := c.Themes[name]
+	b, err := yaml.Marshal(&node)
+	if err != nil {
+		return theme.Theme{}, err
+	}
+	return theme.Parse(name, b)
+}
+
+// DefaultLevel returns the level filter set when a file is opened.
+func (c *Config) DefaultLevel() zerolog.Level {
+	lvl, err := parseFilterLevel(c.Rendering.DefaultLevel)
+	if err != nil {
+		return zerolog.TraceLevel
+	}
+	return lvl
+}
+
+// FieldOrder returns the order of the extra fields.
+func (c *Config) FieldOrder() prettyprint.FieldOrder {
+	fo, _ := prettyprint.ParseFieldOrder(c.Rendering.FieldOrder)
+	return fo
+}
+
+// PrintOptions returns the presentation of the callers and the times of the records. The invalid values are replaced
+// by the defaults, see Validate.
+func (c *Config) PrintOptions() prettyprint.Options {
+	tf, _ := prettyprint.ParseTimestampFormat(c.Rendering.TimestampFormat)
+	loc, _ := prettyprint.ParseTimeZone(c.Rendering.TimeZone)
+	rt, _ := prettyprint.ParseRelativeTime(c.Rendering.RelativeTime)
+	if rt == prettyprint.RelativeToAnchor {
+		rt = prettyprint.RelativeOff // the anchor is chosen in the logs window
+	}
+	return prettyprint.Options{
+		CallerWidth:     c.Rendering.CallerWidth,
+		CallerBasename:  c.Rendering.CallerBasename,
+		TimestampFormat: tf,
+		Location:        loc,
+		Relative:        rt,
+	}
+}
+
+// NewOutput returns the pretty printer of the records of the logLvl (and higher levels) rendering them
+// according to the configuration. The highlight rules are left to the caller.
+func (c *Config) NewOutput(w io.Writer, logLvl zerolog.Level, th theme.Theme) prettyprint.Output {
+	return prettyprint.NewOutput(w, logLvl, c.Rendering.CallerWidth).
+		WithOptions(c.PrintOptions()).
+		WithTheme(th).
+		WithFieldMapping(c.FieldMapping).
+		WithFieldOrder(c.FieldOrder()).
+		WithPriorityFields(c.Rendering.PriorityFields...).
+		WithHiddenFields(c.Rendering.HiddenFields...).
+		WithStackModules(c.Rendering.StackModules...).
+		WithRedaction(c.Redactor())
+}
+
+// Redactor returns the masking of the displayed records, nil if the redaction is disabled.
+// The invalid patterns are skipped, see Validate.
+func (c *Config) Redactor() *redact.Redactor {
+	if !c.Redaction.Enabled {
+		return nil
+	}
+	fields := c.Redaction.Fields
+	if len(fields) == 0 {
+		fields = redact.DefaultFields
+	}
+	patterns := redact.BuiltinPatterns()
+	if len(c.Redaction.Builtin) > 0 {
+		patterns = patterns[:0]
+		for _, name := range c.Redaction.Builtin {
+			if p, err := redact.Builtin(name); err == nil {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	for _, expr := range c.Redaction.Patterns {
+		if p, err := redact.NewPattern(expr); err == nil {
+			patterns = append(patterns, p)
+		}
+	}
+	return redact.New(fields, patterns...)
+}
+
+// KeybindingOverrides returns the keys of the actions which override the keymap preset.
+func (c *Config) KeybindingOverrides() map[string][]string {
+	overrides := make(map[string][]string, len(c.Keybindings))
+	for action, keys := range c.Keybindings {
+		overrides[action] = keys
+	}
+	return overrides
+}
+
+// HighlightRules returns a copy of the highlight rules.
+func (c *Config) HighlightRules() []prettyprint.HighlightRule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]prettyprint.HighlightRule(nil), c.Highlights...)
+}
+
+// SetHighlightRules replaces the highlight rules and saves the configuration.
+func (c *Config) SetHighlightRules(rules []prettyprint.HighlightRule) error {
+	c.mu.Lock()
+	c.Highlights = append([]prettyprint.HighlightRule(nil), rules...)
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// RecentFiles returns the recently opened files, the most recent one first.
+func (c *Config) RecentFiles() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.Recent...)
+}
+
+// AddRecentFile moves the file to the top of the recently opened files and saves the configuration.
+// The files served by the serve command (http:// and https:// URLs) are not recorded, as their URLs
+// may carry the access tokens.
+func (c *Config) AddRecentFile(path string) error {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return nil
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	c.mu.Lock()
+	recent := []string{path}
+	for _, p := range c.Recent {
+		if p != path && len(recent) < maxRecentFiles {
+			recent = append(recent, p)
+		}
+	}
+	c.Recent = recent
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// Dump writes the effective configuration in the format of the configuration file.
+func (c *Config) Dump(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Save writes the settings changed from within the viewer (highlight rules and recent files) to the configuration file.
+// The rest of the file (including the comments) is kept as it is, so that the defaults and the values from the other layers
+// do not leak into it. The file is replaced atomically, so that the configuration is never left half-written.
+func (c *Config) Save() error {
+	if c.path == "" {
+		return errors.New("no config file path set")
+	}
+	doc, err := readDocument(c.path)
+	if err != nil {
+		return err
+	}
+	c.mu.RLock()
+	err = setMappingValue(doc.Content[0], "highlights", c.Highlights)
+	if err == nil {
+		err = setMappingValue(doc.Content[0], "recent_files", c.Recent)
+	}
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name()) // no-op after a successful rename
+	}()
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// readDocument returns the YAML document of the configuration file, an empty mapping if the file does not exist.
+func readDocument(path string) (*yaml.Node, error) {
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, doc); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %s: not a mapping", path)
+	}
+	return doc, nil
+}
+
+// setMappingValue sets the value of the key in the YAML mapping, the key is removed if the value is empty.
+func setMappingValue(mapping *yaml.Node, key string, value interface{}) error {
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return err
+	}
+	isEmpty := valueNode.Kind == yaml.SequenceNode && len(valueNode.Content) == 0 || valueNode.Tag == "!!null"
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if isEmpty {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return nil
+		}
+		mapping.Content[i+1] = &valueNode
+		return nil
+	}
+	if !isEmpty {
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &valueNode)
+	}
+	return nil
+}
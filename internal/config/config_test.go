@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Layers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("theme: light\nrendering:\n  caller_width: 20\n  field_order: alphabetical\n"), 0o600))
+	t.Setenv("LOGVIEWER_CALLER_WIDTH", "25")
+	t.Setenv("LOGVIEWER_FOLLOW_INTERVAL", "1s")
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	envOverrides, err := EnvOverrides()
+	require.NoError(t, err)
+	cfg.Override(envOverrides)
+	cfg.Override(Overrides{Theme: "solarized"})
+	require.NoError(t, cfg.Validate())
+
+	assert.Equal(t, "solarized", cfg.Theme)                    // flag
+	assert.Equal(t, 25, cfg.Rendering.CallerWidth)             // environment
+	assert.Equal(t, time.Second, cfg.Rendering.FollowInterval) // environment
+	assert.Equal(t, "alphabetical", cfg.Rendering.FieldOrder)  // file
+	assert.Equal(t, "./viewer.log", cfg.LogPath)               // default
+	assert.Equal(t, "trace", cfg.Rendering.DefaultLevel)       // default
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "valid",
+			yaml: "filters:\n  - {name: errors, level: error}\nkeybindings:\n  follow: F\n  top: [gg, Home]\n",
+		},
+		{
+			name: "invalid redaction",
+			yaml: "redaction:\n  enabled: true\n  builtin: [email, ssn]\n  patterns: ['order-\\d+', '(']\n",
+			want: []string{
+				`redaction.builtin[1]: unknown pattern "ssn", expected one of jwt, bearer, credit_card, email`,
+				"redaction.patterns[1]: error parsing regexp: missing closing ): `(`",
+			},
+		},
+		{
+			name: "invalid values",
+			yaml: "theme: nonexistent\nrendering:\n  follow_interval: 1ms\n  default_level: verbose\nfilters:\n  - {level: warn}\n",
+			want: []string{
+				`theme: unknown theme "nonexistent", available themes are: dark, light, monochrome, solarized (or a path to a theme file)`,
+				"rendering.follow_interval: must be at least 10ms",
+				`rendering.default_level: unknown level "verbose", expected one of trace, debug, info, warn, error, fatal, panic`,
+				"filters[0].name: must not be empty",
+			},
+		},
+		{
+			name: "invalid time presentation",
+			yaml: "rendering:\n  timestamp_format: iso\n  time_zone: Mars/Olympus\n  relative_time: anchor\n",
+			want: []string{
+				`rendering.timestamp_format: unknown timestamp format "iso", expected time, datetime, rfc3339 or epoch`,
+				`rendering.time_zone: unknown time zone "Mars/Olympus"`,
+				`rendering.relative_time: unknown relative time "anchor", expected off or previous`,
+			},
+		},
+		{
+			name: "custom theme",
+			yaml: "theme: mine\nthemes:\n  mine:\n    base: unknown\n",
+			want: []string{`themes.mine: unknown theme "unknown", available themes are: dark, light, monochrome, solarized`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0o600))
+			cfg, err := Load(path)
+			require.NoError(t, err)
+			err = cfg.Validate()
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.want, validationErr.Problems)
+		})
+	}
+}
+
+func TestConfig_Redactor(t *testing.T) {
+	cfg := Default()
+	assert.Nil(t, cfg.Redactor(), "disabled by default")
+
+	cfg.Redaction = Redaction{Enabled: true}
+	assert.Equal(t, `{"password":"[REDACTED]","message":"[REDACTED]"}`, string(cfg.Redactor().Redact([]byte(`{"password":"x","message":"bob@example.com"}`))))
+
+	cfg.Redaction = Redaction{Enabled: true, Fields: []string{"pin"}, Builtin: []string{"jwt"}, Patterns: []string{`order-\d+`}}
+	assert.Equal(t, `{"password":"x","pin":"[REDACTED]","message":"bob@example.com [REDACTED]"}`, string(cfg.Redactor().Redact([]byte(`{"password":"x","pin":1234,"message":"bob@example.com order-1"}`))))
+}
+
+func TestConfig_PrintOptions(t *testing.T) {
+	cfg := Default()
+	assert.Equal(t, prettyprint.Options{CallerWidth: cfg.Rendering.CallerWidth}, cfg.PrintOptions())
+
+	cfg.Rendering.CallerBasename = true
+	cfg.Rendering.TimestampFormat = "epoch"
+	cfg.Rendering.TimeZone = "utc"
+	cfg.Rendering.RelativeTime = "previous"
+	assert.Equal(t, prettyprint.Options{
+		CallerWidth:     cfg.Rendering.CallerWidth,
+		CallerBasename:  true,
+		TimestampFormat: prettyprint.TimestampEpoch,
+		Location:        time.UTC,
+		Relative:        prettyprint.RelativeToPrevious,
+	}, cfg.PrintOptions())
+}
+
+func TestConfig_Save(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("# my settings\ntheme: light # the dark one is too dark\n"), 0o600))
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	cfg.Override(Overrides{CallerWidth: 50})
+
+	require.NoError(t, cfg.AddRecentFile("/var/log/a.log"))
+	require.NoError(t, cfg.AddRecentFile("/var/log/b.log"))
+	require.NoError(t, cfg.AddRecentFile("/var/log/a.log"))
+	require.NoError(t, cfg.AddRecentFile("http://127.0.0.1:7072/?token=secret")) // attached, not recorded
+	assert.Equal(t, []string{"/var/log/a.log", "/var/log/b.log"}, cfg.RecentFiles())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// the defaults and the overrides must not leak into the file
+	assert.Equal(t, "# my settings\ntheme: light # the dark one is too dark\nrecent_files:\n    - /var/log/a.log\n    - /var/log/b.log\n", string(b))
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// EnvPath is the environment variable with the path of the configuration file.
+const EnvPath = "LOGVIEWER_CONFIG"
+
+// Overrides are the settings coming from the environment variables or the CLI flags. Zero values mean "not set".
+type Overrides struct {
+	LogLevel        string
+	LogPath         string
+	Theme           string
+	Keymap          string
+	CallerWidth     int
+	FollowInterval  time.Duration
+	DefaultLevel    string
+	FieldOrder      string
+	TimestampFormat string
+	TimeZone        string
+}
+
+// EnvOverrides reads the overrides from the LOGVIEWER_* environment variables.
+func EnvOverrides() (Overrides, error) {
+	o := Overrides{
+		LogLevel:        os.Getenv("LOGVIEWER_LOG_LEVEL"),
+		LogPath:         os.Getenv("LOGVIEWER_LOG_PATH"),
+		Theme:           os.Getenv("LOGVIEWER_THEME"),
+		Keymap:          os.Getenv("LOGVIEWER_KEYMAP"),
+		DefaultLevel:    os.Getenv("LOGVIEWER_DEFAULT_LEVEL"),
+		FieldOrder:      os.Getenv("LOGVIEWER_FIELD_ORDER"),
+		TimestampFormat: os.Getenv("LOGVIEWER_TIMESTAMP_FORMAT"),
+		TimeZone:        os.Getenv("LOGVIEWER_TIME_ZONE"),
+	}
+	if v := os.Getenv("LOGVIEWER_CALLER_WIDTH"); v != "" {
+		width, err := strconv.Atoi(v)
+		if err != nil {
+			return Overrides{}, fmt.Errorf("LOGVIEWER_CALLER_WIDTH: %w", err)
+		}
+		o.CallerWidth = width
+	}
+	if v := os.Getenv("LOGVIEWER_FOLLOW_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return Overrides{}, fmt.Errorf("LOGVIEWER_FOLLOW_INTERVAL: %w", err)
+		}
+		o.FollowInterval = interval
+	}
+	return o, nil
+}
+
+// Override applies the set values of the overrides to the configuration.
+func (c *Config) Override(o Overrides) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	overrideString(&c.LogLevel, o.LogLevel)
+	overrideString(&c.LogPath, o.LogPath)
+	overrideString(&c.Theme, o.Theme)
+	overrideString(&c.Keymap, o.Keymap)
+	overrideString(&c.Rendering.DefaultLevel, o.DefaultLevel)
+	overrideString(&c.Rendering.FieldOrder, o.FieldOrder)
+	overrideString(&c.Rendering.TimestampFormat, o.TimestampFormat)
+	overrideString(&c.Rendering.TimeZone, o.TimeZone)
+	if o.CallerWidth != 0 {
+		c.Rendering.CallerWidth = o.CallerWidth
+	}
+	if o.FollowInterval != 0 {
+		c.Rendering.FollowInterval = o.FollowInterval
+	}
+}
+
+func overrideString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
@@ -3,13 +3,17 @@ package cui
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/jroimartin/gocui"
+	"github.com/matusvla/logviewer/internal/config"
 	"github.com/matusvla/logviewer/internal/cui/about"
+	"github.com/matusvla/logviewer/internal/cui/highlights"
 	"github.com/matusvla/logviewer/internal/cui/lib"
 	"github.com/matusvla/logviewer/internal/cui/logs"
 	"github.com/matusvla/logviewer/internal/model"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
 	"github.com/rs/zerolog"
 )
 
@@ -18,29 +22,47 @@ type GuiViewer struct {
 	gui *gocui.Gui
 
 	appManager         *lib.MenuApp
+	logsWindow         *logs.Window
 	deregisterWindowFn func(gui *gocui.Gui) error
+	openAtStart        string
+	followAtStart      bool
 }
 
 func New(
 	log zerolog.Logger,
 	logPath string,
-	logReqCh chan *model.LogRequest,
+	backend *model.Client,
+	th theme.Theme,
+	cfg *config.Config,
 ) (*GuiViewer, error) {
-	gui, err := gocui.NewGui(gocui.Output256)
-	gui.InputEsc = true
-	gui.Highlight = true
-	gui.SelFgColor = gocui.ColorGreen
-
+	padding := lib.NewCoordinates(0, 2, 0, 2)
+	lib.SetTheme(th)
+	keymap, err := lib.NewKeymap(cfg.Keymap, cfg.KeybindingOverrides())
+	if err != nil {
+		return nil, fmt.Errorf("keybindings: %w", err)
+	}
+	lib.SetKeymap(keymap)
+	logsWindow, err := logs.New(log, padding, logPath, backend, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("keybindings: %w", err)
+	}
+	highlightsWindow, err := highlights.New(log, padding, cfg, backend)
+	if err != nil {
+		return nil, fmt.Errorf("keybindings: %w", err)
+	}
+	gui, err := gocui.NewGui(lib.OutputMode())
 	if err != nil {
 		return nil, err
 	}
+	gui.InputEsc = true
+	gui.Highlight = true
+	gui.SelFgColor = lib.Attribute(th.UI.Accent.Bg)
 
-	padding := lib.NewCoordinates(0, 2, 0, 2)
-	logsWindow := logs.New(padding, logPath, logReqCh)
 	aboutWindow := about.New(log, padding)
 	menuApp, err := lib.NewMenuApp([]lib.MenuItem{
-		{logs.WindowName, logsWindow},
-		{about.WindowName, aboutWindow},
+		{WindowName: logs.WindowName, WindowManager: logsWindow},
+		{WindowName: highlights.WindowName, WindowManager: highlightsWindow},
+		{WindowName: about.WindowName, WindowManager: aboutWindow},
 	})
 	if err != nil {
 		return nil, err
@@ -63,14 +85,25 @@ func New(
 		log:                log,
 		gui:                gui,
 		appManager:         menuApp,
+		logsWindow:         logsWindow,
 		deregisterWindowFn: logsWindow.Deregister,
 	}, nil
 }
 
+// OpenAtStart makes the logs window open the log file as soon as the viewer runs and follow it if follow is set.
+func (gv *GuiViewer) OpenAtStart(logPath string, follow bool) {
+	gv.openAtStart = logPath
+	gv.followAtStart = follow
+}
+
 func (gv *GuiViewer) Run(ctx context.Context) {
 	var runWg sync.WaitGroup
 	cuiCtx, cuiCtxCancelFn := context.WithCancel(ctx)
 
+	if gv.openAtStart != "" {
+		gv.logsWindow.Open(gv.gui, gv.openAtStart, gv.followAtStart)
+	}
+
 	runWg.Add(1)
 	go func() {
 		defer runWg.Done()
@@ -0,0 +1,172 @@
+package highlights
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jroimartin/gocui"
+	"github.com/matusvla/logviewer/internal/config"
+	"github.com/matusvla/logviewer/internal/cui/lib"
+	"github.com/matusvla/logviewer/internal/model"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+	"github.com/rs/zerolog"
+)
+
+const (
+	choiceYes         = "yes"
+	choiceNo          = "no"
+	choiceScopeMatch  = "match"
+	choiceScopeRecord = "record"
+)
+
+type Window struct {
+	log                  zerolog.Logger
+	layoutManager        *layoutManager
+	interactiveViewNames []*lib.ViewFocusData
+	activeView           int
+
+	config     *config.Config
+	backend    *model.Client
+	form       *lib.Form
+	fieldInput *lib.TextInput
+	matchInput *lib.TextInput
+	rulesList  *rulesList
+	listenOnce sync.Once
+	mu         sync.Mutex
+}
+
+func New(log zerolog.Logger, padding lib.Coordinates, cfg *config.Config, backend *model.Client) (*Window, error) {
+	if err := lib.ActiveKeymap().CheckConflicts(rulesListActions...); err != nil {
+		return nil, err
+	}
+	w := &Window{
+		log:           log.With().Str("window", WindowName).Logger(),
+		layoutManager: defaultLayout(padding),
+		config:        cfg,
+		backend:       backend,
+		fieldInput:    lib.NewTextInput(fieldInputName, "Field (empty = message)"),
+		matchInput:    lib.NewTextInput(matchInputName, "Value or /regex/"),
+	}
+	w.form = lib.NewForm([]lib.Input{
+		w.fieldInput,
+		w.matchInput,
+		lib.NewChoice(fgInputName, "Foreground", theme.ColorNames(), true),
+		lib.NewChoice(bgInputName, "Background", theme.ColorNames(), true),
+		lib.NewChoice(boldInputName, "Bold", []string{choiceNo, choiceYes}, true),
+		lib.NewChoice(scopeInputName, "Paint", []string{choiceScopeMatch, choiceScopeRecord}, true),
+	}, formName, "New highlight rule", "Add")
+	w.rulesList = newRulesList(cfg.HighlightRules(), w.apply)
+	w.interactiveViewNames = []*lib.ViewFocusData{
+		lib.NewViewFocusData(lib.MenuBarName),
+		lib.NewViewFocusData(fieldInputName).WithCursor(),
+		lib.NewViewFocusData(matchInputName).WithCursor(),
+		lib.NewViewFocusData(fgInputName),
+		lib.NewViewFocusData(bgInputName),
+		lib.NewViewFocusData(boldInputName),
+		lib.NewViewFocusData(scopeInputName),
+		lib.NewViewFocusData(w.form.SubmitButtonName()),
+		lib.NewViewFocusData(rulesListName),
+	}
+	return w, nil
+}
+
+func (w *Window) Register(gui *gocui.Gui) error {
+	w.log.Debug().Msg("registering")
+	w.listenOnce.Do(func() {
+		go w.listen(gui)
+	})
+	if err := w.form.Register(gui); err != nil {
+		return err
+	}
+	if err := w.rulesList.register(gui); err != nil {
+		return err
+	}
+	if err := lib.ResetGlobalTabKeybinding(gui, w.interactiveViewNames, &w.activeView); err != nil {
+		return err
+	}
+	return w.Layout(gui)
+}
+
+func (w *Window) Deregister(gui *gocui.Gui) error {
+	w.log.Debug().Msg("deregistering")
+	if err := w.form.Deregister(gui); err != nil {
+		return err
+	}
+	if err := w.rulesList.deregister(gui); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (w *Window) Layout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	if maxX < 1 || maxY < 1 {
+		return nil // in case that the terminal is not yet initialized we don't do anything
+	}
+	if err := w.form.Layout(gui, w.layoutManager.coordinates(formName, maxX, maxY)); err != nil {
+		return err
+	}
+	if err := w.rulesList.layout(gui, w.layoutManager.coordinates(rulesListName, maxX, maxY)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// listen processes the submitted forms
+func (w *Window) listen(gui *gocui.Gui) {
+	for formData := range w.form.C {
+		rule, err := ruleFromForm(formData)
+		if err != nil {
+			w.rulesList.update(gui, w.config.HighlightRules(), lib.ErrorString(err.Error()))
+			continue
+		}
+		w.apply(gui, append(w.config.HighlightRules(), rule))
+		w.fieldInput.SetValue(gui, "", "")
+		w.matchInput.SetValue(gui, "", "")
+	}
+}
+
+// apply persists the rules in the configuration file and propagates them to the backend
+func (w *Window) apply(gui *gocui.Gui, rules []prettyprint.HighlightRule) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var status string
+	if err := w.config.SetHighlightRules(rules); err != nil {
+		w.log.Error().Err(err).Msg("highlight rules saving failed")
+		status = lib.WarningString(fmt.Sprintf("rules are applied, but could not be saved: %s", err.Error()))
+	}
+	ctx, cancelFn := context.WithTimeout(context.Background(), model.RequestTimeout)
+	defer cancelFn()
+	if err := w.backend.SetHighlightRules(ctx, rules); err != nil {
+		status = lib.ErrorString(err.Error())
+	}
+	w.rulesList.update(gui, rules, status)
+}
+
+func ruleFromForm(formData map[string]interface{}) (prettyprint.HighlightRule, error) {
+	rule := prettyprint.HighlightRule{
+		Field:  strings.TrimSpace(formData[fieldInputName].(string)),
+		Record: formData[scopeInputName].(string) == choiceScopeRecord,
+	}
+	switch match := formData[matchInputName].(string); {
+	case len(match) > 1 && strings.HasPrefix(match, "/") && strings.HasSuffix(match, "/"):
+		rule.Pattern = match[1 : len(match)-1]
+	case rule.Field == "" && match != "":
+		rule.Pattern = regexp.QuoteMeta(match) // plain text is searched for in the message
+	default:
+		rule.Value = match
+	}
+	var err error
+	if rule.Style.Fg, err = theme.ParseColor(formData[fgInputName].(string)); err != nil {
+		return rule, err
+	}
+	if rule.Style.Bg, err = theme.ParseColor(formData[bgInputName].(string)); err != nil {
+		return rule, err
+	}
+	rule.Style.Bold = formData[boldInputName].(string) == choiceYes
+	return rule, rule.Validate()
+}
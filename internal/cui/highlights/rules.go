@@ -0,0 +1,165 @@
+package highlights
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jroimartin/gocui"
+	"github.com/matusvla/logviewer/internal/cui/lib"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+)
+
+// rulesListActions are the actions bound in the rules list
+var rulesListActions = []string{lib.ActionScrollUp, lib.ActionScrollDown, lib.ActionDelete}
+
+type rulesList struct {
+	rules    []prettyprint.HighlightRule
+	selected int
+	status   string
+	onDelete func(*gocui.Gui, []prettyprint.HighlightRule)
+
+	isRegistered    bool
+	lastCoordinates lib.Coordinates
+	mu              sync.RWMutex
+}
+
+func newRulesList(rules []prettyprint.HighlightRule, onDelete func(*gocui.Gui, []prettyprint.HighlightRule)) *rulesList {
+	return &rulesList{
+		rules:           rules,
+		onDelete:        onDelete,
+		lastCoordinates: lib.NewCoordinates(0, 0, 1, 1),
+	}
+}
+
+func (rl *rulesList) layout(gui *gocui.Gui, coordinates lib.Coordinates) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.lastCoordinates = coordinates
+	if !rl.isRegistered {
+		return nil
+	}
+	return rl.setupView(gui, coordinates)
+}
+
+func (rl *rulesList) register(gui *gocui.Gui) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.isRegistered = true
+	gui.Update(func(gui *gocui.Gui) error {
+		rl.mu.Lock()
+		defer rl.mu.Unlock()
+		return rl.setupView(gui, rl.lastCoordinates)
+	})
+	return nil
+}
+
+func (rl *rulesList) deregister(gui *gocui.Gui) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.isRegistered = false
+	if err := gui.DeleteView(rulesListName); err != nil {
+		return err
+	}
+	lib.DeleteKeybindings(gui, rulesListName)
+	return nil
+}
+
+// update sets the rules and the status message shown above them and redraws the view
+func (rl *rulesList) update(gui *gocui.Gui, rules []prettyprint.HighlightRule, status string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.rules = rules
+	rl.status = status
+	if rl.selected > len(rl.rules)-1 {
+		rl.selected = len(rl.rules) - 1
+	}
+	if rl.selected < 0 {
+		rl.selected = 0
+	}
+	gui.Update(func(gui *gocui.Gui) error {
+		rl.mu.Lock()
+		defer rl.mu.Unlock()
+		v, err := gui.View(rulesListName)
+		if err != nil {
+			return nil // the view is not displayed at the moment
+		}
+		return rl.render(v)
+	})
+}
+
+func (rl *rulesList) setupView(gui *gocui.Gui, coordinates lib.Coordinates) error {
+	x0, y0, x1, y1 := coordinates.Value()
+	v, err := gui.SetView(rulesListName, x0, y0, x1, y1)
+	// already set up
+	if err == nil {
+		return nil
+	}
+	// unexpected error
+	if err != gocui.ErrUnknownView {
+		return err
+	}
+	// not yet set up
+	v.Title = "Highlight rules"
+	v.Highlight = true
+	th := lib.Theme()
+	v.SelBgColor = lib.Attribute(th.UI.Accent.Bg)
+	v.SelFgColor = lib.Attribute(th.UI.Accent.Fg)
+	if err := lib.BindAction(gui, rulesListName, lib.ActionScrollUp, "previous rule", rl.makeSelectFn(-1)); err != nil {
+		return err
+	}
+	if err := lib.BindAction(gui, rulesListName, lib.ActionScrollDown, "next rule", rl.makeSelectFn(1)); err != nil {
+		return err
+	}
+	if err := lib.BindAction(gui, rulesListName, lib.ActionDelete, "delete rule", rl.deleteSelected); err != nil {
+		return err
+	}
+	return rl.render(v)
+}
+
+// render is expected to be called with the rl.mu locked
+func (rl *rulesList) render(v *gocui.View) error {
+	v.Clear()
+	th := lib.Theme()
+	if rl.status != "" {
+		_, _ = fmt.Fprintln(v, rl.status)
+	}
+	if len(rl.rules) == 0 {
+		_, _ = fmt.Fprintln(v, lib.MutedString("No highlight rules defined yet. Add one using the form above."))
+		return nil
+	}
+	for i, rule := range rl.rules {
+		_, _ = fmt.Fprintf(v, "%2d. %s %s\n", i+1, th.Render(rule.Style, " sample "), rule.String())
+	}
+	offset := 0
+	if rl.status != "" {
+		offset = 1
+	}
+	return v.SetCursor(0, rl.selected+offset)
+}
+
+func (rl *rulesList) makeSelectFn(moveBy int) func(*gocui.Gui, *gocui.View) error {
+	return func(_ *gocui.Gui, v *gocui.View) error {
+		rl.mu.Lock()
+		defer rl.mu.Unlock()
+		newSelected := rl.selected + moveBy
+		if newSelected < 0 || newSelected > len(rl.rules)-1 {
+			return nil
+		}
+		rl.selected = newSelected
+		return rl.render(v)
+	}
+}
+
+func (rl *rulesList) deleteSelected(gui *gocui.Gui, _ *gocui.View) error {
+	rl.mu.RLock()
+	if len(rl.rules) == 0 {
+		rl.mu.RUnlock()
+		return nil
+	}
+	rules := make([]prettyprint.HighlightRule, 0, len(rl.rules)-1)
+	rules = append(rules, rl.rules[:rl.selected]...)
+	rules = append(rules, rl.rules[rl.selected+1:]...)
+	rl.mu.RUnlock()
+	go rl.onDelete(gui, rules) // the change is propagated to the backend, so it cannot block the gui main loop
+	return nil
+}
@@ -0,0 +1,15 @@
+package highlights
+
+const WindowName = "Highlights"
+
+// list of names of all views in Highlights window
+const (
+	formName       = "highlightForm"
+	fieldInputName = "highlightFieldInput"
+	matchInputName = "highlightMatchInput"
+	fgInputName    = "highlightFgInput"
+	bgInputName    = "highlightBgInput"
+	boldInputName  = "highlightBoldInput"
+	scopeInputName = "highlightScopeInput"
+	rulesListName  = "highlightRulesList"
+)
@@ -1,29 +1,83 @@
 package lib
 
 import (
-	"fmt"
+	"sync"
+
+	"github.com/jroimartin/gocui"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
 )
 
-func RedFGString(s string) string {
-	return fmt.Sprintf("\033[1;31m%s\033[0m", s)
+var currentTheme = struct {
+	theme theme.Theme
+	mu    sync.RWMutex
+}{
+	theme: theme.Dark(),
+}
+
+// SetTheme sets the color scheme used by all the widgets. It should be called before the gui is created.
+func SetTheme(th theme.Theme) {
+	currentTheme.mu.Lock()
+	defer currentTheme.mu.Unlock()
+	currentTheme.theme = th
+}
+
+// Theme returns the color scheme used by the widgets.
+func Theme() theme.Theme {
+	currentTheme.mu.RLock()
+	defer currentTheme.mu.RUnlock()
+	return currentTheme.theme
+}
+
+// OutputMode returns the gocui output mode matching the color mode of the theme.
+func OutputMode() gocui.OutputMode {
+	if Theme().ColorMode == theme.ColorMode256 {
+		return gocui.Output256
+	}
+	return gocui.OutputNormal
+}
+
+// Attribute converts the theme color to the gocui attribute for the output mode returned by OutputMode.
+func Attribute(c theme.Color) gocui.Attribute {
+	switch Theme().ColorMode {
+	case theme.ColorMode256:
+		return gocui.Attribute(c) // both use the same encoding
+	case theme.ColorMode16:
+		c = c.Downsample()
+		if c > theme.White {
+			return gocui.Attribute(c-theme.BrightBlack+theme.Black) | gocui.AttrBold
+		}
+		return gocui.Attribute(c)
+	default:
+		return gocui.ColorDefault
+	}
+}
+
+func ErrorString(s string) string {
+	th := Theme()
+	return th.Render(th.UI.Error, s)
 }
 
-func GreenFGString(s string) string {
-	return fmt.Sprintf("\033[1;32m%s\033[0m", s)
+func SuccessString(s string) string {
+	th := Theme()
+	return th.Render(th.UI.Success, s)
 }
 
-func YellowFGString(s string) string {
-	return fmt.Sprintf("\033[1;33m%s\033[0m", s)
+func WarningString(s string) string {
+	th := Theme()
+	return th.Render(th.UI.Warning, s)
 }
 
-func GrayFGString(s string) string {
-	return fmt.Sprintf("\033[1;37m%s\033[0m", s)
+func MutedString(s string) string {
+	th := Theme()
+	return th.Render(th.UI.Muted, s)
 }
 
-func GreenBGString(s string) string {
-	return fmt.Sprintf("\033[1;42m%s\033[0m", s)
+func AccentString(s string) string {
+	th := Theme()
+	return th.Render(th.UI.Accent, s)
 }
 
 func BoldString(s string) string {
-	return fmt.Sprintf("\033[30;1m%s\033[0m", s)
+	th := Theme()
+	return th.Render(theme.Style{Bold: true}, s)
 }
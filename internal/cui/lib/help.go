@@ -24,7 +24,12 @@ func SetKeybinding(gui *gocui.Gui, viewName string, key interface{}, mod gocui.M
 	if helpText == "" {
 		return nil
 	}
+	setHelp(viewName, keyName(key), helpText)
+	return nil
+}
 
+// setHelp shows the help text for the key label (which can describe multiple keys) when the view is active
+func setHelp(viewName string, keyLabel string, helpText string) {
 	helpMap.mu.Lock()
 	defer helpMap.mu.Unlock()
 
@@ -32,26 +37,30 @@ func SetKeybinding(gui *gocui.Gui, viewName string, key interface{}, mod gocui.M
 	if !ok {
 		view = make(map[string]string)
 	}
-	view[keyName(key)] = helpText
+	view[keyLabel] = helpText
 	helpMap.items[viewName] = view
-	return nil
 }
 
-func DeleteKeybinding(gui *gocui.Gui, viewName string, key interface{}, mod gocui.Modifier) error {
-	if err := gui.DeleteKeybinding(viewName, key, mod); err != nil {
-		return err
-	}
+func deleteHelp(viewName string, keyLabel string) {
 	helpMap.mu.Lock()
 	defer helpMap.mu.Unlock()
 	if _, ok := helpMap.items[viewName]; !ok {
-		return nil
+		return
 	}
-	delete(helpMap.items[viewName], keyName(key))
+	delete(helpMap.items[viewName], keyLabel)
+}
+
+func DeleteKeybinding(gui *gocui.Gui, viewName string, key interface{}, mod gocui.Modifier) error {
+	if err := gui.DeleteKeybinding(viewName, key, mod); err != nil {
+		return err
+	}
+	deleteHelp(viewName, keyName(key))
 	return nil
 }
 
 func DeleteKeybindings(gui *gocui.Gui, viewName string) {
 	gui.DeleteKeybindings(viewName)
+	deleteSequences(viewName)
 	helpMap.mu.Lock()
 	defer helpMap.mu.Unlock()
 	delete(helpMap.items, viewName)
@@ -77,6 +86,11 @@ func Close(gui *gocui.Gui) {
 		items: make(map[string]map[string]string),
 	}
 
+	keymap.mu.Lock()
+	keymap.sequences = make(map[string][]sequenceBinding)
+	keymap.pending = make(map[string]pendingSequence)
+	keymap.mu.Unlock()
+
 	popUpManagerSingleton = nil
 	gui.Close()
 }
@@ -120,7 +134,7 @@ func (h *Help) Layout(gui *gocui.Gui) error {
 	sort.Strings(generalHelpItems)
 	_, err = fmt.Fprintf(v, "%s | %s",
 		strings.Join(viewHelpItems, ", "),
-		GrayFGString(strings.Join(generalHelpItems, ", ")),
+		MutedString(strings.Join(generalHelpItems, ", ")),
 	)
 	return err
 }
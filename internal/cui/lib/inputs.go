@@ -257,8 +257,24 @@ func (c *Choice) Value() interface{} {
 	return c.allowedValues[c.valueIndex]
 }
 
-func (c *Choice) SetValue(_ *gocui.Gui, _ interface{}) {
-	panic("not implemented")
+func (c *Choice) SetValue(gui *gocui.Gui, val interface{}, _ string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, allowedValue := range c.allowedValues {
+		if allowedValue == val.(string) {
+			c.valueIndex = i
+		}
+	}
+	newValue := val.(string)
+	gui.Update(func(gui *gocui.Gui) error {
+		v, err := gui.View(c.name)
+		if err != nil {
+			return nil
+		}
+		v.Clear()
+		_, _ = fmt.Fprint(v, newValue)
+		return nil
+	})
 }
 
 func (c *Choice) setupView(gui *gocui.Gui, coordinates Coordinates) error {
@@ -318,6 +334,86 @@ func (c *Choice) makeUpdateValueFn(scrollBy int) func(g *gocui.Gui, v *gocui.Vie
 
 //----------------------------------------------------------------------------------------------------------------------
 
+type TextInput struct {
+	InputBase
+	value string
+}
+
+func NewTextInput(name string, title string) *TextInput {
+	ti := &TextInput{
+		InputBase: InputBase{
+			name:            name,
+			title:           title,
+			lastCoordinates: NewCoordinates(0, 0, 1, 1),
+		},
+	}
+	ti.InputBase.SetupView = ti.setupView
+	return ti
+}
+
+func (ti *TextInput) Value() interface{} {
+	ti.mu.RLock()
+	defer ti.mu.RUnlock()
+	return ti.value
+}
+
+func (ti *TextInput) SetValue(gui *gocui.Gui, val interface{}, format string) {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	ti.value = val.(string)
+	if format != "" {
+		ti.value = fmt.Sprintf(format, ti.value)
+	}
+	value := ti.value
+
+	gui.Update(func(gui *gocui.Gui) error {
+		v, err := gui.View(ti.name)
+		if err != nil {
+			return nil
+		}
+		v.Clear()
+		_, _ = fmt.Fprint(v, value)
+		return v.SetCursor(len(value), 0)
+	})
+}
+
+func (ti *TextInput) setupView(gui *gocui.Gui, coordinates Coordinates) error {
+	x0, y0, x1, y1 := coordinates.Value()
+	v, err := gui.SetView(ti.name, x0, y0, x1, y1)
+	// already set up
+	if err == nil {
+		return nil
+	}
+	// unexpected error
+	if err != gocui.ErrUnknownView {
+		return err
+	}
+	// not yet set up
+	v.Title = ti.title
+	v.Editable = true
+	v.Editor = gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+		if key == gocui.KeyEnter {
+			return // single line input
+		}
+		gocui.DefaultEditor.Edit(v, key, ch, mod)
+		ti.mu.Lock()
+		defer ti.mu.Unlock()
+		ti.value = strings.TrimSuffix(v.Buffer(), "\n")
+	})
+	_, _ = fmt.Fprint(v, ti.value)
+	_ = v.SetCursor(len(ti.value), 0)
+	return SetKeybinding(gui, ti.name, gocui.KeyCtrlU, gocui.ModNone, "clean",
+		func(g *gocui.Gui, v *gocui.View) error {
+			ti.mu.Lock()
+			defer ti.mu.Unlock()
+			ti.value = ""
+			v.Clear()
+			return v.SetCursor(0, 0)
+		})
+}
+
+//----------------------------------------------------------------------------------------------------------------------
+
 type MultilevelChoice struct {
 	InputBase
 	allowedValues MultilevelChoiceItems
@@ -345,7 +441,7 @@ func (m MultilevelChoiceItems) fprintUnwrapIndex(writer io.Writer, indices []int
 			bulletPoint = "+"
 		}
 		if len(indices) > 0 && indices[0] == i {
-			_, _ = fmt.Fprintf(writer, "%s%s %s\n", strings.Repeat(" ", offsetSpaces), bulletPoint, GreenBGString(item.Value()))
+			_, _ = fmt.Fprintf(writer, "%s%s %s\n", strings.Repeat(" ", offsetSpaces), bulletPoint, AccentString(item.Value()))
 			if len(indices) > 1 {
 				result += item.Subitems().fprintUnwrapIndex(writer, indices[1:], offsetSpaces+indentSpaces, indentSpaces)
 			}
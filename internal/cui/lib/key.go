@@ -1,6 +1,11 @@
 package lib
 
-import "github.com/jroimartin/gocui"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
 
 func keyName(key interface{}) string {
 	if k, ok := key.(rune); ok {
@@ -130,3 +135,34 @@ func keyName(key interface{}) string {
 	}
 	panic("unknown key")
 }
+
+var namedKeys = map[string]gocui.Key{
+	"f1": gocui.KeyF1, "f2": gocui.KeyF2, "f3": gocui.KeyF3, "f4": gocui.KeyF4,
+	"f5": gocui.KeyF5, "f6": gocui.KeyF6, "f7": gocui.KeyF7, "f8": gocui.KeyF8,
+	"f9": gocui.KeyF9, "f10": gocui.KeyF10, "f11": gocui.KeyF11, "f12": gocui.KeyF12,
+	"insert": gocui.KeyInsert, "delete": gocui.KeyDelete,
+	"home": gocui.KeyHome, "end": gocui.KeyEnd, "pgup": gocui.KeyPgup, "pgdn": gocui.KeyPgdn,
+	"up": gocui.KeyArrowUp, "down": gocui.KeyArrowDown, "left": gocui.KeyArrowLeft, "right": gocui.KeyArrowRight,
+	"enter": gocui.KeyEnter, "tab": gocui.KeyTab, "esc": gocui.KeyEsc, "space": gocui.KeySpace, "backspace": gocui.KeyBackspace2,
+	"ctrl-a": gocui.KeyCtrlA, "ctrl-b": gocui.KeyCtrlB, "ctrl-c": gocui.KeyCtrlC, "ctrl-d": gocui.KeyCtrlD,
+	"ctrl-e": gocui.KeyCtrlE, "ctrl-f": gocui.KeyCtrlF, "ctrl-g": gocui.KeyCtrlG, "ctrl-k": gocui.KeyCtrlK,
+	"ctrl-l": gocui.KeyCtrlL, "ctrl-n": gocui.KeyCtrlN, "ctrl-o": gocui.KeyCtrlO, "ctrl-p": gocui.KeyCtrlP,
+	"ctrl-q": gocui.KeyCtrlQ, "ctrl-r": gocui.KeyCtrlR, "ctrl-s": gocui.KeyCtrlS, "ctrl-t": gocui.KeyCtrlT,
+	"ctrl-u": gocui.KeyCtrlU, "ctrl-v": gocui.KeyCtrlV, "ctrl-w": gocui.KeyCtrlW, "ctrl-x": gocui.KeyCtrlX,
+	"ctrl-y": gocui.KeyCtrlY, "ctrl-z": gocui.KeyCtrlZ,
+}
+
+// ParseKey converts the name of a key used in the configuration (e.g. "j", "PgUp", "Ctrl-D") to the gocui key.
+// The returned value is either a rune or a gocui.Key, same as the key argument of the SetKeybinding function.
+func ParseKey(name string) (interface{}, error) {
+	if r := []rune(name); len(r) == 1 {
+		if r[0] == ' ' {
+			return gocui.KeySpace, nil
+		}
+		return r[0], nil
+	}
+	if k, ok := namedKeys[strings.ReplaceAll(strings.ToLower(name), "+", "-")]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", name)
+}
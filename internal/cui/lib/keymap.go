@@ -0,0 +1,426 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// The names of the actions that can be bound to keys by the keymap.
+const (
+	ActionFollow     = "follow"
+	ActionScrollUp   = "scroll_up"
+	ActionScrollDown = "scroll_down"
+	ActionPageUp     = "page_up"
+	ActionPageDown   = "page_down"
+	ActionTop        = "top"
+	ActionBottom     = "bottom"
+	ActionSearch     = "search"
+	ActionSearchNext = "search_next"
+	ActionSearchPrev = "search_prev"
+	ActionDelete     = "delete"
+	ActionReveal     = "reveal"
+	ActionRelative   = "relative_time"
+	ActionAnchor     = "anchor"
+	ActionLevelTrace = "level.trace"
+	ActionLevelDebug = "level.debug"
+	ActionLevelInfo  = "level.info"
+	ActionLevelWarn  = "level.warn"
+	ActionLevelError = "level.error"
+	ActionLevelFatal = "level.fatal"
+	ActionLevelPanic = "level.panic"
+)
+
+// MaxFilterActions is the number of the saved filter actions, see FilterAction.
+const MaxFilterActions = 9
+
+// FilterAction returns the name of the action applying the i-th (0-based) saved filter.
+func FilterAction(i int) string {
+	return fmt.Sprintf("filter.%d", i+1)
+}
+
+// The names of the keymap presets.
+const (
+	KeymapDefault = "default"
+	KeymapVim     = "vim"
+	KeymapLess    = "less"
+)
+
+// sequenceTimeout is the maximal delay between the keys of a key sequence
+const sequenceTimeout = time.Second
+
+var defaultPreset = map[string][]string{
+	ActionFollow:     {"a"},
+	ActionScrollUp:   {"Up"},
+	ActionScrollDown: {"Down"},
+	ActionPageUp:     {"PgUp"},
+	ActionPageDown:   {"PgDn"},
+	ActionTop:        {"Home"},
+	ActionBottom:     {"End"},
+	ActionSearch:     {"/"},
+	ActionSearchNext: {"n"},
+	ActionSearchPrev: {"N"},
+	ActionDelete:     {"d"},
+	ActionReveal:     {"r"},
+	ActionRelative:   {"T"},
+	ActionAnchor:     {"m"},
+	ActionLevelTrace: {"t"},
+	ActionLevelDebug: {"d"},
+	ActionLevelInfo:  {"i"},
+	ActionLevelWarn:  {"w"},
+	ActionLevelError: {"e"},
+	ActionLevelFatal: {"f"},
+	ActionLevelPanic: {"p"},
+}
+
+// keymapPresets hold the differences of the presets from the default one
+var keymapPresets = map[string]map[string][]string{
+	KeymapDefault: {},
+	KeymapVim: {
+		ActionScrollUp:   {"k", "Up"},
+		ActionScrollDown: {"j", "Down"},
+		ActionPageUp:     {"Ctrl-U", "PgUp"},
+		ActionPageDown:   {"Ctrl-D", "PgDn"},
+		ActionTop:        {"gg", "Home"},
+		ActionBottom:     {"G", "End"},
+		ActionDelete:     {"dd"},
+	},
+	KeymapLess: {
+		ActionFollow:     {"F"},
+		ActionScrollUp:   {"k", "y", "Up"},
+		ActionScrollDown: {"j", "e", "Down"},
+		ActionPageUp:     {"b", "u", "PgUp"},
+		ActionPageDown:   {"f", "d", "Space", "PgDn"},
+		ActionTop:        {"g", "<", "Home"},
+		ActionBottom:     {"G", ">", "End"},
+		ActionDelete:     {"Delete"},
+		ActionLevelTrace: {"F1"},
+		ActionLevelDebug: {"F2"},
+		ActionLevelInfo:  {"F3"},
+		ActionLevelWarn:  {"F4"},
+		ActionLevelError: {"F5"},
+		ActionLevelFatal: {"F6"},
+		ActionLevelPanic: {"F7"},
+	},
+}
+
+func init() {
+	for i := 0; i < MaxFilterActions; i++ {
+		defaultPreset[FilterAction(i)] = []string{fmt.Sprint(i + 1)}
+	}
+}
+
+// KeymapPresets returns the names of the available keymap presets.
+func KeymapPresets() []string {
+	names := make([]string, 0, len(keymapPresets))
+	for name := range keymapPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KeySequence is a key (or a sequence of keys, e.g. "gg") triggering an action.
+// Its items are runes or gocui.Keys, same as the key argument of the SetKeybinding function.
+type KeySequence []interface{}
+
+// ParseKeySequence converts the name of a key (see ParseKey) or of a sequence of up to 3 characters (e.g. "gg") to the KeySequence.
+func ParseKeySequence(name string) (KeySequence, error) {
+	key, err := ParseKey(name)
+	if err == nil {
+		return KeySequence{key}, nil
+	}
+	runes := []rune(name)
+	if len(runes) > 3 || strings.ContainsAny(name, "-+ ") {
+		return nil, err
+	}
+	seq := make(KeySequence, 0, len(runes))
+	for _, r := range runes {
+		seq = append(seq, r)
+	}
+	return seq, nil
+}
+
+func (ks KeySequence) String() string {
+	var sb strings.Builder
+	for _, key := range ks {
+		sb.WriteString(keyName(key))
+	}
+	return sb.String()
+}
+
+// hasPrefix reports whether the prefix is the beginning of the sequence (or the whole sequence).
+func (ks KeySequence) hasPrefix(prefix KeySequence) bool {
+	if len(prefix) > len(ks) {
+		return false
+	}
+	for i := range prefix {
+		if ks[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Keymap maps the named actions to the keys which trigger them.
+type Keymap struct {
+	preset   string
+	bindings map[string][]KeySequence
+}
+
+// NewKeymap creates the keymap from the given preset, the overrides replace all the keys of the actions they specify.
+func NewKeymap(preset string, overrides map[string][]string) (*Keymap, error) {
+	if preset == "" {
+		preset = KeymapDefault
+	}
+	presetBindings, ok := keymapPresets[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown keymap preset %q, available presets are: %s", preset, strings.Join(KeymapPresets(), ", "))
+	}
+	km := &Keymap{preset: preset, bindings: make(map[string][]KeySequence, len(defaultPreset))}
+	for _, layer := range []map[string][]string{defaultPreset, presetBindings, overrides} {
+		for action, keyNames := range layer {
+			if _, ok := defaultPreset[action]; !ok {
+				return nil, fmt.Errorf("unknown action %q", action)
+			}
+			seqs := make([]KeySequence, 0, len(keyNames))
+			for _, keyName := range keyNames {
+				seq, err := ParseKeySequence(keyName)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", action, err)
+				}
+				seqs = append(seqs, seq)
+			}
+			km.bindings[action] = seqs
+		}
+	}
+	return km, nil
+}
+
+// Preset returns the name of the preset the keymap is based on.
+func (km *Keymap) Preset() string {
+	return km.preset
+}
+
+// Keys returns the key sequences bound to the action.
+func (km *Keymap) Keys(action string) []KeySequence {
+	return km.bindings[action]
+}
+
+// CheckConflicts checks that the actions, which are going to be active at the same time (i.e. in the same view),
+// can be told apart - none of their key sequences is a prefix of another one and the keys of multi-key sequences are not bound alone.
+func (km *Keymap) CheckConflicts(actions ...string) error {
+	type boundSeq struct {
+		action string
+		seq    KeySequence
+	}
+	var all []boundSeq
+	for _, action := range actions {
+		for _, seq := range km.bindings[action] {
+			all = append(all, boundSeq{action, seq})
+		}
+	}
+	for i, a := range all {
+		for _, b := range all[i+1:] {
+			if a.action == b.action {
+				continue
+			}
+			conflict := a.seq.hasPrefix(b.seq) || b.seq.hasPrefix(a.seq)
+			for _, pair := range [][2]KeySequence{{a.seq, b.seq}, {b.seq, a.seq}} {
+				if len(pair[0]) == 1 && len(pair[1]) > 1 {
+					for _, key := range pair[1] {
+						conflict = conflict || key == pair[0][0]
+					}
+				}
+			}
+			if conflict {
+				return fmt.Errorf("key %q of %s conflicts with key %q of %s", a.seq.String(), a.action, b.seq.String(), b.action)
+			}
+		}
+	}
+	return nil
+}
+
+var keymap = struct {
+	active *Keymap
+	// pending holds the keys of the partially typed key sequences per view
+	pending map[string]pendingSequence
+	// sequences holds the bound multi-key sequences per view
+	sequences map[string][]sequenceBinding
+	mu        sync.Mutex
+}{
+	pending:   make(map[string]pendingSequence),
+	sequences: make(map[string][]sequenceBinding),
+}
+
+type pendingSequence struct {
+	keys KeySequence
+	at   time.Time
+}
+
+type sequenceBinding struct {
+	action  string
+	seq     KeySequence
+	handler func(*gocui.Gui, *gocui.View) error
+}
+
+func init() {
+	km, err := NewKeymap(KeymapDefault, nil)
+	if err != nil {
+		panic(err)
+	}
+	keymap.active = km
+}
+
+// SetKeymap sets the keymap used by BindAction.
+func SetKeymap(km *Keymap) {
+	keymap.mu.Lock()
+	defer keymap.mu.Unlock()
+	keymap.active = km
+}
+
+// ActiveKeymap returns the keymap used by BindAction.
+func ActiveKeymap() *Keymap {
+	keymap.mu.Lock()
+	defer keymap.mu.Unlock()
+	return keymap.active
+}
+
+// BindAction binds all the keys of the action in the active keymap to the handler.
+// The help line shows all of them together with the help text.
+func BindAction(gui *gocui.Gui, viewName string, action string, helpText string, handler func(*gocui.Gui, *gocui.View) error) error {
+	seqs := ActiveKeymap().Keys(action)
+	labels := make([]string, 0, len(seqs))
+	for _, seq := range seqs {
+		labels = append(labels, seq.String())
+		if len(seq) == 1 {
+			if err := gui.SetKeybinding(viewName, seq[0], gocui.ModNone, handler); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := bindSequence(gui, viewName, sequenceBinding{action: action, seq: seq, handler: handler}); err != nil {
+			return err
+		}
+	}
+	if helpText != "" && len(labels) > 0 {
+		setHelp(viewName, strings.Join(labels, "/"), helpText)
+	}
+	return nil
+}
+
+// UnbindAction removes the bindings created by the BindAction.
+func UnbindAction(gui *gocui.Gui, viewName string, action string) error {
+	seqs := ActiveKeymap().Keys(action)
+	labels := make([]string, 0, len(seqs))
+	for _, seq := range seqs {
+		labels = append(labels, seq.String())
+		if len(seq) == 1 {
+			if err := gui.DeleteKeybinding(viewName, seq[0], gocui.ModNone); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := unbindSequence(gui, viewName, action, seq); err != nil {
+			return err
+		}
+	}
+	deleteHelp(viewName, strings.Join(labels, "/"))
+	return nil
+}
+
+func bindSequence(gui *gocui.Gui, viewName string, sb sequenceBinding) error {
+	keymap.mu.Lock()
+	defer keymap.mu.Unlock()
+	for _, key := range sb.seq {
+		if sequenceKeyBound(viewName, key) {
+			continue
+		}
+		key := key
+		if err := gui.SetKeybinding(viewName, key, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+			return dispatchSequenceKey(g, v, viewName, key)
+		}); err != nil {
+			return err
+		}
+	}
+	keymap.sequences[viewName] = append(keymap.sequences[viewName], sb)
+	return nil
+}
+
+func unbindSequence(gui *gocui.Gui, viewName string, action string, seq KeySequence) error {
+	keymap.mu.Lock()
+	defer keymap.mu.Unlock()
+	bindings := keymap.sequences[viewName][:0]
+	for _, sb := range keymap.sequences[viewName] {
+		if sb.action != action || sb.seq.String() != seq.String() {
+			bindings = append(bindings, sb)
+		}
+	}
+	keymap.sequences[viewName] = bindings
+	for _, key := range seq {
+		if sequenceKeyBound(viewName, key) {
+			continue // still used by another sequence
+		}
+		if err := gui.DeleteKeybinding(viewName, key, gocui.ModNone); err != nil && err != gocui.ErrUnknownView {
+			return err
+		}
+	}
+	delete(keymap.pending, viewName)
+	return nil
+}
+
+// sequenceKeyBound is expected to be called with keymap.mu locked
+func sequenceKeyBound(viewName string, key interface{}) bool {
+	for _, sb := range keymap.sequences[viewName] {
+		for _, k := range sb.seq {
+			if k == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dispatchSequenceKey adds the key to the pending sequence of the view and runs the handler once a whole sequence is typed
+func dispatchSequenceKey(gui *gocui.Gui, v *gocui.View, viewName string, key interface{}) error {
+	keymap.mu.Lock()
+	pending := keymap.pending[viewName]
+	if time.Since(pending.at) > sequenceTimeout {
+		pending.keys = nil
+	}
+	candidates := []KeySequence{append(append(KeySequence(nil), pending.keys...), key), {key}}
+	for _, keys := range candidates {
+		var isPrefix bool
+		for _, sb := range keymap.sequences[viewName] {
+			if !sb.seq.hasPrefix(keys) {
+				continue
+			}
+			if len(sb.seq) == len(keys) {
+				delete(keymap.pending, viewName)
+				keymap.mu.Unlock()
+				return sb.handler(gui, v)
+			}
+			isPrefix = true
+		}
+		if isPrefix {
+			keymap.pending[viewName] = pendingSequence{keys: keys, at: time.Now()}
+			keymap.mu.Unlock()
+			return nil
+		}
+	}
+	delete(keymap.pending, viewName)
+	keymap.mu.Unlock()
+	return nil
+}
+
+// deleteSequences forgets all the sequences bound in the view, the keybindings themselves are expected to be deleted by the caller
+func deleteSequences(viewName string) {
+	keymap.mu.Lock()
+	defer keymap.mu.Unlock()
+	delete(keymap.sequences, viewName)
+	delete(keymap.pending, viewName)
+}
@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeySequence(t *testing.T) {
+	tests := []struct {
+		name string
+		want KeySequence
+		err  error
+	}{
+		{name: "j", want: KeySequence{'j'}},
+		{name: "G", want: KeySequence{'G'}},
+		{name: "Ctrl-D", want: KeySequence{gocui.KeyCtrlD}},
+		{name: "pgup", want: KeySequence{gocui.KeyPgup}},
+		{name: "gg", want: KeySequence{'g', 'g'}},
+		{name: "Ctrl-Ä", err: errors.New(`unknown key "Ctrl-Ä"`)},
+		{name: "toolong", err: errors.New(`unknown key "toolong"`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq, err := ParseKeySequence(tt.name)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.want, seq)
+		})
+	}
+}
+
+func TestNewKeymap(t *testing.T) {
+	for _, preset := range KeymapPresets() {
+		t.Run(preset, func(t *testing.T) {
+			km, err := NewKeymap(preset, nil)
+			require.NoError(t, err)
+			assert.NotEmpty(t, km.Keys(ActionScrollDown))
+		})
+	}
+
+	km, err := NewKeymap(KeymapVim, map[string][]string{ActionFollow: {"F", "Ctrl-F"}})
+	require.NoError(t, err)
+	assert.Equal(t, []KeySequence{{'F'}, {gocui.KeyCtrlF}}, km.Keys(ActionFollow))
+	assert.Equal(t, []KeySequence{{'g', 'g'}, {gocui.KeyHome}}, km.Keys(ActionTop))
+
+	_, err = NewKeymap("emacs", nil)
+	assert.EqualError(t, err, `unknown keymap preset "emacs", available presets are: default, less, vim`)
+	_, err = NewKeymap(KeymapDefault, map[string][]string{"fly": {"x"}})
+	assert.EqualError(t, err, `unknown action "fly"`)
+}
+
+func TestKeymap_CheckConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		actions   []string
+		err       string
+	}{
+		{
+			name:    "different views",
+			actions: []string{ActionLevelDebug, ActionFollow},
+		},
+		{
+			name:    "same key",
+			actions: []string{ActionLevelDebug, ActionDelete},
+			err:     `key "d" of level.debug conflicts with key "d" of delete`,
+		},
+		{
+			name:      "prefix",
+			overrides: map[string][]string{ActionTop: {"gg"}, ActionBottom: {"g"}},
+			actions:   []string{ActionTop, ActionBottom},
+			err:       `key "gg" of top conflicts with key "g" of bottom`,
+		},
+		{
+			name:      "sequence key bound alone",
+			overrides: map[string][]string{ActionTop: {"gt"}},
+			actions:   []string{ActionTop, ActionLevelTrace},
+			err:       `key "gt" of top conflicts with key "t" of level.trace`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			km, err := NewKeymap(KeymapDefault, tt.overrides)
+			require.NoError(t, err)
+			err = km.CheckConflicts(tt.actions...)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
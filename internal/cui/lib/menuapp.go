@@ -8,11 +8,6 @@ import (
 	"github.com/jroimartin/gocui"
 )
 
-const (
-	menubarBGColor       = gocui.ColorDefault
-	menubarActiveBGColor = gocui.ColorGreen
-)
-
 type (
 	WindowManager interface {
 		Register(*gocui.Gui) error
@@ -31,7 +26,7 @@ func (its menuItems) highlightString(highlightIndex int) string {
 	for i, item := range its {
 		sb.WriteString("  ")
 		if i == highlightIndex {
-			sb.WriteString(BoldString(GreenBGString(item.WindowName)))
+			sb.WriteString(AccentString(item.WindowName))
 			continue
 		}
 		sb.WriteString(item.WindowName)
@@ -72,11 +67,11 @@ func (m *MenuApp) Layout(gui *gocui.Gui) error {
 			}
 			menuView.Clear()
 			_, _ = fmt.Fprint(menuView, m.items.highlightString(m.activeIndex))
-			menuView.BgColor = menubarActiveBGColor
+			menuView.BgColor = Attribute(Theme().UI.Accent.Bg)
 		} else {
 			menuView.Clear()
 			_, _ = fmt.Fprint(menuView, m.items.highlightString(m.activeIndex))
-			menuView.BgColor = menubarBGColor
+			menuView.BgColor = gocui.ColorDefault
 		}
 		return m.items[m.activeIndex].WindowManager.Layout(gui)
 	}
@@ -13,8 +13,9 @@ type popUp interface {
 	message() string
 }
 
-func noAction() error         { return nil }
-func noActionUInt(uint) error { return nil }
+func noAction() error           { return nil }
+func noActionUInt(uint) error   { return nil }
+func noActionText(string) error { return nil }
 
 var popUpManagerSingleton *PopUpManager
 
@@ -106,6 +107,39 @@ func (p *PopUpManager) Layout(gui *gocui.Gui) error {
 		}
 		return nil
 
+	case *textInputPopUp:
+		coordinates := popUpDimensions(pu.messageFld, pu.centerX, pu.centerY, 3)
+		x0, y0, x1, y1 := coordinates.Value()
+		v, err := gui.SetView(ap.name(), x0, y0, x1, y1)
+		// already set up
+		if err == nil {
+			return nil
+		}
+		// unexpected error
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		// not yet set up
+		if _, err := fmt.Fprint(v, ap.message()); err != nil {
+			panic(err)
+		}
+		input := NewTextInput(PopUpInput, pu.inputTitle)
+		if err := input.setupView(gui, NewCoordinates(x0+2, y1-3, x1-2, y1-1)); err != nil {
+			return err
+		}
+		lastCursor := gui.Cursor
+		if err := SetKeybinding(gui, PopUpInput, gocui.KeyEsc, gocui.ModNone, "cancel", p.makeTextInputPopUpCleanupFn(noActionText, input, lastActiveView, lastCursor)); err != nil {
+			return err
+		}
+		if err := SetKeybinding(gui, PopUpInput, gocui.KeyEnter, gocui.ModNone, "submit", p.makeTextInputPopUpCleanupFn(pu.actionFn, input, lastActiveView, lastCursor)); err != nil {
+			return err
+		}
+		if _, err := SetCurrentView(gui, PopUpInput); err != nil {
+			return err
+		}
+		gui.Cursor = true
+		return nil
+
 	default:
 		panic("unknown popup type")
 	}
@@ -172,6 +206,30 @@ func (p *PopUpManager) makeUIntInputPopUpCleanupFn(action func(uint) error, inpu
 	}
 }
 
+func (p *PopUpManager) makeTextInputPopUpCleanupFn(action func(string) error, input *TextInput, lastActiveView *gocui.View, lastCursor bool) func(g *gocui.Gui, v *gocui.View) error {
+	return func(gui *gocui.Gui, v *gocui.View) error {
+		ap := p.activePopUp
+		p.mu.Lock()
+		DeleteKeybindings(gui, ap.name())
+		err := gui.DeleteView(ap.name())
+		if err == nil {
+			err = gui.DeleteView(input.name)
+		}
+		DeleteKeybindings(gui, input.name)
+		if err == nil {
+			_, err = SetCurrentView(gui, lastActiveView.Name())
+		}
+		gui.Cursor = lastCursor
+		p.activePopUp = nil
+		p.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		// the action runs after the popup is closed, so that it can open another one
+		return action(input.Value().(string))
+	}
+}
+
 type submitPopUp struct {
 	centerX, centerY    int
 	actionFn            func() error
@@ -217,3 +275,26 @@ func UIntInputPopUp(name, inputTitle, message string, centerX, centerY int, acti
 	}
 	popUpManagerSingleton.mu.Unlock()
 }
+
+type textInputPopUp struct {
+	centerX, centerY    int
+	actionFn            func(string) error
+	nameFld, messageFld string
+	inputTitle          string
+}
+
+func (s *textInputPopUp) name() string    { return s.nameFld }
+func (s *textInputPopUp) message() string { return s.messageFld }
+
+func TextInputPopUp(name, inputTitle, message string, centerX, centerY int, action func(string) error) {
+	popUpManagerSingleton.mu.Lock()
+	popUpManagerSingleton.activePopUp = &textInputPopUp{
+		centerX:    centerX,
+		centerY:    centerY,
+		actionFn:   action,
+		nameFld:    name,
+		messageFld: message,
+		inputTitle: inputTitle,
+	}
+	popUpManagerSingleton.mu.Unlock()
+}
@@ -0,0 +1,35 @@
+package logs
+
+import (
+	"github.com/matusvla/logviewer/internal/cui/lib"
+	"github.com/rs/zerolog"
+)
+
+var levelActions = []struct {
+	action string
+	level  zerolog.Level
+}{
+	{lib.ActionLevelTrace, zerolog.TraceLevel},
+	{lib.ActionLevelDebug, zerolog.DebugLevel},
+	{lib.ActionLevelInfo, zerolog.InfoLevel},
+	{lib.ActionLevelWarn, zerolog.WarnLevel},
+	{lib.ActionLevelError, zerolog.ErrorLevel},
+	{lib.ActionLevelFatal, zerolog.FatalLevel},
+	{lib.ActionLevelPanic, zerolog.PanicLevel},
+}
+
+// viewerActions returns all the actions bound in the log viewer, filterCount is the number of the saved filters
+// and canReveal tells whether the redacted values can be revealed
+func viewerActions(filterCount int, canReveal bool) []string {
+	actions := append([]string{lib.ActionFollow, lib.ActionSearch, lib.ActionSearchNext, lib.ActionSearchPrev, lib.ActionRelative, lib.ActionAnchor}, scrollActions...)
+	if canReveal {
+		actions = append(actions, lib.ActionReveal)
+	}
+	for _, la := range levelActions {
+		actions = append(actions, la.action)
+	}
+	for i := 0; i < filterCount; i++ {
+		actions = append(actions, lib.FilterAction(i))
+	}
+	return actions
+}
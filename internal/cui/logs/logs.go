@@ -2,11 +2,14 @@ package logs
 
 import (
 	"github.com/jroimartin/gocui"
+	"github.com/matusvla/logviewer/internal/config"
 	"github.com/matusvla/logviewer/internal/cui/lib"
 	"github.com/matusvla/logviewer/internal/model"
+	"github.com/rs/zerolog"
 )
 
 type Window struct {
+	log                  zerolog.Logger
 	layoutManager        *layoutManager
 	interactiveViewNames []*lib.ViewFocusData
 	activeView           int
@@ -15,18 +18,37 @@ type Window struct {
 	logViewer *viewer
 }
 
-func New(padding lib.Coordinates, logPath string, logReqCh chan *model.LogRequest) *Window {
-	logViewer := newViewer(logReqCh)
+func New(log zerolog.Logger, padding lib.Coordinates, logPath string, backend *model.Client, cfg *config.Config) (*Window, error) {
+	if err := lib.ActiveKeymap().CheckConflicts(viewerActions(len(cfg.Filters), canReveal(cfg))...); err != nil {
+		return nil, err
+	}
+	log = log.With().Str("window", WindowName).Logger()
+	logViewer := newViewer(backend, cfg, func(logPath string) {
+		if err := cfg.AddRecentFile(logPath); err != nil {
+			log.Error().Err(err).Msg("recent files saving failed")
+		}
+	})
 	return &Window{
+		log:           log,
 		layoutManager: defaultLayout(padding),
 		interactiveViewNames: []*lib.ViewFocusData{
 			lib.NewViewFocusData(lib.MenuBarName),
 			lib.NewViewFocusData(pathInputName).WithCursor(),
 			lib.NewViewFocusData(logViewerName),
 		},
-		pathInput: newPathInput(logPath, logViewer.requestLogFile),
+		pathInput: newPathInput(logPath, cfg.RecentFiles, logViewer.requestLogFile),
 		logViewer: logViewer,
+	}, nil
+}
+
+// Open sets the path input to the log file and opens it in the background, the following of the file is switched on if follow is set.
+func (w *Window) Open(gui *gocui.Gui, logPath string, follow bool) {
+	w.pathInput.setValue(gui, logPath)
+	if follow {
+		go w.logViewer.requestAndFollowLogFile(gui, logPath)
+		return
 	}
+	go w.logViewer.requestLogFile(gui, logPath)
 }
 
 func (w *Window) Register(gui *gocui.Gui) error {
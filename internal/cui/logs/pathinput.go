@@ -12,18 +12,22 @@ import (
 )
 
 type pathInput struct {
-	value    string
-	callback func(*gocui.Gui, string)
+	value       string
+	callback    func(*gocui.Gui, string)
+	recentFiles func() []string
+	recentIndex int
 
 	isRegistered    bool
 	lastCoordinates lib.Coordinates
 	mu              sync.RWMutex
 }
 
-func newPathInput(logPath string, callback func(*gocui.Gui, string)) *pathInput {
+func newPathInput(logPath string, recentFiles func() []string, callback func(*gocui.Gui, string)) *pathInput {
 	of := pathInput{
 		value:           logPath,
 		callback:        callback,
+		recentFiles:     recentFiles,
+		recentIndex:     -1,
 		lastCoordinates: lib.NewCoordinates(0, 0, 1, 1),
 	}
 	return &of
@@ -79,6 +83,7 @@ func (pi *pathInput) setupView(gui *gocui.Gui, coordinates lib.Coordinates) erro
 	if err := lib.SetKeybinding(gui, pathInputName, gocui.KeyEnter, gocui.ModNone, "submit",
 		func(g *gocui.Gui, v *gocui.View) error {
 			pi.value = v.Buffer()[:len(v.Buffer())-1] // remove newline
+			pi.recentIndex = -1
 			pi.callback(g, pi.value)
 			return nil
 		}); err != nil {
@@ -118,10 +123,50 @@ func (pi *pathInput) setupView(gui *gocui.Gui, coordinates lib.Coordinates) erro
 		}); err != nil {
 		return err
 	}
+	if err := lib.SetKeybinding(gui, pathInputName, gocui.KeyArrowUp, gocui.ModNone, "older recent file", pi.makeRecentFileFn(1)); err != nil {
+		return err
+	}
+	if err := lib.SetKeybinding(gui, pathInputName, gocui.KeyArrowDown, gocui.ModNone, "newer recent file", pi.makeRecentFileFn(-1)); err != nil {
+		return err
+	}
 	_, _ = fmt.Fprint(v, pi.value)
 	return nil
 }
 
+// setValue replaces the value shown in the input.
+func (pi *pathInput) setValue(gui *gocui.Gui, value string) {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	pi.value = value
+	gui.Update(func(gui *gocui.Gui) error {
+		v, err := gui.View(pathInputName)
+		if err != nil {
+			return nil // the value is shown once the view is set up
+		}
+		v.Clear()
+		_, _ = fmt.Fprint(v, value)
+		_ = v.SetCursor(len(value), 0)
+		return nil
+	})
+}
+
+// makeRecentFileFn returns a function that replaces the value by the recently opened file moveBy positions away from the current one
+func (pi *pathInput) makeRecentFileFn(moveBy int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		recent := pi.recentFiles()
+		newIndex := pi.recentIndex + moveBy
+		if newIndex < 0 || newIndex > len(recent)-1 {
+			return nil
+		}
+		pi.recentIndex = newIndex
+		pi.value = recent[newIndex]
+		v.Clear()
+		_, _ = fmt.Fprint(v, pi.value)
+		_ = v.SetCursor(len(pi.value), 0)
+		return nil
+	}
+}
+
 // todo test this
 func filterFileNames(dirEntries []os.DirEntry, prefix string) string {
 	var longestCommonPrefix string
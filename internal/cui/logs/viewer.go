@@ -3,78 +3,107 @@ package logs
 import (
 	"context"
 	"errors"
-	"io"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/jroimartin/gocui"
+	"github.com/matusvla/logviewer/internal/config"
 	"github.com/matusvla/logviewer/internal/cui/lib"
 	"github.com/matusvla/logviewer/internal/model"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
 	"github.com/rs/zerolog"
 )
 
 type viewer struct {
-	level zerolog.Level
+	level          zerolog.Level
+	defaultLevel   zerolog.Level
+	followInterval time.Duration
+	filters        []config.Filter
+	canReveal      bool // the redacted values can be revealed
+	onOpen         func(logPath string)
 
 	isRegistered    bool
 	lastCoordinates lib.Coordinates
 	mu              sync.RWMutex
-	logRequestCh    chan *model.LogRequest
+	backend         *model.Client
 	offset          int
+	isFileOpen      bool
+
+	total         int
+	searchPattern string
+	searchOffset  int
+	revealed      bool
+	relative      prettyprint.RelativeTime
 
 	isFollowing       bool
 	followWg          sync.WaitGroup
 	followCtxCancelFn context.CancelFunc
 }
 
-func newViewer(logReqCh chan *model.LogRequest) *viewer {
+func newViewer(backend *model.Client, cfg *config.Config, onOpen func(string)) *viewer {
 	return &viewer{
-		level:           zerolog.TraceLevel,
-		logRequestCh:    logReqCh,
+		level:           cfg.DefaultLevel(),
+		defaultLevel:    cfg.DefaultLevel(),
+		followInterval:  cfg.Rendering.FollowInterval,
+		filters:         cfg.Filters,
+		canReveal:       canReveal(cfg),
+		relative:        cfg.PrintOptions().Relative,
+		onOpen:          onOpen,
+		backend:         backend,
 		lastCoordinates: lib.NewCoordinates(0, 0, 1, 1),
 	}
 }
 
+func canReveal(cfg *config.Config) bool {
+	return cfg.Redaction.Enabled && cfg.Redaction.AllowReveal
+}
+
 func (vw *viewer) requestLogFile(gui *gocui.Gui, logPath string) {
 	vw.mu.Lock()
 	defer vw.mu.Unlock()
 
-	vw.level = zerolog.TraceLevel
+	vw.level = vw.defaultLevel
 	vw.offset = 0
-
-	// open request
-	respCh := make(chan *model.LogRequestResponse)
-	vw.logRequestCh <- &model.LogRequest{
-		Body:   &model.OpenLogRequestBody{FilePath: logPath},
-		RespCh: respCh,
+	vw.total = 0
+	vw.searchPattern = ""
+	vw.isFileOpen = false
+	if vw.relative == prettyprint.RelativeToAnchor {
+		vw.relative = prettyprint.RelativeOff // the backend drops the anchor of the closed file
 	}
-	if err := (<-respCh).Err; err != nil {
+
+	// open request - without a deadline, the indexing of a large file takes a while
+	if err := vw.backend.Open(context.Background(), logPath); err != nil {
 		gui.Update(func(gui *gocui.Gui) error {
 			return vw.setupView(gui, vw.lastCoordinates, []byte(err.Error()))
 		})
 		return
 	}
+	vw.isFileOpen = true
+	vw.onOpen(logPath)
 	_, sy := gui.Size() // this ensures that we load enough data when loading the log file for the first time
-	_, _ = vw.getLogData(gui, 0, sy, zerolog.TraceLevel)
+	_, _ = vw.getLogData(gui, 0, sy, vw.level)
 }
 
 func (vw *viewer) getLogData(gui *gocui.Gui, offset, lineCount int, level zerolog.Level) (int, bool) {
-	respCh := make(chan *model.LogRequestResponse)
-	vw.logRequestCh <- &model.LogRequest{
-		Body: &model.GetLogRequestBody{
-			OffsetFromEnd: offset,
-			LineCount:     lineCount,
-			LogLvl:        level,
-		},
-		RespCh: respCh,
-	}
-	resp := <-respCh
+	ctx, cancelFn := context.WithTimeout(context.Background(), model.RequestTimeout)
+	defer cancelFn()
+	resp, err := vw.backend.Get(ctx, offset, lineCount, level)
 	msg := resp.Body
-	if err := resp.Err; err != nil {
-		if errors.Is(err, io.EOF) {
-			return 0, false
-		}
-		msg = []byte(resp.Err.Error())
+	var noRecordsErr *model.NoRecordsError
+	switch {
+	case err == nil:
+		vw.total = resp.Total
+	case errors.Is(err, model.ErrOutOfRange), errors.Is(err, model.ErrNotOpen):
+		return 0, false
+	case errors.As(err, &noRecordsErr):
+		vw.total = 0
+		msg = []byte(lib.WarningString(err.Error()))
+	case errors.Is(err, context.DeadlineExceeded):
+		msg = []byte(lib.ErrorString("the log backend did not respond in time"))
+	default:
+		msg = []byte(lib.ErrorString(err.Error()))
 	}
 	gui.Update(func(gui *gocui.Gui) error {
 		return vw.setupView(gui, vw.lastCoordinates, msg)
@@ -99,6 +128,11 @@ func (vw *viewer) register(gui *gocui.Gui) error {
 	gui.Update(func(gui *gocui.Gui) error {
 		return vw.setupView(gui, vw.lastCoordinates, nil)
 	})
+	if vw.isFileOpen {
+		// the rendering settings (e.g. highlight rules) might have changed while the window was hidden
+		_, y0, _, y1 := vw.lastCoordinates.Value()
+		_, _ = vw.getLogData(gui, vw.offset, y1-y0-1, vw.level)
+	}
 	return nil
 }
 
@@ -107,8 +141,10 @@ func (vw *viewer) deregister(gui *gocui.Gui) error {
 	defer vw.mu.Unlock()
 	if vw.followCtxCancelFn != nil {
 		vw.followCtxCancelFn()
+		vw.followCtxCancelFn = nil
 	}
 	vw.followWg.Wait()
+	vw.isFollowing = false
 	vw.isRegistered = false
 	if err := gui.DeleteView(logViewerName); err != nil {
 		return err
@@ -122,6 +158,7 @@ func (vw *viewer) setupView(gui *gocui.Gui, coordinates lib.Coordinates, content
 	v, err := gui.SetView(logViewerName, x0, y0, x1, y1)
 	// already set up
 	if err == nil {
+		v.Title = vw.title()
 		if contents != nil {
 			v.Clear()
 			if err := v.SetOrigin(0, 0); err != nil {
@@ -138,101 +175,224 @@ func (vw *viewer) setupView(gui *gocui.Gui, coordinates lib.Coordinates, content
 		return err
 	}
 	// not yet set up
-	v.Title = "Console logs"
+	v.Title = vw.title()
 	v.Wrap = true
 	v.Autoscroll = true
-	if err := lib.SetKeybinding(gui, logViewerName, 'a', gocui.ModNone, "toggle autoscroll",
+	if err := lib.BindAction(gui, logViewerName, lib.ActionFollow, "toggle autoscroll",
 		func(g *gocui.Gui, v *gocui.View) error {
 			vw.mu.Lock()
 			defer vw.mu.Unlock()
-			if !vw.isFollowing {
-
-				if err := lib.DeleteKeybinding(gui, logViewerName, gocui.MouseWheelUp, gocui.ModNone); err != nil {
-					panic(err)
-				}
-				if err := lib.DeleteKeybinding(gui, logViewerName, gocui.KeyArrowUp, gocui.ModNone); err != nil {
-					panic(err)
-				}
-				if err := lib.DeleteKeybinding(gui, logViewerName, gocui.MouseWheelDown, gocui.ModNone); err != nil {
-					panic(err)
-				}
-				if err := lib.DeleteKeybinding(gui, logViewerName, gocui.KeyArrowDown, gocui.ModNone); err != nil {
-					panic(err)
-				}
-				ctx, cancelFn := context.WithCancel(context.Background())
-				vw.followCtxCancelFn = cancelFn
-				vw.followWg.Add(1)
-				go func() {
-					defer vw.followWg.Done()
-					t := time.NewTicker(500 * time.Millisecond)
-					for {
-						select {
-						case <-ctx.Done():
-							return
-						case <-t.C:
-							_, sy := v.Size()
-							_, _ = vw.getLogData(g, 0, sy, vw.level)
-						}
-					}
-				}()
-			} else {
-				vw.followCtxCancelFn()
-				vw.followWg.Wait()
-				vw.followCtxCancelFn = nil
-				if err := lib.SetKeybinding(gui, logViewerName, gocui.KeyArrowDown, gocui.ModNone, "scroll down", vw.scrollDown); err != nil {
-					panic(err)
-				}
-				if err := lib.SetKeybinding(gui, logViewerName, gocui.MouseWheelDown, gocui.ModNone, "scroll down", vw.scrollDown); err != nil {
-					panic(err)
-				}
-				if err := lib.SetKeybinding(gui, logViewerName, gocui.KeyArrowUp, gocui.ModNone, "scroll up", vw.scrollUp); err != nil {
-					return err
-				}
-				if err := lib.SetKeybinding(gui, logViewerName, gocui.MouseWheelUp, gocui.ModNone, "scroll up", vw.scrollUp); err != nil {
-					return err
-				}
-			}
-			vw.isFollowing = !vw.isFollowing
-			return nil
+			return vw.toggleFollow(g, v)
 		}); err != nil {
 		return err
 	}
-
-	if err := lib.SetKeybinding(gui, logViewerName, gocui.KeyArrowDown, gocui.ModNone, "scroll down", vw.scrollDown); err != nil {
-		panic(err)
+	if err := vw.setScrollKeybindings(gui); err != nil {
+		return err
 	}
-	if err := lib.SetKeybinding(gui, logViewerName, gocui.MouseWheelDown, gocui.ModNone, "scroll down", vw.scrollDown); err != nil {
-		panic(err)
+	if err := lib.BindAction(gui, logViewerName, lib.ActionSearch, "search", vw.openSearch); err != nil {
+		return err
 	}
-	if err := lib.SetKeybinding(gui, logViewerName, gocui.KeyArrowUp, gocui.ModNone, "scroll up", vw.scrollUp); err != nil {
+	if err := lib.BindAction(gui, logViewerName, lib.ActionSearchNext, "next match", vw.makeSearchFn(false)); err != nil {
 		return err
 	}
-	if err := lib.SetKeybinding(gui, logViewerName, gocui.MouseWheelUp, gocui.ModNone, "scroll up", vw.scrollUp); err != nil {
+	if err := lib.BindAction(gui, logViewerName, lib.ActionSearchPrev, "previous match", vw.makeSearchFn(true)); err != nil {
 		return err
 	}
+	if err := lib.BindAction(gui, logViewerName, lib.ActionRelative, "relative times", vw.toggleRelativeTime); err != nil {
+		return err
+	}
+	if err := lib.BindAction(gui, logViewerName, lib.ActionAnchor, "times since the newest shown record", vw.setAnchor); err != nil {
+		return err
+	}
+	if vw.canReveal {
+		if err := lib.BindAction(gui, logViewerName, lib.ActionReveal, "reveal redacted", vw.toggleReveal); err != nil {
+			return err
+		}
+	}
+	for _, la := range levelActions {
+		if err := lib.BindAction(gui, logViewerName, la.action, la.level.String(), vw.buildSetLevelFn(la.level)); err != nil {
+			return err
+		}
+	}
+	for i, filter := range vw.filters {
+		level, err := zerolog.ParseLevel(filter.Level)
+		if err != nil {
+			return err
+		}
+		if err := lib.BindAction(gui, logViewerName, lib.FilterAction(i), filter.Name, vw.buildSetLevelFn(level)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toggleFollow switches the periodical reloading of the newest records on or off, the caller holds the lock.
+func (vw *viewer) toggleFollow(gui *gocui.Gui, v *gocui.View) error {
+	if !vw.isFollowing {
+		if err := vw.deleteScrollKeybindings(gui); err != nil {
+			return err
+		}
+		ctx, cancelFn := context.WithCancel(context.Background())
+		vw.followCtxCancelFn = cancelFn
+		vw.followWg.Add(1)
+		go func() {
+			defer vw.followWg.Done()
+			t := time.NewTicker(vw.followInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					_, sy := v.Size()
+					_, _ = vw.getLogData(gui, 0, sy, vw.level)
+				}
+			}
+		}()
+	} else {
+		vw.followCtxCancelFn()
+		vw.followWg.Wait()
+		vw.followCtxCancelFn = nil
+		if err := vw.setScrollKeybindings(gui); err != nil {
+			return err
+		}
+	}
+	vw.isFollowing = !vw.isFollowing
+	return nil
+}
+
+// toggleReveal switches the revealing of the redacted values on or off and reloads the shown records.
+func (vw *viewer) toggleReveal(g *gocui.Gui, v *gocui.View) error {
+	vw.mu.Lock()
+	defer vw.mu.Unlock()
+	ctx, cancelFn := context.WithTimeout(context.Background(), model.RequestTimeout)
+	defer cancelFn()
+	if err := vw.backend.SetReveal(ctx, !vw.revealed); err != nil {
+		return vw.setupView(g, vw.lastCoordinates, []byte(lib.ErrorString(err.Error())))
+	}
+	vw.revealed = !vw.revealed
+	v.Title = vw.title()
+	_, sy := v.Size()
+	_, _ = vw.getLogData(g, vw.offset, sy, vw.level)
+	return nil
+}
+
+// toggleRelativeTime switches between the times of the records and the times relative to the previous record
+// and reloads the shown records. The times relative to the anchor are switched off.
+func (vw *viewer) toggleRelativeTime(g *gocui.Gui, v *gocui.View) error {
+	vw.mu.Lock()
+	defer vw.mu.Unlock()
+	relative := prettyprint.RelativeToPrevious
+	if vw.relative != prettyprint.RelativeOff {
+		relative = prettyprint.RelativeOff
+	}
+	return vw.setRelativeTime(g, v, relative)
+}
+
+// setAnchor shows the times relative to the newest shown record and reloads the shown records.
+func (vw *viewer) setAnchor(g *gocui.Gui, v *gocui.View) error {
+	vw.mu.Lock()
+	defer vw.mu.Unlock()
+	return vw.setRelativeTime(g, v, prettyprint.RelativeToAnchor)
+}
+
+// setRelativeTime is expected to be called with vw.mu locked
+func (vw *viewer) setRelativeTime(g *gocui.Gui, v *gocui.View, relative prettyprint.RelativeTime) error {
+	if !vw.isFileOpen {
+		return nil
+	}
+	ctx, cancelFn := context.WithTimeout(context.Background(), model.RequestTimeout)
+	defer cancelFn()
+	if err := vw.backend.SetRelativeTime(ctx, relative, vw.offset, vw.level); err != nil {
+		msg := err.Error()
+		if errors.Is(err, model.ErrNoTime) {
+			msg = "the newest shown record has no time"
+		}
+		maxX, maxY := g.Size()
+		lib.SubmitPopUp(anchorPopUpName, msg, maxX/2, maxY/2, noAction, []gocui.Key{gocui.KeyEnter})
+		return nil
+	}
+	vw.relative = relative
+	v.Title = vw.title()
+	_, sy := v.Size()
+	_, _ = vw.getLogData(g, vw.offset, sy, vw.level)
+	return nil
+}
+
+// title returns the title of the logs window, which tells whether the times are relative and warns when
+// the redacted values are revealed
+func (vw *viewer) title() string {
+	var notes []string
+	switch vw.relative {
+	case prettyprint.RelativeToPrevious:
+		notes = append(notes, "times since the previous record")
+	case prettyprint.RelativeToAnchor:
+		notes = append(notes, "times since the anchor")
+	}
+	if vw.revealed {
+		notes = append(notes, "redacted values revealed")
+	}
+	if len(notes) == 0 {
+		return "Console logs"
+	}
+	return fmt.Sprintf("Console logs (%s)", strings.Join(notes, ", "))
+}
+
+// requestAndFollowLogFile opens the log file and switches the following on.
+func (vw *viewer) requestAndFollowLogFile(gui *gocui.Gui, logPath string) {
+	vw.requestLogFile(gui, logPath)
+	gui.Update(func(gui *gocui.Gui) error {
+		vw.mu.Lock()
+		defer vw.mu.Unlock()
+		if !vw.isFileOpen || vw.isFollowing {
+			return nil
+		}
+		v, err := gui.View(logViewerName)
+		if err != nil {
+			return nil // the logs window is not shown
+		}
+		return vw.toggleFollow(gui, v)
+	})
+}
 
-	if err := lib.SetKeybinding(gui, logViewerName, 't', gocui.ModNone, "trace", vw.buildSetLevelFn(zerolog.TraceLevel)); err != nil {
+// scrollActions are the actions which are disabled while following the file
+var scrollActions = []string{lib.ActionScrollUp, lib.ActionScrollDown, lib.ActionPageUp, lib.ActionPageDown, lib.ActionTop, lib.ActionBottom}
+
+func (vw *viewer) setScrollKeybindings(gui *gocui.Gui) error {
+	if err := lib.BindAction(gui, logViewerName, lib.ActionScrollDown, "scroll down", vw.scrollDown); err != nil {
 		return err
 	}
-	if err := lib.SetKeybinding(gui, logViewerName, 'd', gocui.ModNone, "debug", vw.buildSetLevelFn(zerolog.DebugLevel)); err != nil {
+	if err := lib.BindAction(gui, logViewerName, lib.ActionScrollUp, "scroll up", vw.scrollUp); err != nil {
 		return err
 	}
-	if err := lib.SetKeybinding(gui, logViewerName, 'i', gocui.ModNone, "info", vw.buildSetLevelFn(zerolog.InfoLevel)); err != nil {
+	if err := lib.BindAction(gui, logViewerName, lib.ActionPageDown, "half page down", vw.makePageFn(-1)); err != nil {
 		return err
 	}
-	if err := lib.SetKeybinding(gui, logViewerName, 'w', gocui.ModNone, "warn", vw.buildSetLevelFn(zerolog.WarnLevel)); err != nil {
+	if err := lib.BindAction(gui, logViewerName, lib.ActionPageUp, "half page up", vw.makePageFn(1)); err != nil {
 		return err
 	}
-	if err := lib.SetKeybinding(gui, logViewerName, 'e', gocui.ModNone, "error", vw.buildSetLevelFn(zerolog.ErrorLevel)); err != nil {
+	if err := lib.BindAction(gui, logViewerName, lib.ActionTop, "top", vw.scrollTop); err != nil {
 		return err
 	}
-	if err := lib.SetKeybinding(gui, logViewerName, 'f', gocui.ModNone, "fatal", vw.buildSetLevelFn(zerolog.FatalLevel)); err != nil {
+	if err := lib.BindAction(gui, logViewerName, lib.ActionBottom, "bottom", vw.scrollBottom); err != nil {
 		return err
 	}
-	if err := lib.SetKeybinding(gui, logViewerName, 'p', gocui.ModNone, "panic", vw.buildSetLevelFn(zerolog.PanicLevel)); err != nil {
+	if err := lib.SetKeybinding(gui, logViewerName, gocui.MouseWheelDown, gocui.ModNone, "scroll down", vw.scrollDown); err != nil {
 		return err
 	}
-	return nil
+	return lib.SetKeybinding(gui, logViewerName, gocui.MouseWheelUp, gocui.ModNone, "scroll up", vw.scrollUp)
+}
+
+func (vw *viewer) deleteScrollKeybindings(gui *gocui.Gui) error {
+	for _, action := range scrollActions {
+		if err := lib.UnbindAction(gui, logViewerName, action); err != nil {
+			return err
+		}
+	}
+	if err := lib.DeleteKeybinding(gui, logViewerName, gocui.MouseWheelDown, gocui.ModNone); err != nil {
+		return err
+	}
+	return lib.DeleteKeybinding(gui, logViewerName, gocui.MouseWheelUp, gocui.ModNone)
 }
 
 func (vw *viewer) buildSetLevelFn(level zerolog.Level) func(g *gocui.Gui, v *gocui.View) error {
@@ -272,3 +432,101 @@ func (vw *viewer) scrollDown(g *gocui.Gui, v *gocui.View) error {
 	}
 	return nil
 }
+
+// makePageFn returns a function scrolling by a half of the view height, direction 1 is up and -1 is down
+func (vw *viewer) makePageFn(direction int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		vw.mu.Lock()
+		defer vw.mu.Unlock()
+		_, sy := v.Size()
+		vw.scrollTo(g, vw.offset+direction*(sy/2+1), sy)
+		return nil
+	}
+}
+
+func (vw *viewer) scrollTop(g *gocui.Gui, v *gocui.View) error {
+	vw.mu.Lock()
+	defer vw.mu.Unlock()
+	_, sy := v.Size()
+	vw.scrollTo(g, vw.topOffset(sy), sy)
+	return nil
+}
+
+// topOffset returns the offset from the end of the last record of the page starting with the first record
+func (vw *viewer) topOffset(lineCount int) int {
+	return vw.total - lineCount
+}
+
+func (vw *viewer) scrollBottom(g *gocui.Gui, v *gocui.View) error {
+	vw.mu.Lock()
+	defer vw.mu.Unlock()
+	_, sy := v.Size()
+	vw.scrollTo(g, 0, sy)
+	return nil
+}
+
+// scrollTo shows the records ending with the one at the offset from the end, the offset is limited to the available records.
+// It is expected to be called with vw.mu locked.
+func (vw *viewer) scrollTo(g *gocui.Gui, offset, lineCount int) {
+	if offset > vw.total-1 {
+		offset = vw.total - 1
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	newLines, ok := vw.getLogData(g, offset, lineCount, vw.level)
+	if ok {
+		vw.offset = offset + newLines
+	}
+}
+
+func (vw *viewer) openSearch(g *gocui.Gui, v *gocui.View) error {
+	maxX, maxY := g.Size()
+	lib.TextInputPopUp(searchPopUpName, "Pattern", "Search the records for a regular expression", maxX/2, maxY/2, func(pattern string) error {
+		vw.mu.Lock()
+		defer vw.mu.Unlock()
+		_, sy := v.Size()
+		vw.searchPattern = pattern
+		vw.searchOffset = vw.offset + sy // the search starts at the top of the view
+		vw.search(g, v, false)
+		return nil
+	})
+	return nil
+}
+
+// makeSearchFn returns a function jumping to the next (or the previous if backward is set) record matching the search pattern
+func (vw *viewer) makeSearchFn(backward bool) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		vw.mu.Lock()
+		defer vw.mu.Unlock()
+		if vw.searchPattern == "" {
+			return nil
+		}
+		vw.search(g, v, backward)
+		return nil
+	}
+}
+
+// search is expected to be called with vw.mu locked
+func (vw *viewer) search(g *gocui.Gui, v *gocui.View, backward bool) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), model.RequestTimeout)
+	defer cancelFn()
+	resp, err := vw.backend.Search(ctx, vw.searchPattern, vw.searchOffset, backward, vw.level)
+	_, sy := v.Size()
+	if err != nil {
+		msg := err.Error()
+		if errors.Is(err, model.ErrNotFound) {
+			msg = fmt.Sprintf("pattern %q not found", vw.searchPattern)
+		}
+		maxX, maxY := g.Size()
+		lib.SubmitPopUp(searchPopUpName, msg, maxX/2, maxY/2, noAction, []gocui.Key{gocui.KeyEnter})
+		vw.scrollTo(g, vw.offset, sy) // refreshes the highlighting of the pattern
+		return
+	}
+	vw.total = resp.Total
+	vw.searchOffset = resp.Offset
+	vw.scrollTo(g, resp.Offset, sy)
+	vw.searchOffset += vw.offset - resp.Offset // new lines might have been added in the meantime
+}
+
+func noAction() error { return nil }
@@ -0,0 +1,57 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+	"github.com/matusvla/logviewer/internal/config"
+	"github.com/matusvla/logviewer/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveRecords answers the GetRequests for the records "0" ... "total-1" and sends the indexes of the served records
+func serveRecords(reqCh <-chan model.Request, total int, served chan<- []int) {
+	for req := range reqCh {
+		get, ok := req.(*model.GetRequest)
+		if !ok {
+			req.Fail(fmt.Errorf("unsupported request %T", req))
+			continue
+		}
+		end := total - 1 - get.OffsetFromEnd
+		if end < 0 || end >= total {
+			get.Respond(model.GetResponse{}, model.ErrOutOfRange)
+			continue
+		}
+		var records []int
+		var lines []string
+		for i := end - get.LineCount + 1; i <= end; i++ {
+			if i >= 0 {
+				records = append(records, i)
+				lines = append(lines, fmt.Sprint(i))
+			}
+		}
+		served <- records
+		get.Respond(model.GetResponse{Body: []byte(strings.Join(lines, "\n")), Total: total}, nil)
+	}
+}
+
+func TestViewer_topOffset(t *testing.T) {
+	const total, viewHeight = 50, 10
+	reqCh := make(chan model.Request)
+	defer close(reqCh)
+	served := make(chan []int, 2)
+	go serveRecords(reqCh, total, served)
+
+	vw := newViewer(model.NewClient(reqCh), config.Default(), func(string) {})
+	gui := &gocui.Gui{} // the rendering of the records is never run
+	_, ok := vw.getLogData(gui, 0, viewHeight, vw.level)
+	require.True(t, ok)
+	assert.Equal(t, []int{40, 41, 42, 43, 44, 45, 46, 47, 48, 49}, <-served)
+
+	vw.scrollTo(gui, vw.topOffset(viewHeight), viewHeight)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, <-served, "the top page starts with the first record")
+	assert.Equal(t, total-viewHeight, vw.offset)
+}
@@ -7,3 +7,8 @@ const (
 	logViewerName = "logViewer"
 	pathInputName = "logPathInputName"
 )
+
+const (
+	searchPopUpName = "logSearchPopUp"
+	anchorPopUpName = "logAnchorPopUp"
+)
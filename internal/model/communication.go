@@ -1,26 +1,214 @@
 package model
 
 import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/matusvla/logviewer/pkg/logstore"
 	"github.com/rs/zerolog"
 )
 
-type LogRequest struct {
-	Body   interface{}
-	RespCh chan *LogRequestResponse
+var (
+	// ErrNotOpen is returned by the requests for the records when no log file is open.
+	ErrNotOpen = errors.New("no log file open")
+	// ErrOutOfRange is returned when the requested offset points out of the records.
+	ErrOutOfRange = logstore.ErrOutOfRange
+	// ErrNotFound is returned when no record matches the searched pattern.
+	ErrNotFound = logstore.ErrNotFound
+	// ErrRevealNotPermitted is returned when revealing the redacted values is not permitted by the configuration.
+	ErrRevealNotPermitted = errors.New("revealing the redacted values is not permitted")
+	// ErrNoTime is returned when the record chosen as the anchor of the relative times has no time.
+	ErrNoTime = prettyprint.ErrNoTime
+)
+
+// RequestTimeout is the deadline of the requests which are expected to be answered promptly, e.g. the ones for a page of the records.
+const RequestTimeout = 10 * time.Second
+
+// NoRecordsError is returned when there are no records of the requested level.
+type NoRecordsError = logstore.NoRecordsError
+
+// RequestID identifies the request in the logs of the backend.
+type RequestID uint64
+
+// Request is a request to the log backend - one of *OpenRequest, *GetRequest, *SearchRequest, *SetHighlightRulesRequest,
+// *SetRevealRequest and *SetRelativeTimeRequest. The requests are created and sent by the Client, the backend answers them by their Respond
+// (or Fail) method.
+type Request interface {
+	ID() RequestID
+	// Context is done when the sender is no longer interested in the response.
+	Context() context.Context
+	// Fail responds to the request with the error.
+	Fail(err error)
+	isRequest()
+}
+
+type result[Resp any] struct {
+	resp Resp
+	err  error
+}
+
+// request is the part common to all the requests. It is answered by a response of the type Resp.
+type request[Resp any] struct {
+	id     RequestID
+	ctx    context.Context
+	respCh chan result[Resp]
+}
+
+func (r *request[Resp]) ID() RequestID {
+	return r.id
+}
+
+func (r *request[Resp]) Context() context.Context {
+	return r.ctx
+}
+
+// Respond sends the response to the request. It never blocks and only the first response is delivered.
+func (r *request[Resp]) Respond(resp Resp, err error) {
+	select {
+	case r.respCh <- result[Resp]{resp: resp, err: err}:
+	default:
+	}
+}
+
+func (r *request[Resp]) Fail(err error) {
+	var zero Resp
+	r.Respond(zero, err)
+}
+
+func (r *request[Resp]) isRequest() {}
+
+// OpenRequest opens the log file, the previously opened one is closed.
+type OpenRequest struct {
+	request[OpenResponse]
+	FilePath string
 }
 
-type GetLogRequestBody struct {
+type OpenResponse struct{}
+
+// GetRequest requests LineCount rendered records of the level ending with the one at the OffsetFromEnd.
+type GetRequest struct {
+	request[GetResponse]
 	OffsetFromEnd int
 	LineCount     int
 	LogLvl        zerolog.Level
 }
 
-type OpenLogRequestBody struct {
-	FilePath string
+type GetResponse struct {
+	Body []byte
+	// NewLines is the number of the records of the level appended to the file since the previous request
+	NewLines int
+	// Total is the number of records of the level
+	Total int
 }
 
-type LogRequestResponse struct {
-	Body     []byte
-	NewLines int
-	Err      error
+// SearchRequest looks for the nearest record matching the pattern after (or before if Backward is set)
+// the record at the OffsetFromEnd. The pattern is highlighted in the records returned afterwards, an empty pattern clears it.
+type SearchRequest struct {
+	request[SearchResponse]
+	Pattern       string
+	OffsetFromEnd int
+	Backward      bool
+	LogLvl        zerolog.Level
+}
+
+type SearchResponse struct {
+	// Offset is the offset from the end of the record found by the search
+	Offset int
+	// Total is the number of records of the level
+	Total int
+}
+
+// SetHighlightRulesRequest replaces the highlight rules applied to the rendered records.
+type SetHighlightRulesRequest struct {
+	request[SetHighlightRulesResponse]
+	Rules []prettyprint.HighlightRule
+}
+
+type SetHighlightRulesResponse struct{}
+
+// SetRevealRequest switches the revealing of the redacted values in the rendered records on or off.
+type SetRevealRequest struct {
+	request[SetRevealResponse]
+	Reveal bool
+}
+
+type SetRevealResponse struct{}
+
+// SetRelativeTimeRequest switches the times of the rendered records between the times of the records and the times
+// relative to the previous record or to the anchor record at the OffsetFromEnd among the records of the level.
+type SetRelativeTimeRequest struct {
+	request[SetRelativeTimeResponse]
+	Relative      prettyprint.RelativeTime
+	OffsetFromEnd int // of the anchor record
+	LogLvl        zerolog.Level
+}
+
+type SetRelativeTimeResponse struct{}
+
+// Client sends the requests to the log backend and waits for their responses.
+// The waiting ends when the context of the request is done (e.g. its deadline is exceeded).
+type Client struct {
+	reqCh  chan<- Request
+	lastID uint64
+}
+
+func NewClient(reqCh chan<- Request) *Client {
+	return &Client{reqCh: reqCh}
+}
+
+func (c *Client) Open(ctx context.Context, filePath string) error {
+	req := &OpenRequest{FilePath: filePath}
+	_, err := do(ctx, c, req, &req.request)
+	return err
+}
+
+func (c *Client) Get(ctx context.Context, offsetFromEnd, lineCount int, logLvl zerolog.Level) (GetResponse, error) {
+	req := &GetRequest{OffsetFromEnd: offsetFromEnd, LineCount: lineCount, LogLvl: logLvl}
+	return do(ctx, c, req, &req.request)
+}
+
+func (c *Client) Search(ctx context.Context, pattern string, offsetFromEnd int, backward bool, logLvl zerolog.Level) (SearchResponse, error) {
+	req := &SearchRequest{Pattern: pattern, OffsetFromEnd: offsetFromEnd, Backward: backward, LogLvl: logLvl}
+	return do(ctx, c, req, &req.request)
+}
+
+func (c *Client) SetHighlightRules(ctx context.Context, rules []prettyprint.HighlightRule) error {
+	req := &SetHighlightRulesRequest{Rules: rules}
+	_, err := do(ctx, c, req, &req.request)
+	return err
+}
+
+func (c *Client) SetReveal(ctx context.Context, reveal bool) error {
+	req := &SetRevealRequest{Reveal: reveal}
+	_, err := do(ctx, c, req, &req.request)
+	return err
+}
+
+func (c *Client) SetRelativeTime(ctx context.Context, relative prettyprint.RelativeTime, anchorOffsetFromEnd int, logLvl zerolog.Level) error {
+	req := &SetRelativeTimeRequest{Relative: relative, OffsetFromEnd: anchorOffsetFromEnd, LogLvl: logLvl}
+	_, err := do(ctx, c, req, &req.request)
+	return err
+}
+
+// do sends the request, whose common part is the base, and waits for the response.
+func do[Resp any](ctx context.Context, c *Client, req Request, base *request[Resp]) (Resp, error) {
+	base.id = RequestID(atomic.AddUint64(&c.lastID, 1))
+	base.ctx = ctx
+	base.respCh = make(chan result[Resp], 1)
+
+	var zero Resp
+	select {
+	case c.reqCh <- req:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+	select {
+	case res := <-base.respCh:
+		return res.resp, res.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
 }
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	reqCh := make(chan Request)
+	client := NewClient(reqCh)
+	go func() {
+		for req := range reqCh {
+			switch req := req.(type) {
+			case *GetRequest:
+				req.Respond(GetResponse{Body: []byte(req.LogLvl.String()), Total: int(req.ID())}, nil)
+			case *OpenRequest:
+				req.Fail(ErrNotOpen)
+			default:
+				req.Fail(errors.New("unexpected request"))
+			}
+		}
+	}()
+	defer close(reqCh)
+
+	resp, err := client.Get(context.Background(), 0, 10, zerolog.WarnLevel)
+	require.NoError(t, err)
+	assert.Equal(t, GetResponse{Body: []byte("warn"), Total: 1}, resp)
+
+	resp, err = client.Get(context.Background(), 0, 10, zerolog.InfoLevel)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Total, "request IDs are increasing")
+
+	assert.Equal(t, ErrNotOpen, client.Open(context.Background(), "app.log"))
+}
+
+func TestClient_Deadline(t *testing.T) {
+	reqCh := make(chan Request, 1)
+	client := NewClient(reqCh)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFn()
+	_, err := client.Search(ctx, "pattern", 0, false, zerolog.TraceLevel)
+	assert.Equal(t, context.DeadlineExceeded, err, "the request is not answered")
+
+	req := <-reqCh
+	assert.Equal(t, context.DeadlineExceeded, req.Context().Err())
+	req.Fail(req.Context().Err()) // does not block, even though nobody waits for the response
+
+	_, err = client.Search(ctx, "pattern", 0, false, zerolog.TraceLevel)
+	assert.Equal(t, context.DeadlineExceeded, err, "the request is not sent")
+}
@@ -0,0 +1,262 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/matusvla/logviewer/pkg/logstore"
+	"github.com/rs/zerolog"
+)
+
+const clientTimeout = 30 * time.Second
+
+// Store is the client of the Server providing the records of the remote log file. The records appended to the file
+// are reported by the follow stream of the server, the store keeps it open until it is closed.
+type Store struct {
+	baseURL    *url.URL
+	file       string // the file selected on the server, empty for the default one
+	token      string
+	httpClient *http.Client
+	cancelFn   context.CancelFunc
+	conn       *websocket.Conn
+	done       chan struct{}
+
+	mu         sync.Mutex
+	path       string
+	totals     map[zerolog.Level]int
+	newRecords map[zerolog.Level]int
+	followErr  error
+}
+
+// Dial connects to the server at the URL (e.g. http://host:7070). The token can be passed in the token query parameter
+// of the URL as well, the token argument takes precedence if it is not empty. The file query parameter selects
+// one of the files served by the server.
+func Dial(ctx context.Context, rawURL, token string) (*Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q, expected http or https", u.Scheme)
+	}
+	query := u.Query()
+	if token == "" {
+		token = query.Get("token")
+	}
+	u.RawQuery, u.Fragment = "", ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	s := &Store{
+		baseURL:    u,
+		file:       query.Get("file"),
+		token:      token,
+		httpClient: &http.Client{Timeout: clientTimeout},
+		done:       make(chan struct{}),
+		newRecords: make(map[zerolog.Level]int),
+	}
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	wsURL := s.endpoint("follow", url.Values{"level": {zerolog.TraceLevel.String()}})
+	wsURL.Scheme = strings.Replace(wsURL.Scheme, "http", "ws", 1)
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, wsURL.String(), s.header())
+	if err != nil {
+		if resp != nil {
+			return nil, decodeError(resp)
+		}
+		return nil, err
+	}
+	s.conn = conn
+	followCtx, cancelFn := context.WithCancel(context.Background())
+	s.cancelFn = cancelFn
+	go s.follow(followCtx)
+	return s, nil
+}
+
+// Path returns the path of the log file on the server.
+func (s *Store) Path() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.path
+}
+
+// Close closes the follow stream.
+func (s *Store) Close() error {
+	s.cancelFn()
+	err := s.conn.Close()
+	<-s.done
+	return err
+}
+
+// Update refreshes the numbers of the records and returns the numbers of the records appended to the file
+// per level filter since the last update.
+func (s *Store) Update() (map[zerolog.Level]int, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), clientTimeout)
+	defer cancelFn()
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.followErr != nil {
+		return nil, fmt.Errorf("following the remote log file: %w", s.followErr)
+	}
+	newRecords := s.newRecords
+	s.newRecords = make(map[zerolog.Level]int)
+	return newRecords, nil
+}
+
+// Len returns the number of the records of the level filter known since the last update.
+func (s *Store) Len(lvl zerolog.Level) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totals[lvl]
+}
+
+// Range returns the records of the level filter with the indices from the interval [from, to).
+func (s *Store) Range(lvl zerolog.Level, from, to int) ([]logstore.Record, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), clientTimeout)
+	defer cancelFn()
+	var resp recordsResponse
+	err := s.get(ctx, "records", url.Values{
+		"level": {lvl.String()},
+		"from":  {strconv.Itoa(from)},
+		"to":    {strconv.Itoa(to)},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]logstore.Record, 0, len(resp.Records))
+	for _, rec := range resp.Records {
+		records = append(records, fromRecord(rec))
+	}
+	return records, nil
+}
+
+// Search returns the index of the nearest record of the level filter matching the pattern, see logstore.Store.Search.
+func (s *Store) Search(ctx context.Context, re *regexp.Regexp, lvl zerolog.Level, from int, backward bool) (int, error) {
+	var resp searchResponse
+	err := s.get(ctx, "search", url.Values{
+		"level":    {lvl.String()},
+		"from":     {strconv.Itoa(from)},
+		"backward": {strconv.FormatBool(backward)},
+		"pattern":  {re.String()},
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Index, nil
+}
+
+func (s *Store) refresh(ctx context.Context) error {
+	var resp infoResponse
+	if err := s.get(ctx, "info", nil, &resp); err != nil {
+		return err
+	}
+	totals := make(map[zerolog.Level]int, len(resp.Totals))
+	for name, total := range resp.Totals {
+		lvl, err := zerolog.ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("unexpected level %q in the server response", name)
+		}
+		totals[lvl] = total
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = resp.Path
+	s.totals = totals
+	return nil
+}
+
+// follow counts the records delivered by the follow stream until the stream is closed.
+func (s *Store) follow(ctx context.Context) {
+	defer close(s.done)
+	for {
+		var rec record
+		if err := s.conn.ReadJSON(&rec); err != nil {
+			if ctx.Err() == nil {
+				s.mu.Lock()
+				s.followErr = err
+				s.mu.Unlock()
+			}
+			return
+		}
+		lvl := fromRecord(rec).Level
+		s.mu.Lock()
+		for filterLvl := zerolog.TraceLevel; filterLvl <= lvl; filterLvl++ {
+			s.newRecords[filterLvl]++
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *Store) endpoint(name string, query url.Values) *url.URL {
+	u := *s.baseURL
+	u.Path += apiPrefix + name
+	if s.file != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("file", s.file)
+	}
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+func (s *Store) header() http.Header {
+	return http.Header{"Authorization": {"Bearer " + s.token}}
+}
+
+func (s *Store) get(ctx context.Context, name string, query url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint(name, query).String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header = s.header()
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// decodeError converts the error response of the server to the corresponding error of the logstore package.
+func decodeError(resp *http.Response) error {
+	var errResp errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	switch errResp.Code {
+	case codeOutOfRange:
+		return logstore.ErrOutOfRange
+	case codeNotFound:
+		return logstore.ErrNotFound
+	case codeNoRecords:
+		lvl, _ := zerolog.ParseLevel(errResp.Level)
+		return &logstore.NoRecordsError{Level: lvl}
+	}
+	return errors.New(errResp.Error)
+}
+
+func fromRecord(rec record) logstore.Record {
+	lvl, err := zerolog.ParseLevel(rec.Level)
+	if err != nil {
+		lvl = zerolog.NoLevel
+	}
+	return logstore.Record{Level: lvl, Offset: rec.Offset, Data: []byte(rec.Data)}
+}
@@ -0,0 +1,176 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logstore"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+func newTestServer(t *testing.T, path string) *httptest.Server {
+	store, err := logstore.Open(path, logstore.Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	ts := httptest.NewServer(NewServer(zerolog.Nop(), store, testToken, 10*time.Millisecond))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestServer_auth(t *testing.T) {
+	ts := newTestServer(t, "./testdata/test.log")
+
+	tests := []struct {
+		name   string
+		url    string
+		header string
+		want   int
+	}{
+		{name: "no token", url: "/api/v1/info", want: http.StatusUnauthorized},
+		{name: "wrong token", url: "/api/v1/info?token=guess", want: http.StatusUnauthorized},
+		{name: "query token", url: "/api/v1/info?token=" + testToken, want: http.StatusOK},
+		{name: "bearer token", url: "/api/v1/info", header: "Bearer " + testToken, want: http.StatusOK},
+		{name: "invalid parameter", url: "/api/v1/records?level=loud", header: "Bearer " + testToken, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, ts.URL+tt.url, nil)
+			require.NoError(t, err)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.want, resp.StatusCode)
+		})
+	}
+}
+
+func TestServer_query(t *testing.T) {
+	ts := newTestServer(t, "./testdata/test.log")
+
+	resp, err := http.Get(ts.URL + "/api/v1/query?token=" + testToken + "&level=info&from=9&backward=true&limit=2&field=worker=runLogViewer")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var got recordsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, 10, got.Total)
+	require.Len(t, got.Records, 2)
+	assert.Greater(t, got.Records[0].Index, got.Records[1].Index)
+	for _, rec := range got.Records {
+		assert.Contains(t, rec.Data, `"worker":"runLogViewer"`)
+	}
+}
+
+func TestStore(t *testing.T) {
+	ts := newTestServer(t, "./testdata/test.log")
+	ctx := context.Background()
+
+	_, err := Dial(ctx, ts.URL, "guess")
+	assert.EqualError(t, err, "missing or invalid token")
+
+	s, err := Dial(ctx, ts.URL+"/?token="+testToken, "")
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, "./testdata/test.log", s.Path())
+	assert.Equal(t, 22, s.Len(zerolog.TraceLevel))
+	assert.Equal(t, 2, s.Len(zerolog.WarnLevel))
+
+	records, err := s.Range(zerolog.WarnLevel, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, zerolog.WarnLevel, records[0].Level)
+	assert.Contains(t, string(records[0].Data), "turning off gui due to context cancellation")
+
+	_, err = s.Range(zerolog.WarnLevel, 1, 5)
+	assert.ErrorIs(t, err, logstore.ErrOutOfRange)
+	_, err = s.Range(zerolog.FatalLevel, 0, 1)
+	assert.Equal(t, &logstore.NoRecordsError{Level: zerolog.FatalLevel}, err)
+
+	i, err := s.Search(ctx, regexp.MustCompile("viewer ended"), zerolog.TraceLevel, -1, false)
+	require.NoError(t, err)
+	assert.Equal(t, 21, i)
+	_, err = s.Search(ctx, regexp.MustCompile("no such record"), zerolog.TraceLevel, -1, false)
+	assert.ErrorIs(t, err, logstore.ErrNotFound)
+}
+
+func TestStore_Update(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"info","message":"first"}`+"\n"), 0o600))
+	ts := newTestServer(t, path)
+
+	s, err := Dial(context.Background(), ts.URL, testToken)
+	require.NoError(t, err)
+	defer s.Close()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"level":"debug","message":"second"}` + "\n" + `{"level":"error","message":"third"}` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	want := map[zerolog.Level]int{zerolog.TraceLevel: 2, zerolog.DebugLevel: 2, zerolog.InfoLevel: 1, zerolog.WarnLevel: 1, zerolog.ErrorLevel: 1}
+	var got map[zerolog.Level]int
+	require.Eventually(t, func() bool {
+		newRecords, err := s.Update()
+		require.NoError(t, err)
+		if got == nil && len(newRecords) > 0 {
+			got = make(map[zerolog.Level]int)
+		}
+		for lvl, n := range newRecords {
+			got[lvl] += n
+		}
+		return got[zerolog.TraceLevel] == 2
+	}, time.Second, 20*time.Millisecond)
+	assert.Equal(t, want, got)
+	assert.Equal(t, 3, s.Len(zerolog.TraceLevel))
+	assert.Equal(t, 1, s.Len(zerolog.ErrorLevel))
+}
+
+func TestServer_files(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"warn","message":"other"}`+"\n"), 0o600))
+	first, err := logstore.Open("./testdata/test.log", logstore.Options{})
+	require.NoError(t, err)
+	defer first.Close()
+	other, err := logstore.Open(path, logstore.Options{})
+	require.NoError(t, err)
+	defer other.Close()
+	ts := httptest.NewServer(NewServer(zerolog.Nop(), first, testToken, 10*time.Millisecond).WithStore(other))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/info?token=" + testToken + "&file=" + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var got infoResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, path, got.Path)
+	assert.Equal(t, []string{"./testdata/test.log", path}, got.Files)
+	assert.Equal(t, 1, got.Totals["warn"])
+	assert.Equal(t, recordKeys{Level: "level", Time: "time", Message: "message", Caller: "caller"}, got.Keys)
+
+	resp, err = http.Get(ts.URL + "/api/v1/info?token=" + testToken + "&file=/etc/passwd")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	s, err := Dial(context.Background(), ts.URL+"?file="+path, testToken)
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, path, s.Path())
+	assert.Equal(t, 1, s.Len(zerolog.TraceLevel))
+}
@@ -0,0 +1,429 @@
+// Package remote serves the records of a log file over HTTP (and WebSocket for following the file) and provides
+// the client side of the protocol, so that the viewer can show a log file of another machine.
+//
+// All the endpoints require the token - either in the "Authorization: Bearer <token>" header or in the token query parameter.
+// The server can serve several files, the file query parameter selects one of them (the first one by default).
+//
+//	GET /api/v1/info                                         - the served files, the numbers of the records per level and the record keys
+//	GET /api/v1/records?level=&from=&to=                     - the records of the level with the indices [from, to)
+//	GET /api/v1/query?level=&from=&backward=&limit=&filter…  - up to limit records passing the filter (pattern, field, since, until)
+//	GET /api/v1/search?level=&from=&backward=&pattern=       - the index of the nearest record matching the pattern
+//	GET /api/v1/follow?level=                                - WebSocket delivering the records appended to the file
+package remote
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/matusvla/logviewer/pkg/logstore"
+	"github.com/rs/zerolog"
+)
+
+const (
+	apiPrefix     = "/api/v1/"
+	maxQueryLimit = 1000
+)
+
+// error codes of the API, the client converts them back to the errors of the logstore package
+const (
+	codeUnauthorized = "unauthorized"
+	codeBadRequest   = "bad_request"
+	codeOutOfRange   = "out_of_range"
+	codeNoRecords    = "no_records"
+	codeNotFound     = "not_found"
+	codeInternal     = "internal"
+)
+
+var levels = []zerolog.Level{
+	zerolog.TraceLevel, zerolog.DebugLevel, zerolog.InfoLevel, zerolog.WarnLevel,
+	zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel,
+}
+
+type infoResponse struct {
+	Path   string         `json:"path"`
+	Files  []string       `json:"files"`
+	Totals map[string]int `json:"totals"`
+	Keys   recordKeys     `json:"keys"`
+}
+
+// recordKeys are the keys of the special fields of the records
+type recordKeys struct {
+	Level   string `json:"level"`
+	Time    string `json:"time"`
+	Message string `json:"message"`
+	Caller  string `json:"caller"`
+}
+
+type record struct {
+	Index  int    `json:"index"`
+	Level  string `json:"level"`
+	Offset int64  `json:"offset"`
+	Data   string `json:"data"`
+}
+
+type recordsResponse struct {
+	Records []record `json:"records"`
+	Total   int      `json:"total"`
+}
+
+type searchResponse struct {
+	Index int `json:"index"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+	// Level is the level without records for the no_records code
+	Level string `json:"level,omitempty"`
+}
+
+// Server serves the records of the stores. The stores are kept up to date by the Run method.
+type Server struct {
+	log            zerolog.Logger
+	stores         map[string]*logstore.Store
+	files          []string // paths of the stores in the order they were added
+	token          string
+	followInterval time.Duration
+	mapping        prettyprint.FieldMapping
+	mux            *http.ServeMux
+	upgrader       websocket.Upgrader
+}
+
+func NewServer(log zerolog.Logger, store *logstore.Store, token string, followInterval time.Duration) *Server {
+	s := &Server{
+		log:            log,
+		stores:         map[string]*logstore.Store{store.Path(): store},
+		files:          []string{store.Path()},
+		token:          token,
+		followInterval: followInterval,
+		mux:            http.NewServeMux(),
+		upgrader: websocket.Upgrader{
+			// the requests are authorized by the token, so any origin (e.g. a web UI served elsewhere) is allowed
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+	}
+	s.mux.HandleFunc(apiPrefix+"info", s.handleInfo)
+	s.mux.HandleFunc(apiPrefix+"records", s.handleRecords)
+	s.mux.HandleFunc(apiPrefix+"query", s.handleQuery)
+	s.mux.HandleFunc(apiPrefix+"search", s.handleSearch)
+	s.mux.HandleFunc(apiPrefix+"follow", s.handleFollow)
+	return s
+}
+
+// WithStore adds another log file to the served ones.
+func (s *Server) WithStore(store *logstore.Store) *Server {
+	if _, ok := s.stores[store.Path()]; !ok {
+		s.files = append(s.files, store.Path())
+	}
+	s.stores[store.Path()] = store
+	return s
+}
+
+// WithFieldMapping sets the keys of the special fields used by the query filters.
+func (s *Server) WithFieldMapping(mapping prettyprint.FieldMapping) *Server {
+	s.mapping = mapping
+	return s
+}
+
+// Run indexes the records appended to the files every follow interval until the context is done.
+func (s *Server) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.followInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			for _, path := range s.files {
+				if _, err := s.stores[path].Update(); err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, codeUnauthorized, errors.New("missing or invalid token"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, codeBadRequest, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// store returns the store selected by the file parameter, the first one if it is not set
+func (s *Server) store(p *params) *logstore.Store {
+	path := p.get("file")
+	if path == "" {
+		return s.stores[s.files[0]]
+	}
+	store, ok := s.stores[path]
+	if !ok {
+		p.setErr("file", fmt.Errorf("unknown file %q", path))
+	}
+	return store
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	p := params{values: r.URL.Query()}
+	store := s.store(&p)
+	if p.err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, p.err)
+		return
+	}
+	resp := infoResponse{
+		Path:   store.Path(),
+		Files:  s.files,
+		Totals: make(map[string]int, len(levels)),
+		Keys: recordKeys{
+			Level:   s.mapping.LevelKey(),
+			Time:    s.mapping.TimeKey(),
+			Message: s.mapping.MessageKey(),
+			Caller:  s.mapping.CallerKey(),
+		},
+	}
+	for _, lvl := range levels {
+		resp.Totals[lvl.String()] = store.Len(lvl)
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleRecords(w http.ResponseWriter, r *http.Request) {
+	p := params{values: r.URL.Query()}
+	store, lvl, from, to := s.store(&p), p.level("level"), p.int("from", 0), p.int("to", 0)
+	if p.err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, p.err)
+		return
+	}
+	records, err := store.Range(lvl, from, to)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	resp := recordsResponse{Records: make([]record, 0, len(records)), Total: store.Len(lvl)}
+	for i, rec := range records {
+		resp.Records = append(resp.Records, toRecord(from+i, rec))
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	p := params{values: r.URL.Query()}
+	store, lvl, from, backward, limit := s.store(&p), p.level("level"), p.int("from", 0), p.bool("backward"), p.int("limit", 100)
+	filter := p.filter()
+	if p.err == nil && (limit <= 0 || limit > maxQueryLimit) {
+		p.err = fmt.Errorf("limit: must be between 1 and %d", maxQueryLimit)
+	}
+	if p.err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, p.err)
+		return
+	}
+	it := store.Iter(lvl, from, backward).WithContext(r.Context())
+	if !filter.IsZero() {
+		it = it.Filter(logstore.MatchFilter(filter, s.mapping))
+	}
+	resp := recordsResponse{Records: []record{}, Total: store.Len(lvl)}
+	for len(resp.Records) < limit && it.Next() {
+		resp.Records = append(resp.Records, toRecord(it.Index(), it.Record()))
+	}
+	if err := it.Err(); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	p := params{values: r.URL.Query()}
+	store, lvl, from, backward, re := s.store(&p), p.level("level"), p.int("from", 0), p.bool("backward"), p.regexp("pattern")
+	if p.err == nil && re == nil {
+		p.err = errors.New("pattern: missing")
+	}
+	if p.err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, p.err)
+		return
+	}
+	i, err := store.Search(r.Context(), re, lvl, from, backward)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, searchResponse{Index: i})
+}
+
+func (s *Server) handleFollow(w http.ResponseWriter, r *http.Request) {
+	p := params{values: r.URL.Query()}
+	store, lvl := s.store(&p), p.level("level")
+	if p.err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, p.err)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // the upgrader has already responded
+	}
+	defer conn.Close()
+
+	ctx, cancelFn := context.WithCancel(r.Context())
+	defer cancelFn()
+	go func() {
+		// the client does not send anything, reading just detects the closed connection
+		defer cancelFn()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	follower := store.Follow(ctx, lvl, s.followInterval)
+	for rec := range follower.C {
+		if err := conn.WriteJSON(toRecord(-1, rec)); err != nil {
+			s.log.Debug().Err(err).Msg("follow stream ended")
+			return
+		}
+	}
+	if err := follower.Err(); err != nil {
+		s.log.Error().Err(err).Msg("following the log file failed")
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()), time.Now().Add(time.Second))
+	}
+}
+
+// toRecord converts the record to its JSON representation, the index of the followed records is unknown (-1).
+func toRecord(i int, rec logstore.Record) record {
+	return record{Index: i, Level: rec.Level.String(), Offset: rec.Offset, Data: string(rec.Data)}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, err error) {
+	writeErrorResponse(w, status, errorResponse{Error: err.Error(), Code: code})
+}
+
+func writeErrorResponse(w http.ResponseWriter, status int, resp errorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	var noRecordsErr *logstore.NoRecordsError
+	switch {
+	case errors.Is(err, logstore.ErrOutOfRange):
+		writeError(w, http.StatusRequestedRangeNotSatisfiable, codeOutOfRange, err)
+	case errors.As(err, &noRecordsErr):
+		writeErrorResponse(w, http.StatusNotFound, errorResponse{Error: err.Error(), Code: codeNoRecords, Level: noRecordsErr.Level.String()})
+	case errors.Is(err, logstore.ErrNotFound):
+		writeError(w, http.StatusNotFound, codeNotFound, err)
+	default:
+		writeError(w, http.StatusInternalServerError, codeInternal, err)
+	}
+}
+
+// params parses the query parameters, the first error is kept
+type params struct {
+	values map[string][]string
+	err    error
+}
+
+func (p *params) get(name string) string {
+	if v := p.values[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (p *params) setErr(name string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%s: %w", name, err)
+	}
+}
+
+func (p *params) level(name string) zerolog.Level {
+	v := p.get(name)
+	if v == "" {
+		return zerolog.TraceLevel
+	}
+	lvl, err := zerolog.ParseLevel(v)
+	if err != nil || lvl < zerolog.TraceLevel || lvl > zerolog.PanicLevel {
+		p.setErr(name, fmt.Errorf("unknown level %q", v))
+	}
+	return lvl
+}
+
+func (p *params) int(name string, def int) int {
+	v := p.get(name)
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		p.setErr(name, err)
+	}
+	return i
+}
+
+func (p *params) bool(name string) bool {
+	v := p.get(name)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.setErr(name, err)
+	}
+	return b
+}
+
+func (p *params) regexp(name string) *regexp.Regexp {
+	v := p.get(name)
+	if v == "" {
+		return nil
+	}
+	re, err := regexp.Compile(v)
+	if err != nil {
+		p.setErr(name, err)
+	}
+	return re
+}
+
+func (p *params) filter() prettyprint.RecordFilter {
+	var filter prettyprint.RecordFilter
+	var err error
+	now := time.Now()
+	filter.Pattern = p.regexp("pattern")
+	if filter.Fields, err = prettyprint.ParseFilterFields(p.get("field")); err != nil {
+		p.setErr("field", err)
+	}
+	if filter.Since, err = prettyprint.ParseFilterTime(p.get("since"), now); err != nil {
+		p.setErr("since", err)
+	}
+	if filter.Until, err = prettyprint.ParseFilterTime(p.get("until"), now); err != nil {
+		p.setErr("until", err)
+	}
+	return filter
+}
@@ -1,146 +1,223 @@
 package viewer
 
 import (
-	"bufio"
 	"bytes"
-	"errors"
-	"fmt"
-	"io"
-	"os"
+	"context"
 	"regexp"
+	"strings"
+	"time"
 
+	"github.com/matusvla/logviewer/internal/config"
+	"github.com/matusvla/logviewer/internal/model"
+	"github.com/matusvla/logviewer/internal/remote"
 	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+	"github.com/matusvla/logviewer/pkg/logstore"
 	"github.com/rs/zerolog"
 )
 
-const (
-	maxOffsetListSize  = 5000
-	trimOffsetListSize = 1000
-)
+// recordSource provides the records of the log file - it is either the local logstore.Store or the remote.Store
+// of a file served by the serve command.
+type recordSource interface {
+	Len(lvl zerolog.Level) int
+	Range(lvl zerolog.Level, from, to int) ([]logstore.Record, error)
+	Update() (map[zerolog.Level]int, error)
+	Search(ctx context.Context, re *regexp.Regexp, lvl zerolog.Level, from int, backward bool) (int, error)
+	Close() error
+}
 
+// logViewer renders the records of the log file indexed by the logstore.Store. The records are addressed
+// by their offset from the newest one, as the terminal UI shows the newest records at the bottom.
 type logViewer struct {
-	log           zerolog.Logger
-	file          *os.File
-	offsetListMap map[zerolog.Level][]int64
+	log         zerolog.Logger
+	theme       theme.Theme
+	config      *config.Config
+	remoteToken string
+	highlights  []prettyprint.HighlightRule
+	search      *regexp.Regexp
+	revealed    bool // the redacted values are shown as they are
+	relative    prettyprint.RelativeTime
+	anchor      time.Time // of RelativeToAnchor
+	store       recordSource
 }
 
-func newLogViewer(log zerolog.Logger) *logViewer {
+func newLogViewer(log zerolog.Logger, th theme.Theme, cfg *config.Config) *logViewer {
 	return &logViewer{
-		log:           log,
-		offsetListMap: make(map[zerolog.Level][]int64),
+		log:        log,
+		theme:      th,
+		config:     cfg,
+		highlights: cfg.HighlightRules(),
+		relative:   cfg.PrintOptions().Relative,
+	}
+}
+
+// Open opens the log file. An http:// or https:// URL is opened as the file served by the serve command.
+func (lv *logViewer) Open(ctx context.Context, logFilePath string) error {
+	var store recordSource
+	var err error
+	if isRemote(logFilePath) {
+		store, err = remote.Dial(ctx, logFilePath, lv.remoteToken)
+	} else {
+		store, err = logstore.Open(logFilePath, logstore.Options{LevelKey: lv.config.FieldMapping.LevelKey()})
+	}
+	if err != nil {
+		return err
 	}
+	lv.store = store
+	return nil
+}
+
+func isRemote(logFilePath string) bool {
+	return strings.HasPrefix(logFilePath, "http://") || strings.HasPrefix(logFilePath, "https://")
+}
+
+func (lv *logViewer) SetHighlightRules(rules []prettyprint.HighlightRule) {
+	lv.highlights = rules
 }
 
-var levelRe = regexp.MustCompile(`"level":"(trace|debug|info|warn|error|fatal|panic)"`)
+// SetReveal switches the revealing of the redacted values on or off, if the configuration permits it.
+func (lv *logViewer) SetReveal(reveal bool) error {
+	if reveal && !lv.config.Redaction.AllowReveal {
+		return model.ErrRevealNotPermitted
+	}
+	lv.revealed = reveal
+	return nil
+}
 
-func (lv *logViewer) Open(logFilePath string) error {
-	f, err := os.Open(logFilePath)
+// SetRelativeTime switches the times of the rendered records to the ones relative to the previous record or to the time
+// of the anchor record at the offset from the end among the records of the level, or back to the times of the records.
+func (lv *logViewer) SetRelativeTime(relative prettyprint.RelativeTime, anchorOffsetFromEnd int, logLvl zerolog.Level) error {
+	if relative != prettyprint.RelativeToAnchor {
+		lv.relative, lv.anchor = relative, time.Time{}
+		return nil
+	}
+	if lv.store == nil {
+		return model.ErrNotOpen
+	}
+	i := lv.store.Len(logLvl) - 1 - anchorOffsetFromEnd
+	if i < 0 {
+		return model.ErrOutOfRange
+	}
+	records, err := lv.store.Range(logLvl, i, i+1)
+	if err != nil {
+		return err
+	}
+	anchor, err := prettyprint.RecordTime(string(records[0].Data), lv.config.FieldMapping)
 	if err != nil {
 		return err
 	}
-	lv.file = f
-	_, err = lv.updateOffsets(0, zerolog.TraceLevel)
-	return err
+	lv.relative, lv.anchor = relative, anchor
+	return nil
 }
 
 func (lv *logViewer) Close() error {
-	if lv.file != nil {
-		if err := lv.file.Close(); err != nil {
-			return err
-		}
+	lv.search = nil
+	if lv.relative == prettyprint.RelativeToAnchor {
+		lv.relative, lv.anchor = prettyprint.RelativeOff, time.Time{}
 	}
-	lv.offsetListMap = make(map[zerolog.Level][]int64)
-	return nil
+	if lv.store == nil {
+		return nil
+	}
+	store := lv.store
+	lv.store = nil
+	return store.Close()
 }
 
+// Get renders lineCount records of the level ending with the one at the offset from the end. It returns the number
+// of the records of the level appended to the file since the last call as well.
 func (lv *logViewer) Get(lineOffsetFromEnd, lineCount int, logLvl zerolog.Level) ([]byte, int, error) {
-	if lv.file == nil {
-		return nil, 0, errors.New("no file open for get")
+	if lv.store == nil {
+		return nil, 0, model.ErrNotOpen
 	}
-	offsetList := lv.offsetListMap[logLvl]
-	offsetListLen := len(offsetList)
-	if offsetListLen == 0 {
-		return nil, 0, fmt.Errorf("no records for the level %s", logLvl.String())
+	total := lv.store.Len(logLvl)
+	if total == 0 {
+		// the records might have been appended to the file since the last update, e.g. to an empty followed file
+		if _, err := lv.store.Update(); err != nil {
+			return nil, 0, err
+		}
+		if total = lv.store.Len(logLvl); total == 0 {
+			return nil, 0, &model.NoRecordsError{Level: logLvl}
+		}
 	}
-	soIndex := offsetListLen - 1 - lineOffsetFromEnd - lineCount
-	eoIndex := offsetListLen - 1 - lineOffsetFromEnd
-
-	if eoIndex < 0 {
-		return nil, 0, io.EOF
+	end := total - 1 - lineOffsetFromEnd
+	if end < 0 || end >= total {
+		return nil, 0, model.ErrOutOfRange
 	}
-	if eoIndex > len(offsetList)-1 {
-		return nil, 0, io.EOF
+	start := end - lineCount + 1
+	if start < 0 {
+		start = 0
 	}
-	var startOffset int64
-	if soIndex > 0 {
-		startOffset = offsetList[soIndex]
+	from := start
+	if lv.relative == prettyprint.RelativeToPrevious && from > 0 {
+		from-- // the time of the first record is relative to the preceding one
 	}
-	endOffset := offsetList[eoIndex]
-	b := make([]byte, endOffset-1-startOffset)
-	if _, err := lv.file.ReadAt(b, startOffset); err != nil {
+	records, err := lv.store.Range(logLvl, from, end+1)
+	if err != nil {
 		return nil, 0, err
 	}
 
 	bb := bytes.NewBuffer([]byte{})
-	out := prettyprint.NewOutput(bb, logLvl, 30)
-	for _, b := range bytes.Split(b, []byte("\n")) {
-		if err := out.ProcessLine(string(b)); err != nil {
+	out := lv.config.NewOutput(bb, logLvl, lv.theme).WithHighlightRules(lv.highlightRules()...)
+	if lv.revealed {
+		out = out.WithRedaction(nil)
+	}
+	opts := out.Options()
+	opts.Relative, opts.Anchor = lv.relative, lv.anchor
+	out = out.WithOptions(opts)
+	if from < start {
+		out.Observe(string(records[0].Data))
+		records = records[1:]
+	}
+	for _, rec := range records {
+		if err := out.ProcessLine(string(rec.Data)); err != nil {
 			return nil, 0, err
 		}
 	}
-	newLines, err := lv.updateOffsets(lv.offsetListMap[zerolog.TraceLevel][len(lv.offsetListMap[zerolog.TraceLevel])-1], logLvl)
+	newRecords, err := lv.store.Update()
 	if err != nil {
 		return nil, 0, err
 	}
-	return bytes.TrimSpace(bb.Bytes()), newLines, nil
+	return bytes.TrimSpace(bb.Bytes()), newRecords[logLvl], nil
 }
 
-func (lv *logViewer) updateOffsets(fromOffset int64, newLinesLogLvl zerolog.Level) (int, error) {
-	if _, err := lv.file.Seek(fromOffset, io.SeekStart); err != nil {
-		return 0, err
+// Total returns the number of records of the level.
+func (lv *logViewer) Total(logLvl zerolog.Level) int {
+	if lv.store == nil {
+		return 0
 	}
+	return lv.store.Len(logLvl)
+}
 
-	var newLinesCount int
-	scanner := bufio.NewScanner(lv.file)
-	for scanner.Scan() {
-		t := scanner.Bytes()
-		fromOffset += int64(len(t)) + 1
-		reResult := levelRe.FindSubmatch(t)
-		if len(reResult) < 2 {
-			continue
-		}
+// Search returns the offset from the end of the nearest record of the level matching the pattern, which follows
+// (or precedes if backward is set) the record at the given offset. The pattern stays highlighted in the records returned by Get.
+func (lv *logViewer) Search(ctx context.Context, pattern string, lineOffsetFromEnd int, backward bool, logLvl zerolog.Level) (int, error) {
+	if lv.store == nil {
+		return 0, model.ErrNotOpen
+	}
+	if pattern == "" {
+		lv.search = nil
+		return lineOffsetFromEnd, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return 0, err
+	}
+	lv.search = re
+	total := lv.store.Len(logLvl)
+	i, err := lv.store.Search(ctx, re, logLvl, total-1-lineOffsetFromEnd, backward)
+	if err != nil {
+		return 0, err
+	}
+	return total - 1 - i, nil
+}
 
-		lvl, _ := zerolog.ParseLevel(string(reResult[1]))
-		switch lvl {
-		case zerolog.PanicLevel:
-			lv.offsetListMap[zerolog.PanicLevel] = append(lv.offsetListMap[zerolog.PanicLevel], fromOffset)
-			fallthrough
-		case zerolog.FatalLevel:
-			lv.offsetListMap[zerolog.FatalLevel] = append(lv.offsetListMap[zerolog.FatalLevel], fromOffset)
-			fallthrough
-		case zerolog.ErrorLevel:
-			lv.offsetListMap[zerolog.ErrorLevel] = append(lv.offsetListMap[zerolog.ErrorLevel], fromOffset)
-			fallthrough
-		case zerolog.WarnLevel:
-			lv.offsetListMap[zerolog.WarnLevel] = append(lv.offsetListMap[zerolog.WarnLevel], fromOffset)
-			fallthrough
-		case zerolog.InfoLevel:
-			lv.offsetListMap[zerolog.InfoLevel] = append(lv.offsetListMap[zerolog.InfoLevel], fromOffset)
-			fallthrough
-		case zerolog.DebugLevel:
-			lv.offsetListMap[zerolog.DebugLevel] = append(lv.offsetListMap[zerolog.DebugLevel], fromOffset)
-			fallthrough
-		default:
-			lv.offsetListMap[zerolog.TraceLevel] = append(lv.offsetListMap[zerolog.TraceLevel], fromOffset)
-		}
-		for i := range lv.offsetListMap {
-			if len(lv.offsetListMap[i]) > maxOffsetListSize {
-				lv.offsetListMap[i] = lv.offsetListMap[i][trimOffsetListSize:]
-			}
-		}
-		if lvl >= newLinesLogLvl {
-			newLinesCount++
-		}
+// highlightRules returns the configured highlight rules together with the one for the searched pattern.
+func (lv *logViewer) highlightRules() []prettyprint.HighlightRule {
+	if lv.search == nil {
+		return lv.highlights
 	}
-	return newLinesCount, nil
+	return append(append([]prettyprint.HighlightRule(nil), lv.highlights...), prettyprint.HighlightRule{
+		Pattern: lv.search.String(),
+		Style:   lv.theme.UI.Accent,
+	})
 }
@@ -1,13 +1,20 @@
 package viewer
 
 import (
+	"context"
 	"errors"
-	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/matusvla/logviewer/internal/config"
+	"github.com/matusvla/logviewer/internal/model"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLogViewer_Get(t *testing.T) {
@@ -34,7 +41,7 @@ func TestLogViewer_Get(t *testing.T) {
 				logLvl:            zerolog.TraceLevel,
 			},
 			want: want{
-				output: "\x1b[90m21:47:55.235_767_000\x1b[0m \x1b[32mINF\x1b[0m ...nternal/viewer/viewer.go:71\x1b[36m > \x1b[0m cui subsystem Run method finished \x1b[34mcomponent=\x1b[0mbackend \x1b[35mmodule=\x1b[0mviewer\n\x1b[90m21:47:55.235_775_000\x1b[0m \x1b[32mINF\x1b[0m ...folio/cmd/viewer/main.go:47\x1b[36m > \x1b[0m viewer ended \x1b[35mmodule=\x1b[0mviewer",
+				output: "\x1b[90m21:47:55.235_767_000\x1b[0m \x1b[32mINF\x1b[0m ...nternal/viewer/viewer.go:71\x1b[36m > \x1b[0m cui subsystem Run method finished \x1b[35mmodule=\x1b[0mviewer \x1b[34mcomponent=\x1b[0mbackend \x1b[34mts=\x1b[0m1650743275235767000\n\x1b[90m21:47:55.235_775_000\x1b[0m \x1b[32mINF\x1b[0m ...iewer/cmd/viewer/main.go:47\x1b[36m > \x1b[0m viewer ended \x1b[35mmodule=\x1b[0mviewer \x1b[34mts=\x1b[0m1650743275235775000",
 			},
 		},
 		{
@@ -46,7 +53,7 @@ func TestLogViewer_Get(t *testing.T) {
 			},
 			want: want{
 				output: "",
-				err:    io.EOF,
+				err:    model.ErrOutOfRange,
 			},
 		},
 		{
@@ -58,7 +65,7 @@ func TestLogViewer_Get(t *testing.T) {
 			},
 			want: want{
 				output: "",
-				err:    io.EOF,
+				err:    model.ErrOutOfRange,
 			},
 		},
 		{
@@ -70,7 +77,7 @@ func TestLogViewer_Get(t *testing.T) {
 			},
 			want: want{
 				output: "",
-				err:    errors.New("no records for the level fatal"),
+				err:    &model.NoRecordsError{Level: zerolog.FatalLevel},
 			},
 		},
 		{
@@ -81,14 +88,14 @@ func TestLogViewer_Get(t *testing.T) {
 				logLvl:            zerolog.WarnLevel,
 			},
 			want: want{
-				output: "\x1b[90m21:47:55.235_653_000\x1b[0m \x1b[31mWRN\x1b[0m ...olio/internal/cui/cui.go:89\x1b[36m > \x1b[0m turning off gui due to context cancellation \x1b[34mcomponent=\x1b[0mcui \x1b[35mmodule=\x1b[0mviewer\n\x1b[90m21:47:55.235_734_000\x1b[0m \x1b[1m\x1b[31mERR\x1b[0m\x1b[0m ...nternal/viewer/viewer.go:67\x1b[36m > \x1b[0m cui subsystem ended, cancelling context \x1b[34mcomponent=\x1b[0mbackend \x1b[35mmodule=\x1b[0mviewer",
+				output: "\x1b[90m21:47:55.235_653_000\x1b[0m \x1b[31mWRN\x1b[0m ...ewer/internal/cui/cui.go:89\x1b[36m > \x1b[0m turning off gui due to context cancellation \x1b[35mmodule=\x1b[0mviewer \x1b[34mcomponent=\x1b[0mcui \x1b[34mts=\x1b[0m1650743275235653000\n\x1b[90m21:47:55.235_734_000\x1b[0m \x1b[1m\x1b[31mERR\x1b[0m\x1b[0m ...nternal/viewer/viewer.go:67\x1b[36m > \x1b[0m cui subsystem ended, cancelling context \x1b[35mmodule=\x1b[0mviewer \x1b[34mcomponent=\x1b[0mbackend \x1b[34mts=\x1b[0m1650743275235734000",
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			lv := newLogViewer(zerolog.New(os.Stdout))
-			assert.NoError(t, lv.Open("./testdata/test.log"))
+			lv := newLogViewer(zerolog.New(os.Stdout), theme.Dark(), config.Default())
+			assert.NoError(t, lv.Open(context.Background(), "./testdata/test.log"))
 			result, _, err := lv.Get(tt.args.lineOffsetFromEnd, tt.args.lineCount, tt.args.logLvl)
 			assert.Equal(t, tt.want.err, err, "error")
 			assert.Equal(t, tt.want.output, string(result), "output")
@@ -96,3 +103,161 @@ func TestLogViewer_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestLogViewer_Search(t *testing.T) {
+	type args struct {
+		pattern           string
+		lineOffsetFromEnd int
+		backward          bool
+		logLvl            zerolog.Level
+	}
+	type want struct {
+		offset int
+		err    error
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "forward",
+			args: args{pattern: `"window":"About"`, lineOffsetFromEnd: 21, logLvl: zerolog.TraceLevel},
+			want: want{offset: 18},
+		},
+		{
+			name: "backward",
+			args: args{pattern: `"window":"About"`, lineOffsetFromEnd: 0, backward: true, logLvl: zerolog.TraceLevel},
+			want: want{offset: 7},
+		},
+		{
+			name: "level filter",
+			args: args{pattern: `"component":"backend"`, lineOffsetFromEnd: 4, logLvl: zerolog.WarnLevel},
+			want: want{offset: 0},
+		},
+		{
+			name: "not found",
+			args: args{pattern: `"window":"About"`, lineOffsetFromEnd: 7, logLvl: zerolog.TraceLevel},
+			want: want{err: model.ErrNotFound},
+		},
+		{
+			name: "invalid pattern",
+			args: args{pattern: `(`, logLvl: zerolog.TraceLevel},
+			want: want{err: errors.New("error parsing regexp: missing closing ): `(`")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lv := newLogViewer(zerolog.New(os.Stdout), theme.Dark(), config.Default())
+			assert.NoError(t, lv.Open(context.Background(), "./testdata/test.log"))
+			offset, err := lv.Search(context.Background(), tt.args.pattern, tt.args.lineOffsetFromEnd, tt.args.backward, tt.args.logLvl)
+			if tt.want.err != nil {
+				assert.EqualError(t, err, tt.want.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want.offset, offset, "offset")
+			assert.NoError(t, lv.Close())
+		})
+	}
+}
+
+func TestLogViewer_Get_appendedToEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.log")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	cfg := config.Default()
+	lv := newLogViewer(zerolog.New(os.Stdout), theme.Monochrome(), cfg)
+	require.NoError(t, lv.Open(context.Background(), path))
+	defer lv.Close()
+
+	_, _, err := lv.Get(0, 10, zerolog.TraceLevel)
+	assert.Equal(t, &model.NoRecordsError{Level: zerolog.TraceLevel}, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"info","message":"first"}`+"\n"), 0o600))
+	result, _, err := lv.Get(0, 10, zerolog.TraceLevel)
+	require.NoError(t, err)
+	assert.Contains(t, string(result), "first")
+}
+
+func TestLogViewer_SetReveal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"info","message":"login","password":"hunter2"}`+"\n"), 0o600))
+
+	tests := []struct {
+		name        string
+		allowReveal bool
+		wantErr     error
+		wantSecret  bool
+	}{
+		{
+			name:        "reveal permitted",
+			allowReveal: true,
+			wantSecret:  true,
+		},
+		{
+			name:    "reveal not permitted",
+			wantErr: model.ErrRevealNotPermitted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Redaction.Enabled = true
+			cfg.Redaction.AllowReveal = tt.allowReveal
+			lv := newLogViewer(zerolog.New(os.Stdout), theme.Monochrome(), cfg)
+			require.NoError(t, lv.Open(context.Background(), path))
+			defer lv.Close()
+
+			result, _, err := lv.Get(0, 10, zerolog.TraceLevel)
+			require.NoError(t, err)
+			assert.NotContains(t, string(result), "hunter2")
+
+			assert.Equal(t, tt.wantErr, lv.SetReveal(true))
+			result, _, err = lv.Get(0, 10, zerolog.TraceLevel)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSecret, strings.Contains(string(result), "hunter2"))
+
+			require.NoError(t, lv.SetReveal(false))
+			result, _, err = lv.Get(0, 10, zerolog.TraceLevel)
+			require.NoError(t, err)
+			assert.NotContains(t, string(result), "hunter2")
+		})
+	}
+}
+
+func TestLogViewer_SetRelativeTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool.log")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+		`{"level":"info","message":"no time"}`,
+		`{"level":"info","time":"2022-05-22T10:00:00Z","message":"started"}`,
+		`{"level":"info","time":"2022-05-22T10:00:01.5Z","message":"connected"}`,
+		`{"level":"info","time":"2022-05-22T10:00:04Z","message":"done"}`,
+	}, "\n")+"\n"), 0o600))
+
+	tests := []struct {
+		name         string
+		relative     prettyprint.RelativeTime
+		anchorOffset int
+		wantErr      error
+		want         string
+	}{
+		{name: "off", relative: prettyprint.RelativeOff, want: "10:00:04.000_000_000"},
+		{name: "to previous", relative: prettyprint.RelativeToPrevious, want: "+00:00:02.500_000_000"},
+		{name: "to anchor", relative: prettyprint.RelativeToAnchor, anchorOffset: 2, want: "+00:00:04.000_000_000"},
+		{name: "anchor without time", relative: prettyprint.RelativeToAnchor, anchorOffset: 3, wantErr: model.ErrNoTime, want: "10:00:04.000_000_000"},
+		{name: "anchor out of range", relative: prettyprint.RelativeToAnchor, anchorOffset: 4, wantErr: model.ErrOutOfRange, want: "10:00:04.000_000_000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lv := newLogViewer(zerolog.New(os.Stdout), theme.Monochrome(), config.Default())
+			require.NoError(t, lv.Open(context.Background(), path))
+			defer lv.Close()
+
+			assert.Equal(t, tt.wantErr, lv.SetRelativeTime(tt.relative, tt.anchorOffset, zerolog.TraceLevel))
+			result, _, err := lv.Get(0, 1, zerolog.TraceLevel)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(result), tt.want+" "), "got %q", result)
+		})
+	}
+}
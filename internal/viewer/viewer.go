@@ -2,18 +2,24 @@ package viewer
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
+	"github.com/matusvla/logviewer/internal/config"
 	"github.com/matusvla/logviewer/internal/cui"
 	"github.com/matusvla/logviewer/internal/model"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
 	"github.com/rs/zerolog"
 )
 
 type Viewer struct {
-	log      zerolog.Logger
-	logPath  string
-	logReqCh chan *model.LogRequest
-	cui      *cui.GuiViewer
+	log         zerolog.Logger
+	logPath     string
+	theme       theme.Theme
+	config      *config.Config
+	reqCh       chan model.Request
+	cui         *cui.GuiViewer
+	remoteToken string
 
 	runWg sync.WaitGroup
 }
@@ -21,27 +27,45 @@ type Viewer struct {
 func New(
 	log zerolog.Logger,
 	logPath string,
+	th theme.Theme,
+	cfg *config.Config,
 ) (*Viewer, error) {
-	logReqCh := make(chan *model.LogRequest)
+	reqCh := make(chan model.Request)
 
 	cuiViewer, err := cui.New(
 		log.With().Str("component", "cui").Logger(),
 		logPath,
-		logReqCh,
+		model.NewClient(reqCh),
+		th,
+		cfg,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Viewer{
-		log:      log.With().Str("component", "backend").Logger(),
-		logPath:  logPath,
-		logReqCh: logReqCh,
-		cui:      cuiViewer,
-		runWg:    sync.WaitGroup{},
+		log:     log.With().Str("component", "backend").Logger(),
+		logPath: logPath,
+		theme:   th,
+		config:  cfg,
+		reqCh:   reqCh,
+		cui:     cuiViewer,
+		runWg:   sync.WaitGroup{},
 	}, nil
 }
 
+// Attach makes the viewer open the log file served by the serve command at the URL when it starts.
+// The token authorizes the requests to the server.
+func (v *Viewer) Attach(url, token string) {
+	v.remoteToken = token
+	v.cui.OpenAtStart(url, false)
+}
+
+// Follow makes the viewer open the log file and follow the records appended to it when it starts.
+func (v *Viewer) Follow(logPath string) {
+	v.cui.OpenAtStart(logPath, true)
+}
+
 func (v *Viewer) Run() error {
 	v.log.Info().Str("component", "backend").Msg("starting viewer")
 	v.runWg.Add(1)
@@ -76,7 +100,8 @@ func (v *Viewer) runLogViewer(ctx context.Context) error {
 	log := v.log.With().Str("worker", "runLogViewer").Logger()
 	log.Info().Msg("started")
 	defer log.Info().Msg("ended")
-	lv := newLogViewer(log)
+	lv := newLogViewer(log, v.theme, v.config)
+	lv.remoteToken = v.remoteToken
 	defer func() {
 		if err := lv.Close(); err != nil {
 			log.Error().Err(err).Msg("log viewer closing failed")
@@ -87,28 +112,50 @@ func (v *Viewer) runLogViewer(ctx context.Context) error {
 		case <-ctx.Done():
 			log.Info().Msg("stopped due to context cancellation")
 			return nil
-		case logRequest, ok := <-v.logReqCh:
+		case req, ok := <-v.reqCh:
 			if !ok {
 				panic("reqCh unexpectedly closed")
 			}
-
-			switch body := logRequest.Body.(type) {
-			case *model.OpenLogRequestBody:
-				if err := lv.Close(); err != nil {
-					log.Error().Err(err).Msg("log viewer closing failed")
-				}
-				respErr := lv.Open(body.FilePath)
-				logRequest.RespCh <- &model.LogRequestResponse{Err: respErr}
-			case *model.GetLogRequestBody:
-				respBody, newLines, respErr := lv.Get(body.OffsetFromEnd, body.LineCount, body.LogLvl)
-				logRequest.RespCh <- &model.LogRequestResponse{
-					Body:     respBody,
-					NewLines: newLines,
-					Err:      respErr,
-				}
-			default:
-				panic("unexpected log request type")
+			reqLog := log.With().Uint64("request_id", uint64(req.ID())).Logger()
+			if err := req.Context().Err(); err != nil {
+				reqLog.Debug().Err(err).Msg("request abandoned before handling")
+				req.Fail(err)
+				continue
 			}
+			v.handleRequest(reqLog, lv, req)
+		}
+	}
+}
+
+func (v *Viewer) handleRequest(log zerolog.Logger, lv *logViewer, req model.Request) {
+	switch req := req.(type) {
+	case *model.OpenRequest:
+		if err := lv.Close(); err != nil {
+			log.Error().Err(err).Msg("log viewer closing failed")
 		}
+		req.Respond(model.OpenResponse{}, lv.Open(req.Context(), req.FilePath))
+	case *model.GetRequest:
+		body, newLines, err := lv.Get(req.OffsetFromEnd, req.LineCount, req.LogLvl)
+		req.Respond(model.GetResponse{
+			Body:     body,
+			NewLines: newLines,
+			Total:    lv.Total(req.LogLvl),
+		}, err)
+	case *model.SearchRequest:
+		offset, err := lv.Search(req.Context(), req.Pattern, req.OffsetFromEnd, req.Backward, req.LogLvl)
+		req.Respond(model.SearchResponse{
+			Offset: offset,
+			Total:  lv.Total(req.LogLvl),
+		}, err)
+	case *model.SetHighlightRulesRequest:
+		lv.SetHighlightRules(req.Rules)
+		req.Respond(model.SetHighlightRulesResponse{}, nil)
+	case *model.SetRevealRequest:
+		req.Respond(model.SetRevealResponse{}, lv.SetReveal(req.Reveal))
+	case *model.SetRelativeTimeRequest:
+		req.Respond(model.SetRelativeTimeResponse{}, lv.SetRelativeTime(req.Relative, req.OffsetFromEnd, req.LogLvl))
+	default:
+		log.Error().Msgf("unexpected request type %T", req)
+		req.Fail(fmt.Errorf("unsupported request %T", req))
 	}
 }
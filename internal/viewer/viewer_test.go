@@ -0,0 +1,54 @@
+package viewer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/matusvla/logviewer/internal/config"
+	"github.com/matusvla/logviewer/internal/model"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewer_runLogViewer(t *testing.T) {
+	v := &Viewer{
+		log:    zerolog.New(os.Stdout).Level(zerolog.Disabled),
+		theme:  theme.Monochrome(),
+		config: config.Default(),
+		reqCh:  make(chan model.Request),
+	}
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- v.runLogViewer(ctx)
+	}()
+	client := model.NewClient(v.reqCh)
+
+	_, err := client.Get(ctx, 0, 1, zerolog.TraceLevel)
+	assert.Equal(t, model.ErrNotOpen, err)
+
+	require.NoError(t, client.Open(ctx, "./testdata/test.log"))
+	resp, err := client.Get(ctx, 0, 1, zerolog.WarnLevel)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Total)
+	assert.Contains(t, string(resp.Body), "cui subsystem ended, cancelling context")
+
+	_, err = client.Get(ctx, 0, 1, zerolog.FatalLevel)
+	var noRecordsErr *model.NoRecordsError
+	assert.True(t, errors.As(err, &noRecordsErr))
+	_, err = client.Get(ctx, 5, 1, zerolog.WarnLevel)
+	assert.Equal(t, model.ErrOutOfRange, err)
+
+	searchResp, err := client.Search(ctx, `"component":"backend"`, 1, false, zerolog.WarnLevel)
+	require.NoError(t, err)
+	assert.Equal(t, model.SearchResponse{Offset: 0, Total: 2}, searchResp)
+	_, err = client.Search(ctx, `"component":"backend"`, 0, false, zerolog.WarnLevel)
+	assert.Equal(t, model.ErrNotFound, err)
+
+	cancelFn()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,477 @@
+'use strict';
+
+// The web UI of the viewer. It uses the API of the remote package, see the README for its description.
+// The whole state of the view is kept in the URL, so that the URL can be shared.
+
+const PAGE_SIZE = 200;
+const MAX_FOLLOWED = 2000; // number of the records kept on the page when following the file
+const TOKEN_KEY = 'logviewer.token';
+const STATE_KEYS = ['file', 'level', 'pattern', 'field', 'since', 'until', 'search'];
+
+const el = id => document.getElementById(id);
+
+const state = {
+	file: '',
+	level: 'trace',
+	pattern: '', // filters, see the query endpoint
+	field: '',
+	since: '',
+	until: '',
+	search: '',
+	at: null, // index of the newest shown record, null for the newest record of the file
+	sel: null, // index of the record shown in the detail
+	follow: false,
+};
+
+let info = null; // the response of the info endpoint
+let shown = []; // the shown records, the oldest one first
+let socket = null; // the follow stream
+
+class APIError extends Error {
+	constructor(message, code) {
+		super(message);
+		this.code = code;
+	}
+}
+
+function token() {
+	return localStorage.getItem(TOKEN_KEY) || '';
+}
+
+async function api(endpoint, params) {
+	const query = new URLSearchParams();
+	for (const [key, value] of Object.entries(params)) {
+		if (value !== '' && value !== null && value !== undefined) {
+			query.set(key, value);
+		}
+	}
+	const resp = await fetch(`api/v1/${endpoint}?${query}`, {headers: {Authorization: `Bearer ${token()}`}});
+	const body = await resp.json().catch(() => ({error: resp.statusText}));
+	if (!resp.ok) {
+		throw new APIError(body.error || resp.statusText, body.code);
+	}
+	return body;
+}
+
+// URL
+
+function parseIndex(value) {
+	const i = Number.parseInt(value, 10);
+	return Number.isInteger(i) && i >= 0 ? i : null;
+}
+
+function readURL() {
+	const query = new URLSearchParams(location.search);
+	if (query.get('token')) {
+		localStorage.setItem(TOKEN_KEY, query.get('token'));
+	}
+	for (const key of STATE_KEYS) {
+		state[key] = query.get(key) || state[key];
+	}
+	state.at = parseIndex(query.get('at'));
+	state.sel = parseIndex(query.get('sel'));
+	state.follow = query.get('follow') === '1';
+}
+
+// shareURL returns the URL of the current view, the token is not part of it
+function shareURL() {
+	const query = new URLSearchParams();
+	for (const key of STATE_KEYS) {
+		if (state[key] && !(key === 'level' && state.level === 'trace')) {
+			query.set(key, state[key]);
+		}
+	}
+	if (state.follow) {
+		query.set('follow', '1');
+	} else if (state.at !== null) {
+		query.set('at', state.at);
+	}
+	if (state.sel !== null) {
+		query.set('sel', state.sel);
+	}
+	const search = query.toString();
+	return location.pathname + (search ? `?${search}` : '');
+}
+
+function writeURL() {
+	history.replaceState(null, '', shareURL());
+}
+
+// rendering
+
+function setStatus(message, isError) {
+	el('status').textContent = message;
+	el('status').classList.toggle('error', !!isError);
+}
+
+function searchRegexp() {
+	if (!state.search) {
+		return null;
+	}
+	try {
+		return new RegExp(state.search, 'g');
+	} catch {
+		return null;
+	}
+}
+
+// highlighted returns the text with the matches of the searched pattern marked
+function highlighted(text, re) {
+	const frag = document.createDocumentFragment();
+	let last = 0;
+	if (re) {
+		for (const m of text.matchAll(re)) {
+			if (m[0] === '') {
+				continue;
+			}
+			const mark = document.createElement('mark');
+			mark.textContent = m[0];
+			frag.append(text.slice(last, m.index), mark);
+			last = m.index + m[0].length;
+		}
+	}
+	frag.append(text.slice(last));
+	return frag;
+}
+
+function stringify(value) {
+	return typeof value === 'string' ? value : JSON.stringify(value);
+}
+
+// parse splits the record into its special fields and the rest, obj is null if the record is not a JSON object
+function parse(rec) {
+	let obj = null;
+	try {
+		obj = JSON.parse(rec.data);
+	} catch {
+		// not a JSON record, shown as it is
+	}
+	if (obj === null || typeof obj !== 'object' || Array.isArray(obj)) {
+		return {obj: null, time: '', level: rec.level, message: rec.data, fields: ''};
+	}
+	const keys = info.keys;
+	const special = new Set([keys.time, keys.level, keys.message]);
+	const fields = Object.entries(obj).
+		filter(([key]) => !special.has(key)).
+		map(([key, value]) => `${key}=${stringify(value)}`).
+		join(' ');
+	return {
+		obj,
+		time: stringify(obj[keys.time] ?? ''),
+		level: stringify(obj[keys.level] ?? rec.level),
+		message: stringify(obj[keys.message] ?? ''),
+		fields,
+	};
+}
+
+function rowOf(rec, re) {
+	const parsed = parse(rec);
+	const tr = document.createElement('tr');
+	tr.dataset.index = rec.index;
+	tr.classList.toggle('selected', rec.index >= 0 && rec.index === state.sel);
+	for (const [name, text] of [['time', parsed.time], ['level', parsed.level], ['message', parsed.message], ['fields', parsed.fields]]) {
+		const td = document.createElement('td');
+		td.className = name;
+		if (name === 'level') {
+			td.classList.add(`lvl-${parsed.level}`);
+		}
+		td.append(highlighted(text, re));
+		tr.append(td);
+	}
+	tr.addEventListener('click', () => showDetail(rec));
+	return tr;
+}
+
+function render() {
+	const re = searchRegexp();
+	el('rows').replaceChildren(...shown.map(rec => rowOf(rec, re)));
+	const total = info.totals[state.level] || 0;
+	const indices = shown.map(rec => rec.index).filter(i => i >= 0);
+	el('position').textContent = indices.length ?
+		`records ${indices[0] + 1}–${indices[indices.length - 1] + 1} of ${total}` :
+		`no records of ${total}`;
+}
+
+function showDetail(rec) {
+	state.sel = rec.index >= 0 ? rec.index : null;
+	const parsed = parse(rec);
+	el('detail-title').textContent = `${rec.index >= 0 ? `record ${rec.index + 1}` : 'followed record'}, ${parsed.level}, file offset ${rec.offset}`;
+	el('detail-body').textContent = parsed.obj ? JSON.stringify(parsed.obj, null, 2) : rec.data;
+	el('detail').hidden = false;
+	for (const tr of el('rows').children) {
+		tr.classList.toggle('selected', tr.dataset.index === String(rec.index));
+	}
+	writeURL();
+}
+
+function hideDetail() {
+	state.sel = null;
+	el('detail').hidden = true;
+	for (const tr of el('rows').querySelectorAll('.selected')) {
+		tr.classList.remove('selected');
+	}
+	writeURL();
+}
+
+function scrollToSelected() {
+	const tr = el('rows').querySelector('.selected');
+	if (tr) {
+		tr.scrollIntoView({block: 'center'});
+	}
+}
+
+// loading
+
+function filters() {
+	return {file: state.file, level: state.level, pattern: state.pattern, field: state.field, since: state.since, until: state.until};
+}
+
+function fillFiles() {
+	el('file').replaceChildren(...info.files.map(file => new Option(file, file, false, file === state.file)));
+}
+
+// load shows the page of the records ending with the one at state.at
+async function load() {
+	info = await api('info', {file: state.file});
+	state.file = info.path;
+	fillFiles();
+	const total = info.totals[state.level] || 0;
+	if (state.at !== null && state.at >= total - 1) {
+		state.at = null;
+	}
+	shown = [];
+	if (total > 0) {
+		const from = state.at === null ? total - 1 : state.at;
+		const resp = await api('query', {...filters(), from, backward: true, limit: PAGE_SIZE});
+		shown = resp.records.reverse();
+	}
+	render();
+	writeURL();
+	if (state.sel !== null) {
+		const rec = shown.find(r => r.index === state.sel) ||
+			(await api('records', {file: state.file, level: state.level, from: state.sel, to: state.sel + 1})).records[0];
+		showDetail(rec);
+		scrollToSelected();
+	} else {
+		el('detail').hidden = true;
+		el('records').scrollTop = el('records').scrollHeight;
+	}
+}
+
+// refresh loads the records and reports the errors, it asks for the token if it is missing or invalid
+async function refresh() {
+	stopFollow();
+	try {
+		await load();
+		setStatus('');
+		if (state.follow) {
+			startFollow();
+		}
+	} catch (err) {
+		if (err.code === 'unauthorized') {
+			const t = prompt('Token of the log server (printed by the serve command)');
+			if (t) {
+				localStorage.setItem(TOKEN_KEY, t);
+				return refresh();
+			}
+		}
+		if (err.code === 'out_of_range' && state.sel !== null) {
+			state.sel = null; // the record is no longer indexed
+			return refresh();
+		}
+		setStatus(err.message, true);
+	}
+}
+
+async function goTo(fromOldest) {
+	state.follow = false;
+	el('follow').checked = false;
+	const total = info.totals[state.level] || 0;
+	let at = null;
+	if (fromOldest || shown.length) {
+		const from = fromOldest ? 0 : shown[shown.length - 1].index + 1;
+		try {
+			const resp = await api('query', {...filters(), from, backward: false, limit: PAGE_SIZE});
+			if (resp.records.length) {
+				at = resp.records[resp.records.length - 1].index;
+			}
+		} catch (err) {
+			setStatus(err.message, true);
+			return;
+		}
+	}
+	state.at = at !== null && at < total - 1 ? at : null;
+	state.sel = null;
+	await refresh();
+}
+
+async function goOlder() {
+	if (!shown.length || shown[0].index <= 0) {
+		return;
+	}
+	state.follow = false;
+	el('follow').checked = false;
+	state.at = shown[0].index - 1;
+	state.sel = null;
+	await refresh();
+}
+
+// search moves to the nearest record matching the searched pattern older (backward) or newer than the selected one
+async function search(backward) {
+	if (!state.search) {
+		return;
+	}
+	const total = info.totals[state.level] || 0;
+	let from = state.sel;
+	if (from === null) {
+		from = backward ? (state.at ?? total - 1) + 1 : (shown.length ? shown[0].index - 1 : -1);
+	}
+	try {
+		const {index} = await api('search', {file: state.file, level: state.level, from, backward, pattern: state.search});
+		state.follow = false;
+		el('follow').checked = false;
+		state.sel = index;
+		if (!shown.some(rec => rec.index === index)) {
+			state.at = Math.min(index + PAGE_SIZE / 2, total - 1);
+		}
+		await refresh();
+	} catch (err) {
+		setStatus(err.code === 'not_found' ? `pattern ${JSON.stringify(state.search)} not found` : err.message, true);
+	}
+}
+
+// following
+
+// passes checks the followed record against the pattern and field filters, the time filters are not applied to the new records
+function passes(rec) {
+	if (state.pattern) {
+		try {
+			if (!new RegExp(state.pattern).test(rec.data)) {
+				return false;
+			}
+		} catch {
+			return true;
+		}
+	}
+	if (state.field) {
+		const {obj} = parse(rec);
+		for (const item of state.field.split(',')) {
+			const [key, ...value] = item.split('=');
+			if (!obj || !(key in obj) || stringify(obj[key]) !== value.join('=')) {
+				return false;
+			}
+		}
+	}
+	return true;
+}
+
+function startFollow() {
+	const query = new URLSearchParams({file: state.file, level: state.level, token: token()});
+	const scheme = location.protocol === 'https:' ? 'wss:' : 'ws:';
+	const dir = location.pathname.replace(/[^/]*$/, '');
+	socket = new WebSocket(`${scheme}//${location.host}${dir}api/v1/follow?${query}`);
+	socket.addEventListener('message', event => {
+		const rec = JSON.parse(event.data);
+		if (!passes(rec)) {
+			return;
+		}
+		const box = el('records');
+		const atBottom = box.scrollTop + box.clientHeight >= box.scrollHeight - 4;
+		shown.push(rec);
+		el('rows').append(rowOf(rec, searchRegexp()));
+		while (shown.length > MAX_FOLLOWED) {
+			shown.shift();
+			el('rows').firstChild.remove();
+		}
+		if (atBottom) {
+			box.scrollTop = box.scrollHeight;
+		}
+	});
+	socket.addEventListener('close', () => {
+		if (state.follow && socket) {
+			setStatus('the follow stream was closed by the server', true);
+		}
+	});
+	setStatus('following');
+}
+
+function stopFollow() {
+	if (socket) {
+		const s = socket;
+		socket = null;
+		s.close();
+	}
+}
+
+// controls
+
+function readControls() {
+	for (const key of STATE_KEYS) {
+		state[key] = el(key).value.trim();
+	}
+	state.at = null;
+	state.sel = null;
+}
+
+function writeControls() {
+	for (const key of STATE_KEYS) {
+		if (key !== 'file') {
+			el(key).value = state[key];
+		}
+	}
+	el('follow').checked = state.follow;
+}
+
+el('controls').addEventListener('submit', event => {
+	event.preventDefault();
+	readControls();
+	refresh();
+});
+for (const id of ['file', 'level']) {
+	el(id).addEventListener('change', () => {
+		readControls();
+		refresh();
+	});
+}
+el('searchform').addEventListener('submit', event => {
+	event.preventDefault();
+	search(false);
+});
+el('search-older').addEventListener('click', () => search(true));
+el('search').addEventListener('input', () => {
+	state.search = el('search').value.trim();
+	render();
+	writeURL();
+});
+el('follow').addEventListener('change', () => {
+	state.follow = el('follow').checked;
+	state.at = null;
+	if (!state.follow) {
+		setStatus('');
+	}
+	refresh();
+});
+el('share').addEventListener('click', async () => {
+	const url = location.origin + shareURL();
+	try {
+		await navigator.clipboard.writeText(url);
+		setStatus('link copied');
+	} catch {
+		prompt('Link to this view', url);
+	}
+});
+el('oldest').addEventListener('click', () => goTo(true));
+el('older').addEventListener('click', goOlder);
+el('newer').addEventListener('click', () => goTo(false));
+el('newest').addEventListener('click', () => {
+	state.follow = false;
+	el('follow').checked = false;
+	state.at = null;
+	state.sel = null;
+	refresh();
+});
+el('detail-close').addEventListener('click', hideDetail);
+
+readURL();
+writeControls();
+refresh();
@@ -0,0 +1,21 @@
+// Package web provides the single-page web UI of the viewer. The page talks to the API of the remote.Server,
+// which has to be served by the same HTTP server under the /api/ path.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the files of the web UI. They need no token, the page asks for it when the API rejects its requests.
+func Handler() http.Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err) // the embedded directory always exists
+	}
+	return http.FileServer(http.FS(files))
+}
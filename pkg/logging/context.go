@@ -0,0 +1,252 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// The names of the correlation fields added to the logs by FromContext.
+const (
+	RequestIDFieldName = "request_id"
+	TraceIDFieldName   = "trace_id"
+	UserFieldName      = "user"
+)
+
+const (
+	// RequestIDHeader is the HTTP header propagating the request ID, it is generated by HTTPMiddleware if it is missing.
+	RequestIDHeader = "X-Request-ID"
+	// TraceparentHeader is the W3C Trace Context header, the trace ID is taken from it by HTTPMiddleware.
+	TraceparentHeader = "traceparent"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	correlationKey
+)
+
+// correlation are the fields identifying the request in all its logs
+type correlation struct {
+	requestID, traceID, user string
+}
+
+func correlationOf(ctx context.Context) correlation {
+	c, _ := ctx.Value(correlationKey).(correlation)
+	return c
+}
+
+// ContextWithLogger returns the context carrying the logger, see FromContext.
+func ContextWithLogger(ctx context.Context, log zerolog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, log)
+}
+
+// ContextWithRequestID returns the context carrying the request ID added to the logs by FromContext.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	c := correlationOf(ctx)
+	c.requestID = requestID
+	return context.WithValue(ctx, correlationKey, c)
+}
+
+// ContextWithTraceID returns the context carrying the trace ID added to the logs by FromContext.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	c := correlationOf(ctx)
+	c.traceID = traceID
+	return context.WithValue(ctx, correlationKey, c)
+}
+
+// ContextWithUser returns the context carrying the user added to the logs by FromContext.
+func ContextWithUser(ctx context.Context, user string) context.Context {
+	c := correlationOf(ctx)
+	c.user = user
+	return context.WithValue(ctx, correlationKey, c)
+}
+
+// RequestID returns the request ID carried by the context, an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	return correlationOf(ctx).requestID
+}
+
+// TraceID returns the trace ID carried by the context, an empty string if there is none.
+func TraceID(ctx context.Context) string {
+	return correlationOf(ctx).traceID
+}
+
+// User returns the user carried by the context, an empty string if there is none.
+func User(ctx context.Context) string {
+	return correlationOf(ctx).user
+}
+
+// FromContext returns the logger carried by the context with the correlation fields of the context (request_id, trace_id
+// and user), so all the logs of a request can be filtered by them. A disabled logger is returned if the context
+// carries none. Like zerolog.Ctx it returns a pointer, so the logs can be written directly by FromContext(ctx).Info().
+func FromContext(ctx context.Context) *zerolog.Logger {
+	log, ok := ctx.Value(loggerKey).(zerolog.Logger)
+	if !ok {
+		nop := zerolog.Nop()
+		return &nop
+	}
+	c := correlationOf(ctx)
+	if c == (correlation{}) {
+		return &log
+	}
+	logCtx := log.With()
+	if c.requestID != "" {
+		logCtx = logCtx.Str(RequestIDFieldName, c.requestID)
+	}
+	if c.traceID != "" {
+		logCtx = logCtx.Str(TraceIDFieldName, c.traceID)
+	}
+	if c.user != "" {
+		logCtx = logCtx.Str(UserFieldName, c.user)
+	}
+	log = logCtx.Logger()
+	return &log
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "" // the logs are only not correlated
+	}
+	return hex.EncodeToString(b)
+}
+
+// traceIDOf returns the trace ID of the W3C traceparent header, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func traceIDOf(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 {
+		return ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return ""
+	}
+	return parts[1]
+}
+
+// HTTPMiddleware returns the middleware logging the start (by the debug level) and the finish of every request
+// with its status and latency (by the error level for the 5xx statuses, by the info level otherwise). The request ID
+// is taken from the RequestIDHeader or generated and it is returned in the same header of the response, the trace ID
+// is taken from the TraceparentHeader. The context of the request carries the logger and the IDs, so the handlers
+// should log by FromContext(r.Context()).
+func HTTPMiddleware(log zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if requestID == "" {
+				requestID = newRequestID()
+			}
+			ctx := ContextWithRequestID(ContextWithLogger(r.Context(), log), requestID)
+			if traceID := traceIDOf(r.Header.Get(TraceparentHeader)); traceID != "" {
+				ctx = ContextWithTraceID(ctx, traceID)
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			reqLog := FromContext(ctx)
+			reqLog.Debug().
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Str("remote", r.RemoteAddr).
+				Msg("request started")
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			e := reqLog.Info()
+			if sw.status >= http.StatusInternalServerError {
+				e = reqLog.Error()
+			}
+			e.Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Int("status", sw.status).
+				Int64("size", sw.size).
+				Dur("latency", time.Since(start)).
+				Msg("request finished")
+		})
+	}
+}
+
+// statusWriter records the status and the size of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.status, sw.wroteHeader = status, true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	n, err := sw.ResponseWriter.Write(b)
+	sw.size += int64(n)
+	return n, err
+}
+
+// Flush supports the streamed responses.
+func (sw *statusWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack supports the WebSocket connections, which are logged with the status 101.
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := sw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+	sw.status, sw.wroteHeader = http.StatusSwitchingProtocols, true
+	return h.Hijack()
+}
+
+// UnaryHandler handles a request of a remote procedure call, it has the signature of grpc.UnaryHandler.
+type UnaryHandler func(ctx context.Context, req interface{}) (interface{}, error)
+
+// UnaryInterceptor returns the equivalent of HTTPMiddleware for the remote procedure calls logging the start and
+// the finish of every call of the method with its latency and error. The request ID and the logger already carried by
+// the context are kept, otherwise the log is used and the request ID is generated. It can be used as a gRPC unary
+// server interceptor by:
+//
+//	func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+//		return interceptor(ctx, info.FullMethod, req, UnaryHandler(handler))
+//	}
+func UnaryInterceptor(log zerolog.Logger) func(ctx context.Context, method string, req interface{}, handler UnaryHandler) (interface{}, error) {
+	return func(ctx context.Context, method string, req interface{}, handler UnaryHandler) (interface{}, error) {
+		if _, ok := ctx.Value(loggerKey).(zerolog.Logger); !ok {
+			ctx = ContextWithLogger(ctx, log)
+		}
+		if RequestID(ctx) == "" {
+			ctx = ContextWithRequestID(ctx, newRequestID())
+		}
+
+		reqLog := FromContext(ctx)
+		reqLog.Debug().Str("method", method).Msg("call started")
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		e := reqLog.Info()
+		if err != nil {
+			e = reqLog.Error().Err(err)
+		}
+		e.Str("method", method).
+			Dur("latency", time.Since(start)).
+			Msg("call finished")
+		return resp, err
+	}
+}
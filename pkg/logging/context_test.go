@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// records parses the logs of the buffer
+func records(t *testing.T, out *syncBuffer) []map[string]interface{} {
+	t.Helper()
+	var recs []map[string]interface{}
+	for _, line := range out.lines() {
+		var rec map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &rec), line)
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func TestFromContext(t *testing.T) {
+	var out syncBuffer
+	log := zerolog.New(&out)
+
+	FromContext(context.Background()).Info().Msg("no logger")
+	ctx := ContextWithLogger(context.Background(), log)
+	FromContext(ctx).Info().Msg("no correlation")
+	ctx = ContextWithUser(ContextWithTraceID(ContextWithRequestID(ctx, "req-1"), "trace-1"), "alice")
+	FromContext(ctx).Info().Msg("correlated")
+
+	assert.Equal(t, "req-1", RequestID(ctx))
+	assert.Equal(t, "trace-1", TraceID(ctx))
+	assert.Equal(t, "alice", User(ctx))
+	assert.Equal(t, []string{
+		`{"level":"info","message":"no correlation"}`,
+		`{"level":"info","request_id":"req-1","trace_id":"trace-1","user":"alice","message":"correlated"}`,
+	}, out.lines())
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        map[string]string
+		status        int
+		wantRequestID string
+		wantTraceID   interface{}
+		wantLevel     string
+	}{
+		{
+			name:          "propagated IDs",
+			header:        map[string]string{RequestIDHeader: "req-1", TraceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			status:        http.StatusTeapot,
+			wantRequestID: "req-1",
+			wantTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantLevel:     "info",
+		},
+		{
+			name:      "generated request ID",
+			header:    map[string]string{TraceparentHeader: "invalid"},
+			status:    http.StatusInternalServerError,
+			wantLevel: "error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			handler := HTTPMiddleware(zerolog.New(&out))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				FromContext(r.Context()).Warn().Msg("handling")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("hello"))
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/records?offset=1", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			requestID := rec.Header().Get(RequestIDHeader)
+			if tt.wantRequestID != "" {
+				assert.Equal(t, tt.wantRequestID, requestID)
+			} else {
+				assert.Len(t, requestID, 32)
+			}
+			recs := records(t, &out)
+			require.Len(t, recs, 3)
+			assert.Equal(t, []interface{}{"request started", "handling", "request finished"},
+				[]interface{}{recs[0]["message"], recs[1]["message"], recs[2]["message"]})
+			for _, r := range recs {
+				assert.Equal(t, requestID, r[RequestIDFieldName])
+				assert.Equal(t, tt.wantTraceID, r[TraceIDFieldName])
+			}
+			finished := recs[2]
+			assert.Equal(t, tt.wantLevel, finished["level"])
+			assert.Equal(t, float64(tt.status), finished["status"])
+			assert.Equal(t, float64(5), finished["size"])
+			assert.Equal(t, "/api/records", finished["path"])
+			assert.Contains(t, finished, "latency")
+		})
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	var out, ctxOut syncBuffer
+	interceptor := UnaryInterceptor(zerolog.New(&out))
+	handlerErr := errors.New("not found")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		FromContext(ctx).Info().Msg("handling")
+		if req == "missing" {
+			return nil, handlerErr
+		}
+		return "ok", nil
+	}
+
+	resp, err := interceptor(context.Background(), "/records.Store/Get", "present", handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	recs := records(t, &out)
+	require.Len(t, recs, 3)
+	assert.NotEmpty(t, recs[0][RequestIDFieldName])
+	assert.Equal(t, recs[0][RequestIDFieldName], recs[2][RequestIDFieldName])
+	assert.Equal(t, "info", recs[2]["level"])
+	assert.Equal(t, "/records.Store/Get", recs[2]["method"])
+
+	// the logger and the request ID of the context are kept
+	ctx := ContextWithRequestID(ContextWithLogger(context.Background(), zerolog.New(&ctxOut)), "req-1")
+	_, err = interceptor(ctx, "/records.Store/Get", "missing", handler)
+	assert.Equal(t, handlerErr, err)
+	recs = records(t, &ctxOut)
+	require.Len(t, recs, 3)
+	assert.Equal(t, "req-1", recs[2][RequestIDFieldName])
+	assert.Equal(t, "error", recs[2]["level"])
+	assert.Equal(t, "not found", recs[2]["error"])
+}
@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DroppedMessage is the message of the record reporting the logs dropped by an output.
+const DroppedMessage = "logs dropped"
+
+type overflowKind int
+
+const (
+	overflowBlock overflowKind = iota
+	overflowDropNewest
+	overflowDropOldest
+	overflowDropBelowLevel
+	overflowBlockWithTimeout
+)
+
+// OverflowPolicy decides what happens with a log which does not fit in the full buffer of an output.
+// The dropped logs are counted (see DropCounter) and reported by a record with the DroppedMessage written
+// to the output once it catches up (see WithDropReportInterval).
+type OverflowPolicy struct {
+	kind    overflowKind
+	level   zerolog.Level
+	timeout time.Duration
+}
+
+var (
+	// Block makes the logging wait until there is space in the buffer, no logs are dropped (see New).
+	Block = OverflowPolicy{kind: overflowBlock}
+	// DropNewest drops the logs which do not fit in the buffer (see NewDropping).
+	DropNewest = OverflowPolicy{kind: overflowDropNewest}
+	// DropOldest drops the oldest buffered log to make space for the new one, so the most recent logs are written.
+	DropOldest = OverflowPolicy{kind: overflowDropOldest}
+)
+
+// DropBelowLevel drops the logs below the level which do not fit in the buffer, the logging of the other logs waits
+// until there is space in the buffer.
+func DropBelowLevel(level zerolog.Level) OverflowPolicy {
+	return OverflowPolicy{kind: overflowDropBelowLevel, level: level}
+}
+
+// BlockWithTimeout makes the logging wait until there is space in the buffer at most for the timeout,
+// the log is dropped after it.
+func BlockWithTimeout(timeout time.Duration) OverflowPolicy {
+	return OverflowPolicy{kind: overflowBlockWithTimeout, timeout: timeout}
+}
+
+// dropLevels are the levels of the dropped logs counted separately, zerolog.NoLevel is the last one
+var dropLevels = []zerolog.Level{
+	zerolog.TraceLevel, zerolog.DebugLevel, zerolog.InfoLevel, zerolog.WarnLevel,
+	zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel, zerolog.NoLevel,
+}
+
+// levelIndex returns the index of the level in the dropLevels
+func levelIndex(level zerolog.Level) int {
+	if level < zerolog.TraceLevel || level > zerolog.NoLevel {
+		return len(dropLevels) - 1
+	}
+	return int(level - zerolog.TraceLevel)
+}
+
+// DropCounter counts the logs dropped by the outputs of a logger (see WithDropCounter and SinkDropCounter).
+// It is safe for concurrent use.
+type DropCounter struct {
+	counts [8]uint64 // by the levelIndex
+}
+
+func (c *DropCounter) add(level zerolog.Level) {
+	atomic.AddUint64(&c.counts[levelIndex(level)], 1)
+}
+
+// Dropped returns the number of the dropped logs of the level, zerolog.NoLevel for the logs without a level.
+func (c *DropCounter) Dropped(level zerolog.Level) uint64 {
+	return atomic.LoadUint64(&c.counts[levelIndex(level)])
+}
+
+// Total returns the number of all the dropped logs.
+func (c *DropCounter) Total() uint64 {
+	var total uint64
+	for i := range c.counts {
+		total += atomic.LoadUint64(&c.counts[i])
+	}
+	return total
+}
+
+// dropWindow collects the drops of an output since the last report
+type dropWindow struct {
+	mu          sync.Mutex
+	counts      [8]uint64
+	since, last time.Time
+}
+
+func (dw *dropWindow) add(level zerolog.Level, now time.Time) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if dw.since.IsZero() {
+		dw.since = now
+	}
+	dw.last = now
+	dw.counts[levelIndex(level)]++
+}
+
+// report returns the record reporting the drops since the last report and resets the counts,
+// it returns nil if no log was dropped.
+func (dw *dropWindow) report(now time.Time) []byte {
+	dw.mu.Lock()
+	counts, since, last := dw.counts, dw.since, dw.last
+	dw.counts, dw.since, dw.last = [8]uint64{}, time.Time{}, time.Time{}
+	dw.mu.Unlock()
+	if since.IsZero() {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	dropped := zerolog.Dict()
+	var total uint64
+	for i, count := range counts {
+		if count == 0 {
+			continue
+		}
+		name := dropLevels[i].String()
+		if name == "" { // zerolog.NoLevel
+			name = "none"
+		}
+		dropped.Uint64(name, count)
+		total += count
+	}
+	log := zerolog.New(&buf)
+	log.Warn().
+		Str(zerolog.TimestampFieldName, now.Format(TimeFormat)).
+		Dict("dropped", dropped).
+		Uint64("dropped_total", total).
+		Str("dropped_since", since.Format(TimeFormat)).
+		Str("dropped_until", last.Format(TimeFormat)).
+		Msg(DroppedMessage)
+	return buf.Bytes()
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDropWindow_report(t *testing.T) {
+	var dw dropWindow
+	assert.Nil(t, dw.report(rotateNow), "nothing dropped")
+
+	dw.add(zerolog.InfoLevel, rotateNow.Add(time.Second))
+	dw.add(zerolog.InfoLevel, rotateNow.Add(2*time.Second))
+	dw.add(zerolog.NoLevel, rotateNow.Add(3*time.Second))
+	assert.Equal(t,
+		`{"level":"warn","time":"2022-05-22T11:59:05Z","dropped":{"info":2,"none":1},"dropped_total":3,`+
+			`"dropped_since":"2022-05-22T11:59:01Z","dropped_until":"2022-05-22T11:59:03Z","message":"logs dropped"}`+"\n",
+		string(dw.report(rotateNow.Add(5*time.Second))))
+	assert.Nil(t, dw.report(rotateNow), "the counts are reset")
+}
+
+func TestDropCounter(t *testing.T) {
+	var c DropCounter
+	c.add(zerolog.DebugLevel)
+	c.add(zerolog.ErrorLevel)
+	c.add(zerolog.ErrorLevel)
+	c.add(zerolog.Disabled)
+	assert.Equal(t, uint64(1), c.Dropped(zerolog.DebugLevel))
+	assert.Equal(t, uint64(2), c.Dropped(zerolog.ErrorLevel))
+	assert.Equal(t, uint64(1), c.Dropped(zerolog.NoLevel))
+	assert.Zero(t, c.Dropped(zerolog.InfoLevel))
+	assert.Equal(t, uint64(4), c.Total())
+}
+
+func TestNew_dropReportInterval(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	var logger, sink DropCounter
+	log, logFlushFn := New("test", zerolog.InfoLevel,
+		WithSink(out, SinkBufferSize(1), SinkDropCounter(&sink)),
+		WithOverflowPolicy(DropNewest),
+		WithDropCounter(&logger),
+		WithDropReportInterval(10*time.Millisecond),
+	)
+	defer logFlushFn()
+	for i := 0; i < 10; i++ {
+		log.Info().Int("i", i).Send()
+	}
+	close(out.release)
+
+	// the report is written once the output catches up, not only by the flush function
+	require.Eventually(t, func() bool {
+		lines := out.lines()
+		return strings.Contains(lines[len(lines)-1], DroppedMessage)
+	}, time.Second, time.Millisecond)
+	lines := out.lines()
+	var report struct {
+		Dropped      map[string]uint64 `json:"dropped"`
+		DroppedTotal uint64            `json:"dropped_total"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &report))
+	assert.Equal(t, logger.Total(), report.DroppedTotal)
+	assert.Equal(t, map[string]uint64{"info": report.DroppedTotal}, report.Dropped)
+	assert.Equal(t, logger.Total(), sink.Total())
+	assert.Len(t, lines, 10-int(report.DroppedTotal)+1)
+}
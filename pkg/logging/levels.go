@@ -0,0 +1,252 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const defaultWatchInterval = time.Second
+
+// LevelController changes the levels of the loggers created with it (see WithLevelController) at runtime - the global level
+// and the levels of the modules overriding it. The levels can be changed directly, by HTTP requests (see ServeHTTP),
+// by signals (see WatchSignals) or by a control file (see WatchFile). It is safe for concurrent use.
+type LevelController struct {
+	mu      sync.Mutex   // serializes the changes
+	state   atomic.Value // *levelState, read by every log
+	initial zerolog.Level
+}
+
+// levelState is an immutable snapshot of the levels
+type levelState struct {
+	level   zerolog.Level
+	modules map[string]zerolog.Level
+}
+
+// NewLevelController returns the controller with the global level.
+func NewLevelController(level zerolog.Level) *LevelController {
+	c := &LevelController{initial: level}
+	c.state.Store(&levelState{level: level})
+	return c
+}
+
+func (c *LevelController) load() *levelState {
+	return c.state.Load().(*levelState)
+}
+
+// update changes a copy of the levels and stores it
+func (c *LevelController) update(fn func(s *levelState)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	old := c.load()
+	s := &levelState{level: old.level, modules: make(map[string]zerolog.Level, len(old.modules))}
+	for module, level := range old.modules {
+		s.modules[module] = level
+	}
+	fn(s)
+	c.state.Store(s)
+}
+
+// Level returns the global level.
+func (c *LevelController) Level() zerolog.Level {
+	return c.load().level
+}
+
+// SetLevel sets the global level used by the modules without their own level.
+func (c *LevelController) SetLevel(level zerolog.Level) {
+	c.update(func(s *levelState) { s.level = level })
+}
+
+// ModuleLevel returns the level of the module - its own level if it is set, the global level otherwise.
+func (c *LevelController) ModuleLevel(module string) zerolog.Level {
+	s := c.load()
+	if level, ok := s.modules[module]; ok {
+		return level
+	}
+	return s.level
+}
+
+// SetModuleLevel sets the level of the module overriding the global level.
+func (c *LevelController) SetModuleLevel(module string, level zerolog.Level) {
+	c.update(func(s *levelState) { s.modules[module] = level })
+}
+
+// ResetModuleLevel makes the module use the global level again.
+func (c *LevelController) ResetModuleLevel(module string) {
+	c.update(func(s *levelState) { delete(s.modules, module) })
+}
+
+// ModuleLevels returns the modules with their own level.
+func (c *LevelController) ModuleLevels() map[string]zerolog.Level {
+	s := c.load()
+	modules := make(map[string]zerolog.Level, len(s.modules))
+	for module, level := range s.modules {
+		modules[module] = level
+	}
+	return modules
+}
+
+type levelsResponse struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// ServeHTTP returns the levels as JSON for the GET requests, e.g. {"level":"info","modules":{"db":"trace"}}.
+// The PUT and POST requests change the level given by the level query parameter - the global one or the one
+// of the module query parameter. An empty level resets the level of the module. The changed levels are returned.
+func (c *LevelController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		query := r.URL.Query()
+		module, levelStr := query.Get("module"), query.Get("level")
+		if module != "" && levelStr == "" {
+			c.ResetModuleLevel(module)
+			break
+		}
+		level, err := parseLevel(levelStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if module != "" {
+			c.SetModuleLevel(module, level)
+		} else {
+			c.SetLevel(level)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "only GET, PUT and POST requests are accepted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s := c.load()
+	resp := levelsResponse{Level: s.level.String()}
+	if len(s.modules) > 0 {
+		resp.Modules = make(map[string]string, len(s.modules))
+		for module, level := range s.modules {
+			resp.Modules[module] = level.String()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// cycleLevel makes the global level one level more verbose, after the trace level it starts again by the initial level.
+func (c *LevelController) cycleLevel() {
+	c.update(func(s *levelState) {
+		if s.level <= zerolog.TraceLevel || s.level > zerolog.PanicLevel {
+			s.level = c.initial
+			return
+		}
+		s.level--
+	})
+}
+
+// WatchFile applies the levels of the control file whenever its content changes until the context is done. The file
+// is checked every interval (1s by default). Every line of the file is either the global level or module=level, e.g.
+//
+//	info
+//	db=trace
+//
+// The empty lines and the lines starting by # are ignored. The modules of the file replace all the module levels,
+// the levels are kept when the file is removed. The errors of reading and parsing the file are reported to onError,
+// which can be nil.
+func (c *LevelController) WatchFile(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	if onError == nil {
+		onError = func(error) {}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last []byte
+	for {
+		data, err := os.ReadFile(path)
+		switch {
+		case err != nil:
+			if !os.IsNotExist(err) {
+				onError(err)
+			}
+			last = nil
+		case last == nil || !bytes.Equal(data, last):
+			last = data
+			if err := c.applyFile(data); err != nil {
+				onError(fmt.Errorf("control file %s: %w", path, err))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyFile applies the levels of the control file, nothing is changed if it is invalid
+func (c *LevelController) applyFile(data []byte) error {
+	level, hasLevel := zerolog.NoLevel, false
+	modules := make(map[string]zerolog.Level)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		module, levelStr, isModule := strings.Cut(line, "=")
+		if !isModule {
+			levelStr = module
+		}
+		l, err := parseLevel(strings.TrimSpace(levelStr))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if isModule {
+			modules[strings.TrimSpace(module)] = l
+		} else {
+			level, hasLevel = l, true
+		}
+	}
+	c.update(func(s *levelState) {
+		if hasLevel {
+			s.level = level
+		}
+		s.modules = modules
+	})
+	return nil
+}
+
+// parseLevel parses the level name, unlike zerolog.ParseLevel it does not accept an empty name
+func parseLevel(s string) (zerolog.Level, error) {
+	if s == "" {
+		return zerolog.NoLevel, errors.New("missing level")
+	}
+	level, err := zerolog.ParseLevel(strings.ToLower(s))
+	if err != nil {
+		return zerolog.NoLevel, fmt.Errorf("invalid level %q", s)
+	}
+	return level, nil
+}
+
+// levelSampler enables the logs of the module by the level of the controller, zerolog asks it before
+// the event is created, so the disabled logs cost nearly nothing.
+type levelSampler struct {
+	c      *LevelController
+	module string
+}
+
+func (ls levelSampler) Sample(level zerolog.Level) bool {
+	return level >= ls.c.ModuleLevel(ls.module)
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package logging
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSignals changes the global level by the signals until the context is done - SIGUSR1 makes it one level more verbose
+// (after the trace level it starts again by the level the controller was created with), SIGUSR2 resets it to that level.
+// The signals are not supported on Windows, where it only waits for the context.
+func (c *LevelController) WatchSignals(ctx context.Context) {
+	signalC := make(chan os.Signal, 1)
+	signal.Notify(signalC, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signalC)
+	c.handleSignals(ctx, signalC)
+}
+
+func (c *LevelController) handleSignals(ctx context.Context, signalC <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signalC:
+			if sig == syscall.SIGUSR1 {
+				c.cycleLevel()
+			} else {
+				c.SetLevel(c.initial)
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package logging
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelController_handleSignals(t *testing.T) {
+	c := NewLevelController(zerolog.InfoLevel)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	signalC := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.handleSignals(ctx, signalC)
+	}()
+
+	signalC <- syscall.SIGUSR1 // debug
+	signalC <- syscall.SIGUSR1 // trace
+	signalC <- syscall.SIGUSR2 // info, the next signal would start again by info without it
+	signalC <- syscall.SIGUSR1 // debug
+	cancelFn()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the handling did not stop")
+	}
+	assert.Equal(t, zerolog.DebugLevel, c.Level())
+}
@@ -0,0 +1,8 @@
+package logging
+
+import "context"
+
+// WatchSignals only waits for the context, the signals changing the level are not supported on Windows.
+func (c *LevelController) WatchSignals(ctx context.Context) {
+	<-ctx.Done()
+}
@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelController(t *testing.T) {
+	c := NewLevelController(zerolog.InfoLevel)
+	c.SetModuleLevel("db", zerolog.TraceLevel)
+	assert.Equal(t, zerolog.TraceLevel, c.ModuleLevel("db"))
+	assert.Equal(t, zerolog.InfoLevel, c.ModuleLevel("api"))
+
+	c.SetLevel(zerolog.WarnLevel)
+	assert.Equal(t, zerolog.WarnLevel, c.Level())
+	assert.Equal(t, zerolog.WarnLevel, c.ModuleLevel("api"))
+	assert.Equal(t, map[string]zerolog.Level{"db": zerolog.TraceLevel}, c.ModuleLevels())
+
+	c.ResetModuleLevel("db")
+	assert.Equal(t, zerolog.WarnLevel, c.ModuleLevel("db"))
+	assert.Empty(t, c.ModuleLevels())
+
+	var cycled []zerolog.Level
+	for i := 0; i < 6; i++ {
+		c.cycleLevel()
+		cycled = append(cycled, c.Level())
+	}
+	assert.Equal(t, []zerolog.Level{
+		zerolog.InfoLevel, zerolog.DebugLevel, zerolog.TraceLevel,
+		zerolog.InfoLevel, zerolog.DebugLevel, zerolog.TraceLevel,
+	}, cycled, "the first step is from warn to info, after trace it starts again by the initial level")
+}
+
+func TestNew_levelController(t *testing.T) {
+	var apiOut, dbOut syncBuffer
+	c := NewLevelController(zerolog.InfoLevel)
+	apiLog, apiFlushFn := New("api", zerolog.ErrorLevel, WithWriter(&apiOut), WithLevelController(c))
+	dbLog, dbFlushFn := New("db", zerolog.ErrorLevel, WithWriter(&dbOut), WithLevelController(c))
+	logAll := func(msg string) {
+		apiLog.Debug().Msg(msg)
+		apiLog.Info().Msg(msg)
+		dbLog.Trace().Msg(msg)
+		dbLog.Info().Msg(msg)
+	}
+
+	logAll("initial")
+	c.SetModuleLevel("db", zerolog.TraceLevel)
+	logAll("db traced")
+	c.SetLevel(zerolog.DebugLevel)
+	c.ResetModuleLevel("db")
+	logAll("debug")
+	apiFlushFn()
+	dbFlushFn()
+
+	levelsAndMessages := func(out *syncBuffer) []string {
+		var got []string
+		for _, line := range out.lines() {
+			for _, field := range []string{`"level":"`, `"message":"`} {
+				i := strings.Index(line, field) + len(field)
+				got = append(got, line[i:i+strings.IndexByte(line[i:], '"')])
+			}
+		}
+		return got
+	}
+	assert.Equal(t, []string{"info", "initial", "info", "db traced", "debug", "debug", "info", "debug"}, levelsAndMessages(&apiOut))
+	assert.Equal(t, []string{"info", "initial", "trace", "db traced", "info", "db traced", "info", "debug"}, levelsAndMessages(&dbOut))
+}
+
+func TestLevelController_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "get", method: http.MethodGet, wantStatus: http.StatusOK, wantBody: `{"level":"info","modules":{"db":"debug"}}`},
+		{name: "set global", method: http.MethodPut, query: "level=TRACE", wantStatus: http.StatusOK, wantBody: `{"level":"trace","modules":{"db":"debug"}}`},
+		{name: "set module", method: http.MethodPost, query: "module=api&level=warn", wantStatus: http.StatusOK, wantBody: `{"level":"info","modules":{"api":"warn","db":"debug"}}`},
+		{name: "reset module", method: http.MethodPut, query: "module=db", wantStatus: http.StatusOK, wantBody: `{"level":"info"}`},
+		{name: "missing level", method: http.MethodPut, wantStatus: http.StatusBadRequest, wantBody: "missing level"},
+		{name: "invalid level", method: http.MethodPut, query: "level=loud", wantStatus: http.StatusBadRequest, wantBody: `invalid level "loud"`},
+		{name: "method", method: http.MethodDelete, wantStatus: http.StatusMethodNotAllowed, wantBody: "only GET, PUT and POST requests are accepted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLevelController(zerolog.InfoLevel)
+			c.SetModuleLevel("db", zerolog.DebugLevel)
+			rec := httptest.NewRecorder()
+			c.ServeHTTP(rec, httptest.NewRequest(tt.method, "/loglevel?"+tt.query, nil))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		})
+	}
+}
+
+func TestLevelController_WatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loglevel")
+	c := NewLevelController(zerolog.InfoLevel)
+	var mu sync.Mutex
+	var errs []string
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.WatchFile(ctx, path, time.Millisecond, func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err.Error())
+		})
+	}()
+	defer func() {
+		cancelFn()
+		<-done
+	}()
+
+	require.NoError(t, os.WriteFile(path, []byte("# the levels\ndebug\n\ndb = trace\napi=warn\n"), 0o600))
+	require.Eventually(t, func() bool { return c.ModuleLevel("db") == zerolog.TraceLevel }, time.Second, time.Millisecond)
+	assert.Equal(t, zerolog.DebugLevel, c.Level())
+	assert.Equal(t, zerolog.WarnLevel, c.ModuleLevel("api"))
+
+	require.NoError(t, os.WriteFile(path, []byte("db=error\nloud\n"), 0o600))
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{`control file ` + path + `: line 2: invalid level "loud"`}, errs)
+	assert.Equal(t, zerolog.TraceLevel, c.ModuleLevel("db"), "the invalid file is not applied")
+
+	require.NoError(t, os.WriteFile(path, []byte("db=error\n"), 0o600))
+	require.Eventually(t, func() bool { return c.ModuleLevel("db") == zerolog.ErrorLevel }, time.Second, time.Millisecond)
+	assert.Equal(t, zerolog.DebugLevel, c.ModuleLevel("api"), "the modules are replaced")
+	assert.Equal(t, zerolog.DebugLevel, c.Level(), "the global level is kept")
+}
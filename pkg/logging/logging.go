@@ -1,7 +1,6 @@
 package logging
 
 import (
-	"io"
 	"os"
 	"time"
 
@@ -17,50 +16,96 @@ func init() {
 	zerolog.TimeFieldFormat = TimeFormat
 }
 
-//	New prepares a new zerolog.Logger logging to os.Stderr.
+//	New prepares a new zerolog.Logger logging to os.Stderr or to the writers given by the options (see Option).
 //	It guarantees that no logs will be dropped and will sacrifice performance to achieve this.
 //	However, this takes effect onlyin the case of high-frequency logging,
 //	otherwise it performs at the same velocity as a zerolog.Logger created using the NewDropping function.
 //	The New function returns the zerolog.Logger and a flush function which should be deferred immediately after the call of the New function.
 //	This ensures that the program does not finish before all the buffered logs have been written to the output
 //	If we do not need this functionality, this function can be ignored.
-func New(module string, severity zerolog.Level) (zerolog.Logger, func()) {
-	wr := newPW(os.Stderr, false)
-	return newLogger(wr, severity).With().Str(ModuleFieldName, module).Logger(), wr.Finalize
+func New(module string, severity zerolog.Level, opts ...Option) (zerolog.Logger, func()) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.newLogger(module, severity, true)
 }
 
-// NewDropping prepares a new unsafe zerolog.Logger logging to os.Stderr.
-// It focuses on the performance and can drop logs without any trace in case of a buffer overflow.
+// NewDropping prepares a new unsafe zerolog.Logger logging to os.Stderr or to the writers given by the options.
+// It focuses on the performance and can drop logs in case of a buffer overflow.
 // This can happen when the frequency of the logs is so high that we don't manage to write them to os.Stderr at this speed
+// The dropped logs are counted and reported by a record with the DroppedMessage (see WithDropCounter and WithDropReportInterval),
+// other overflow policies than DropNewest can be set by WithOverflowPolicy.
 // The flush function return value is described above (see New).
-func NewDropping(module string, severity zerolog.Level) (zerolog.Logger, func()) {
-	wr := newPW(os.Stderr, true)
-	return newLogger(wr, severity).With().Str(ModuleFieldName, module).Logger(), wr.Finalize
+func NewDropping(module string, severity zerolog.Level, opts ...Option) (zerolog.Logger, func()) {
+	return New(module, severity, append([]Option{WithOverflowPolicy(DropNewest)}, opts...)...)
 }
 
-func newLogger(w *parallelWriter, severity zerolog.Level) zerolog.Logger {
-	return zerolog.
-		New(io.Writer(w)).
-		With().
-		Caller().
-		Timestamp().
-		Logger().
-		Hook(nanoTsHook{}).
-		Hook(zerolog.LevelHook{FatalHook: &fatalHook{w}}).
-		Level(severity)
+// NewNetwork prepares a new zerolog.Logger logging to os.Stderr and to the collector configured by cfg (see NetworkWriter).
+// Each of the outputs is buffered separately, so an unreachable collector does not hold back the logs written to os.Stderr.
+// Like New, it does not drop any logs - when the buffer of the collector's output is full, the logging waits for the collector.
+// The flush function sends the remaining logs to the collector, giving up after cfg.CloseTimeout.
+func NewNetwork(module string, severity zerolog.Level, cfg NetworkConfig, opts ...Option) (zerolog.Logger, func(), error) {
+	nw, err := NewNetworkWriter(cfg)
+	if err != nil {
+		return zerolog.Nop(), func() {}, err
+	}
+	log, flushFn := New(module, severity, append([]Option{WithWriter(os.Stderr), WithWriteCloser(nw)}, opts...)...)
+	return log, flushFn, nil
+}
+
+// NewDroppingNetwork is the variant of NewNetwork dropping the logs of the full buffers like NewDropping.
+// The logs for the collector are dropped while it is unreachable, the ones written to os.Stderr only if they are too frequent.
+func NewDroppingNetwork(module string, severity zerolog.Level, cfg NetworkConfig, opts ...Option) (zerolog.Logger, func(), error) {
+	return NewNetwork(module, severity, cfg, append([]Option{WithOverflowPolicy(DropNewest)}, opts...)...)
+}
+
+// NewFile prepares a new zerolog.Logger logging to the file rotated by its size or by time (see RotatingFileWriter)
+// instead of os.Stderr. Like New, it does not drop any logs. The flush function closes the file.
+func NewFile(module string, severity zerolog.Level, cfg RotatingFileConfig, opts ...Option) (zerolog.Logger, func(), error) {
+	rw, err := NewRotatingFileWriter(cfg)
+	if err != nil {
+		return zerolog.Nop(), func() {}, err
+	}
+	log, flushFn := New(module, severity, append([]Option{WithWriteCloser(rw)}, opts...)...)
+	return log, flushFn, nil
+}
+
+// NewDroppingFile is the variant of NewFile dropping the logs of the full buffer like NewDropping.
+func NewDroppingFile(module string, severity zerolog.Level, cfg RotatingFileConfig, opts ...Option) (zerolog.Logger, func(), error) {
+	return NewFile(module, severity, cfg, append([]Option{WithOverflowPolicy(DropNewest)}, opts...)...)
 }
 
 // nanoTsHook adds a Unix nanosecond timestamp to the event into a field "ts"
 type nanoTsHook struct{}
 
 func (nth nanoTsHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
-	e.Int64("ts", time.Now().UnixNano())
+	nth.add(e, time.Now())
+}
+
+func (nanoTsHook) add(e *zerolog.Event, t time.Time) {
+	e.Int64("ts", t.UnixNano())
+}
+
+// timestampHook adds the current time in the format to the event, unlike zerolog's Timestamp it does not depend
+// on the global zerolog.TimeFieldFormat
+type timestampHook struct {
+	format string
 }
 
-type fatalHook struct {
-	w *parallelWriter
+func (th timestampHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	th.add(e, time.Now())
 }
 
-func (fh *fatalHook) Run(_ *zerolog.Event, _ zerolog.Level, _ string) {
-	fh.w.fatalShutdown()
+func (th timestampHook) add(e *zerolog.Event, t time.Time) {
+	switch th.format {
+	case zerolog.TimeFormatUnix:
+		e.Int64(zerolog.TimestampFieldName, t.Unix())
+	case zerolog.TimeFormatUnixMs:
+		e.Int64(zerolog.TimestampFieldName, t.UnixMilli())
+	case zerolog.TimeFormatUnixMicro:
+		e.Int64(zerolog.TimestampFieldName, t.UnixMicro())
+	default:
+		e.Str(zerolog.TimestampFieldName, t.Format(th.format))
+	}
 }
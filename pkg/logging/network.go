@@ -0,0 +1,347 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultMinBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff    = 30 * time.Second
+	defaultNetTimeout    = 10 * time.Second
+	defaultCloseTimeout  = 5 * time.Second
+
+	// udpMaxDatagram is the maximal size of a datagram with several records, bigger records are sent alone
+	udpMaxDatagram = 8 << 10
+	// udpMaxPayload is the maximal size of a UDP datagram, the bigger records cannot be sent and are dropped
+	udpMaxPayload = 65507
+	// SourceHeader is the HTTP header with the label of the sender of the records.
+	SourceHeader = "X-Log-Source"
+)
+
+// errPermanent marks the errors after which the sending of the batch is not retried
+var errPermanent = errors.New("permanent error")
+
+// NetworkConfig configures the NetworkWriter. Only the Address is required.
+type NetworkConfig struct {
+	// Address of the collector - tcp://host:port, udp://host:port or an http:// or https:// URL,
+	// to which the batches are sent by POST requests.
+	Address string
+	// Source labels the records of the sender, it is sent in the X-Log-Source header of the HTTP requests.
+	Source string
+	// BatchSize is the number of records sent together, 100 by default.
+	BatchSize int
+	// FlushInterval is the longest time the records wait for the batch to fill up, 1s by default.
+	FlushInterval time.Duration
+	// Gzip compresses the bodies of the HTTP requests.
+	Gzip bool
+	// MinBackoff and MaxBackoff bound the exponentially growing delay between the retries, 100ms and 30s by default.
+	MinBackoff, MaxBackoff time.Duration
+	// Timeout of connecting and of sending a batch, 10s by default.
+	Timeout time.Duration
+	// CloseTimeout limits the time of sending the remaining records by Close, 5s by default.
+	CloseTimeout time.Duration
+	// OnError is called with the errors of the sending, they are ignored if it is nil.
+	OnError func(error)
+}
+
+func (cfg *NetworkConfig) setDefaults() {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = defaultMaxBackoff
+		if cfg.MaxBackoff < cfg.MinBackoff {
+			cfg.MaxBackoff = cfg.MinBackoff
+		}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultNetTimeout
+	}
+	if cfg.CloseTimeout <= 0 {
+		cfg.CloseTimeout = defaultCloseTimeout
+	}
+}
+
+// NetworkWriter sends the newline delimited records to a collector, e.g. to the viewer started by `viewer listen`.
+// The records are sent in batches, a failed batch is retried with an exponential backoff until it is sent,
+// so the records are delivered at least once. Meanwhile, the Write calls block, which makes the parallelWriter
+// in front of the NetworkWriter either buffer or drop the records (see New and NewDropping).
+// The connection is established lazily, so the collector does not have to be running when the writer is created.
+type NetworkWriter struct {
+	cfg     NetworkConfig
+	network string // tcp, udp or http
+	addr    string // host:port or the URL
+	client  *http.Client
+
+	mu      sync.Mutex
+	batch   bytes.Buffer
+	records int
+	conn    net.Conn
+	closed  bool
+
+	stopCh    chan struct{} // stops the periodic flushing
+	abortCh   chan struct{} // stops the retrying when the CloseTimeout elapses
+	wg        sync.WaitGroup
+	abortOnce sync.Once
+	closeOnce sync.Once
+}
+
+// NewNetworkWriter returns the NetworkWriter sending the records to the cfg.Address.
+// The writer must be closed to send the remaining records.
+func NewNetworkWriter(cfg NetworkConfig) (*NetworkWriter, error) {
+	cfg.setDefaults()
+	u, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", cfg.Address, err)
+	}
+	nw := &NetworkWriter{
+		cfg:     cfg,
+		stopCh:  make(chan struct{}),
+		abortCh: make(chan struct{}),
+	}
+	switch u.Scheme {
+	case "tcp", "udp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid address %q: missing host:port", cfg.Address)
+		}
+		nw.network, nw.addr = u.Scheme, u.Host
+	case "http", "https":
+		nw.network, nw.addr = "http", u.String()
+		nw.client = &http.Client{Timeout: cfg.Timeout}
+	default:
+		return nil, fmt.Errorf("invalid address %q: the scheme must be tcp, udp, http or https", cfg.Address)
+	}
+	if cfg.Gzip && nw.network != "http" {
+		return nil, errors.New("gzip is supported only by the http and https addresses")
+	}
+
+	nw.wg.Add(1)
+	go func() {
+		defer nw.wg.Done()
+		ticker := time.NewTicker(cfg.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-nw.stopCh:
+				return
+			case <-ticker.C:
+				_ = nw.Flush()
+			}
+		}
+	}()
+	return nw, nil
+}
+
+// Write adds the record to the batch, which is sent if it is full.
+// The errors of the sending are reported to the OnError function, not returned.
+func (nw *NetworkWriter) Write(b []byte) (int, error) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	if nw.closed {
+		return 0, errors.New("network writer closed")
+	}
+	nw.batch.Write(b)
+	if len(b) == 0 || b[len(b)-1] != '\n' {
+		nw.batch.WriteByte('\n')
+	}
+	nw.records++
+	if nw.records >= nw.cfg.BatchSize {
+		_ = nw.flush()
+	}
+	return len(b), nil
+}
+
+// Flush sends the batch of the records immediately, it returns an error only if the batch is dropped.
+func (nw *NetworkWriter) Flush() error {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return nw.flush()
+}
+
+// Close sends the remaining records and closes the connection. If the collector is not reachable
+// within the CloseTimeout, the records are dropped.
+func (nw *NetworkWriter) Close() error {
+	var err error
+	nw.closeOnce.Do(func() {
+		nw.startClosing()
+		close(nw.stopCh)
+		nw.wg.Wait()
+
+		nw.mu.Lock()
+		defer nw.mu.Unlock()
+		nw.closed = true
+		err = nw.flush()
+		if nw.conn != nil {
+			_ = nw.conn.Close()
+			nw.conn = nil
+		}
+	})
+	return err
+}
+
+// startClosing starts the CloseTimeout, after which the retrying is aborted. It is called before the parallelWriter
+// in front of the NetworkWriter is finalized, so that its finalization does not wait for an unreachable collector forever.
+func (nw *NetworkWriter) startClosing() {
+	nw.abortOnce.Do(func() {
+		time.AfterFunc(nw.cfg.CloseTimeout, func() { close(nw.abortCh) })
+	})
+}
+
+// flush sends the batch, retrying it until it succeeds, a permanent error occurs or the writer is aborted.
+// The caller must hold the lock.
+func (nw *NetworkWriter) flush() error {
+	if nw.records == 0 {
+		return nil
+	}
+	defer func() {
+		nw.batch.Reset()
+		nw.records = 0
+	}()
+	backoff := nw.cfg.MinBackoff
+	for {
+		err := nw.send(nw.batch.Bytes())
+		if err == nil {
+			return nil
+		}
+		nw.reportErr(err)
+		if errors.Is(err, errPermanent) {
+			return fmt.Errorf("%d records dropped: %w", nw.records, err)
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-nw.abortCh:
+			timer.Stop()
+			err = fmt.Errorf("%d records dropped: %w", nw.records, err)
+			nw.reportErr(err)
+			return err
+		}
+		if backoff *= 2; backoff > nw.cfg.MaxBackoff {
+			backoff = nw.cfg.MaxBackoff
+		}
+	}
+}
+
+func (nw *NetworkWriter) send(batch []byte) error {
+	switch nw.network {
+	case "http":
+		return nw.post(batch)
+	case "udp":
+		for len(batch) > 0 {
+			n := datagramEnd(batch)
+			if err := nw.writeDatagram(batch[:n]); err != nil {
+				return err
+			}
+			batch = batch[n:]
+		}
+		return nil
+	default:
+		return nw.write(batch)
+	}
+}
+
+// datagramEnd returns the length of the records at the beginning of the batch fitting to one datagram.
+func datagramEnd(batch []byte) int {
+	if len(batch) <= udpMaxDatagram {
+		return len(batch)
+	}
+	if i := bytes.LastIndexByte(batch[:udpMaxDatagram], '\n'); i >= 0 {
+		return i + 1
+	}
+	if i := bytes.IndexByte(batch, '\n'); i >= 0 {
+		return i + 1
+	}
+	return len(batch)
+}
+
+// writeDatagram sends the datagram, a record which does not fit in a datagram is dropped and reported to the OnError
+// function, as its sending would never succeed.
+func (nw *NetworkWriter) writeDatagram(datagram []byte) error {
+	var err error = syscall.EMSGSIZE
+	if len(datagram) <= udpMaxPayload {
+		err = nw.write(datagram)
+	}
+	if errors.Is(err, syscall.EMSGSIZE) {
+		nw.reportErr(fmt.Errorf("record of %d bytes dropped: %w", len(datagram), err))
+		return nil
+	}
+	return err
+}
+
+// write writes the data to the TCP or UDP connection, which is reestablished on the next write if it fails.
+func (nw *NetworkWriter) write(data []byte) error {
+	if nw.conn == nil {
+		conn, err := net.DialTimeout(nw.network, nw.addr, nw.cfg.Timeout)
+		if err != nil {
+			return err
+		}
+		nw.conn = conn
+	}
+	_ = nw.conn.SetWriteDeadline(time.Now().Add(nw.cfg.Timeout))
+	if _, err := nw.conn.Write(data); err != nil {
+		_ = nw.conn.Close()
+		nw.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (nw *NetworkWriter) post(batch []byte) error {
+	body, encoding := batch, ""
+	if nw.cfg.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(batch) // writing to a buffer never fails
+		_ = zw.Close()
+		body, encoding = buf.Bytes(), "gzip"
+	}
+	req, err := http.NewRequest(http.MethodPost, nw.addr, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %s", errPermanent, err.Error())
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	if nw.cfg.Source != "" {
+		req.Header.Set(SourceHeader, nw.cfg.Source)
+	}
+	resp, err := nw.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // so that the connection can be reused
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("collector responded with %s", resp.Status)
+	default:
+		return fmt.Errorf("%w: collector responded with %s", errPermanent, resp.Status)
+	}
+}
+
+func (nw *NetworkWriter) reportErr(err error) {
+	if nw.cfg.OnError != nil {
+		nw.cfg.OnError(err)
+	}
+}
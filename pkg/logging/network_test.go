@@ -0,0 +1,271 @@
+package logging
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tcpCollector collects the lines received over all the accepted connections
+type tcpCollector struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	lines []string
+	conns []net.Conn
+}
+
+func newTCPCollector(t *testing.T) *tcpCollector {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	c := &tcpCollector{ln: ln}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.mu.Lock()
+			c.conns = append(c.conns, conn)
+			c.mu.Unlock()
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					c.mu.Lock()
+					c.lines = append(c.lines, scanner.Text())
+					c.mu.Unlock()
+				}
+			}()
+		}
+	}()
+	return c
+}
+
+func (c *tcpCollector) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+// dropConns closes the accepted connections, as if the collector was restarted
+func (c *tcpCollector) dropConns() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, conn := range c.conns {
+		_ = conn.Close()
+	}
+	c.conns = nil
+}
+
+func TestNewNetworkWriter_invalid(t *testing.T) {
+	for _, cfg := range []NetworkConfig{
+		{Address: "localhost:5170"},
+		{Address: "ftp://localhost:5170"},
+		{Address: "tcp://"},
+		{Address: "udp://localhost:5171", Gzip: true},
+	} {
+		_, err := NewNetworkWriter(cfg)
+		assert.Error(t, err, cfg.Address)
+	}
+}
+
+func TestNetworkWriter_tcp(t *testing.T) {
+	c := newTCPCollector(t)
+	nw, err := NewNetworkWriter(NetworkConfig{
+		Address:       "tcp://" + c.ln.Addr().String(),
+		BatchSize:     10,
+		FlushInterval: 10 * time.Millisecond,
+		MinBackoff:    time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	_, _ = nw.Write([]byte(`{"message":"1"}` + "\n"))
+	_, _ = nw.Write([]byte(`{"message":"2"}`)) // the newline is added
+	require.Eventually(t, func() bool { return len(c.received()) == 2 }, time.Second, time.Millisecond)
+
+	// the writes to the dropped connection fail sooner or later, after which the writer reconnects
+	c.dropConns()
+	require.Eventually(t, func() bool {
+		_, _ = nw.Write([]byte(`{"message":"after reconnect"}`))
+		_ = nw.Flush()
+		lines := c.received()
+		return lines[len(lines)-1] == `{"message":"after reconnect"}`
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, nw.Close())
+	assert.Equal(t, []string{`{"message":"1"}`, `{"message":"2"}`}, c.received()[:2])
+}
+
+func TestNetworkWriter_udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	nw, err := NewNetworkWriter(NetworkConfig{Address: "udp://" + conn.LocalAddr().String()})
+	require.NoError(t, err)
+
+	record := `{"message":"` + strings.Repeat("x", 3000) + `"}` + "\n"
+	for i := 0; i < 5; i++ {
+		_, _ = nw.Write([]byte(record))
+	}
+	require.NoError(t, nw.Close())
+
+	var datagrams []int
+	buf := make([]byte, 64<<10)
+	for received := 0; received < 5*len(record); {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n%len(record), "datagrams hold whole records")
+		datagrams = append(datagrams, n/len(record))
+		received += n
+	}
+	assert.Equal(t, []int{2, 2, 1}, datagrams)
+}
+
+func TestNetworkWriter_udpOversizedRecord(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	var errs []error
+	nw, err := NewNetworkWriter(NetworkConfig{
+		Address:       "udp://" + conn.LocalAddr().String(),
+		FlushInterval: time.Hour,
+		OnError:       func(err error) { errs = append(errs, err) },
+	})
+	require.NoError(t, err)
+
+	_, _ = nw.Write([]byte(`{"message":"1"}` + "\n"))
+	_, _ = nw.Write([]byte(`{"message":"` + strings.Repeat("x", 70<<10) + `"}` + "\n"))
+	_, _ = nw.Write([]byte(`{"message":"2"}` + "\n"))
+	done := make(chan error)
+	go func() { done <- nw.Flush() }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "only the oversized record is dropped")
+	case <-time.After(time.Second):
+		t.Fatal("flushing is retried forever")
+	}
+	require.NoError(t, nw.Close())
+
+	var received []string
+	buf := make([]byte, 64<<10)
+	for len(received) < 2 {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		received = append(received, strings.TrimSuffix(string(buf[:n]), "\n"))
+	}
+	assert.Equal(t, []string{`{"message":"1"}`, `{"message":"2"}`}, received)
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "record of 71695 bytes dropped: message too long")
+}
+
+func TestNetworkWriter_http(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		lines    []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "api", r.Header.Get(SourceHeader))
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		lines = append(lines, strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	var errs []error
+	nw, err := NewNetworkWriter(NetworkConfig{
+		Address:       ts.URL + "/ingest",
+		Source:        "api",
+		Gzip:          true,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		MinBackoff:    time.Millisecond,
+		OnError:       func(err error) { errs = append(errs, err) },
+	})
+	require.NoError(t, err)
+	for _, msg := range []string{"1", "2", "3"} {
+		_, _ = nw.Write([]byte(`{"message":"` + msg + `"}` + "\n"))
+	}
+	require.NoError(t, nw.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, requests, "the first batch is retried")
+	assert.Equal(t, []string{`{"message":"1"}`, `{"message":"2"}`, `{"message":"3"}`}, lines)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "503")
+}
+
+func TestNetworkWriter_httpPermanentError(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	nw, err := NewNetworkWriter(NetworkConfig{Address: ts.URL})
+	require.NoError(t, err)
+	_, _ = nw.Write([]byte(`{"message":"1"}`))
+	assert.ErrorContains(t, nw.Close(), "1 records dropped")
+	assert.Equal(t, 1, requests, "the batch is not retried")
+}
+
+func TestNetworkWriter_closeUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close()) // nobody listens on the address
+
+	nw, err := NewNetworkWriter(NetworkConfig{
+		Address:      "tcp://" + addr,
+		MinBackoff:   time.Millisecond,
+		CloseTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	_, _ = nw.Write([]byte(`{"message":"lost"}`))
+	start := time.Now()
+	assert.ErrorContains(t, nw.Close(), "1 records dropped")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.NoError(t, nw.Close(), "closing again is a no-op")
+}
+
+func TestNewNetwork(t *testing.T) {
+	c := newTCPCollector(t)
+	log, logFlushFn, err := NewNetwork("test", zerolog.InfoLevel, NetworkConfig{Address: "tcp://" + c.ln.Addr().String()})
+	require.NoError(t, err)
+	log.Debug().Msg("filtered")
+	log.Info().Msg("shipped")
+	logFlushFn()
+
+	require.Eventually(t, func() bool { return len(c.received()) == 1 }, time.Second, time.Millisecond)
+	line := c.received()[0]
+	assert.Contains(t, line, `"module":"test"`)
+	assert.Contains(t, line, `"message":"shipped"`)
+
+	_, _, err = NewDroppingNetwork("test", zerolog.InfoLevel, NetworkConfig{Address: "localhost"})
+	assert.Error(t, err)
+}
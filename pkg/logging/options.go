@@ -0,0 +1,374 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging/redact"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultBufferSize      = 100
+	defaultExitCode        = 10
+	defaultShutdownTimeout = 5 * time.Second
+	defaultDropReport      = 10 * time.Second
+)
+
+// Option configures the logger created by New.
+type Option func(*options)
+
+type output struct {
+	w      io.Writer
+	closer io.Closer // closed by the flush function, nil if the output is not owned by the logger
+	sinkOptions
+}
+
+// SinkOption configures an output added by WithSink.
+type SinkOption func(*sinkOptions)
+
+type sinkOptions struct {
+	level      zerolog.Level
+	bufferSize int             // the logger's buffer size if zero
+	overflow   *OverflowPolicy // the logger's overflow policy if nil
+	drops      *DropCounter
+	onError    func(error)
+	close      bool
+}
+
+type options struct {
+	outputs         []output
+	bufferSize      int
+	overflow        OverflowPolicy
+	drops           *DropCounter
+	dropReport      time.Duration
+	sampling        map[zerolog.Level]Sampling
+	samplingSummary time.Duration
+	levels          *LevelController
+	redactor        *redact.Redactor
+	stackLevel      zerolog.Level
+	fields          map[string]interface{}
+	callerSkip      int
+	hooks           []zerolog.Hook
+	timeFormat      string
+	fatal           fatalOptions
+}
+
+type fatalOptions struct {
+	exit            func(code int)
+	exitCode        int
+	shutdownFns     []func(ctx context.Context)
+	shutdownTimeout time.Duration
+}
+
+func defaultOptions() options {
+	return options{
+		bufferSize:      defaultBufferSize,
+		dropReport:      defaultDropReport,
+		samplingSummary: defaultSamplingSummary,
+		stackLevel:      zerolog.Disabled,
+		timeFormat:      TimeFormat,
+		fatal: fatalOptions{
+			exit:            os.Exit,
+			exitCode:        defaultExitCode,
+			shutdownTimeout: defaultShutdownTimeout,
+		},
+	}
+}
+
+// WithWriter adds an output of the logs, os.Stderr is used if no output is given.
+// Every output has its own buffer and goroutine writing to it, so a slow output does not hold back the others.
+// The writer is not closed by the flush function.
+func WithWriter(w io.Writer) Option {
+	return WithSink(w)
+}
+
+// WithWriteCloser adds an output like WithWriter, which is closed by the flush function once all its logs are written,
+// e.g. a file, a RotatingFileWriter or a NetworkWriter.
+func WithWriteCloser(w io.WriteCloser) Option {
+	return WithSink(w, SinkClose())
+}
+
+// WithSink adds an output like WithWriter with its own level threshold, buffer size and overflow policy, e.g.
+// the pretty-printed logs on the console from the info level, all the logs in a file and the warnings sent to a collector.
+// The severity of the logger applies to all the outputs, so the outputs cannot get the logs below it.
+func WithSink(w io.Writer, opts ...SinkOption) Option {
+	return func(o *options) {
+		out := output{w: w, sinkOptions: sinkOptions{level: zerolog.TraceLevel}}
+		for _, opt := range opts {
+			opt(&out.sinkOptions)
+		}
+		if c, ok := w.(io.Closer); ok && out.close {
+			out.closer = c
+		}
+		o.outputs = append(o.outputs, out)
+	}
+}
+
+// SinkLevel makes the output get only the logs of the level and the higher levels.
+func SinkLevel(level zerolog.Level) SinkOption {
+	return func(so *sinkOptions) {
+		so.level = level
+	}
+}
+
+// SinkBufferSize sets the number of the logs buffered for the output, the size set by WithBufferSize is used by default.
+func SinkBufferSize(size int) SinkOption {
+	return func(so *sinkOptions) {
+		if size > 0 {
+			so.bufferSize = size
+		}
+	}
+}
+
+// SinkOverflowPolicy sets the overflow policy of the output, the policy set by WithOverflowPolicy is used by default.
+func SinkOverflowPolicy(policy OverflowPolicy) SinkOption {
+	return func(so *sinkOptions) {
+		so.overflow = &policy
+	}
+}
+
+// SinkDropCounter counts the logs dropped by the output in the counter in addition to the counter set by WithDropCounter.
+func SinkDropCounter(c *DropCounter) SinkOption {
+	return func(so *sinkOptions) {
+		so.drops = c
+	}
+}
+
+// SinkOnError reports the errors of writing to the output to the function, e.g. a full disk. The logs which could not
+// be written are counted and reported as dropped, the errors are ignored if no function is set.
+func SinkOnError(fn func(error)) SinkOption {
+	return func(so *sinkOptions) {
+		so.onError = fn
+	}
+}
+
+// SinkClose makes the flush function close the output implementing io.Closer once all its logs are written.
+func SinkClose() SinkOption {
+	return func(so *sinkOptions) {
+		so.close = true
+	}
+}
+
+// WithBufferSize sets the number of the logs buffered for every output, 100 by default.
+func WithBufferSize(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.bufferSize = size
+		}
+	}
+}
+
+// WithOverflowPolicy sets what happens with the logs when the buffer of an output is full, Block by default.
+// The dropped logs are counted (see WithDropCounter) and reported (see WithDropReportInterval).
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflow = policy
+	}
+}
+
+// WithDropCounter counts the logs dropped by all the outputs in the counter, e.g. to export them as a metric.
+func WithDropCounter(c *DropCounter) Option {
+	return func(o *options) {
+		o.drops = c
+	}
+}
+
+// WithDropReportInterval sets how often an output checks if it dropped any logs and writes the record with
+// the DroppedMessage reporting them once its buffer is at most half full, 10s by default. The record is written
+// by the warn level and has the fields dropped (the counts by the level), dropped_total, dropped_since and dropped_until.
+// The periodic reports are disabled by a non-positive interval, the drops are still reported by the flush function.
+func WithDropReportInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.dropReport = interval
+	}
+}
+
+// WithSampling samples the logs of the level, e.g. the debug logs of the hot loops, so they do not flood the outputs.
+// The suppressed logs are summarized by a record with the SampledMessage (see WithSamplingSummaryInterval).
+// The fatal and panic logs are never sampled.
+func WithSampling(level zerolog.Level, sampling Sampling) Option {
+	return func(o *options) {
+		if o.sampling == nil {
+			o.sampling = make(map[zerolog.Level]Sampling)
+		}
+		o.sampling[level] = sampling
+	}
+}
+
+// WithSamplingSummaryInterval sets how often the logs suppressed by the sampling are summarized, 10s by default.
+// The summary is written by the info level with the first sampled log after the interval passes and by the flush function,
+// it has the fields sampled (the counts by the level), sampled_total, sampled_since, sampled_until
+// and sampled_messages (the counts by the message of the logs sampled by the message).
+// Only the flush function writes the summary if the interval is not positive.
+func WithSamplingSummaryInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.samplingSummary = interval
+	}
+}
+
+// WithLevelController makes the level of the logger controlled at runtime by the controller, the severity given
+// to the constructor is replaced by the level of the logger's module in the controller. One controller can be shared
+// by the loggers of several modules. The controller is a zerolog.Sampler, so it is replaced by the calls of Sample.
+func WithLevelController(c *LevelController) Option {
+	return func(o *options) {
+		o.levels = c
+	}
+}
+
+// WithRedaction masks the secrets and the personal data of the logs (see redact.Redactor) before they are written
+// to any output, e.g. redact.Default(). The zerolog hooks can only add the fields, so the redaction is applied
+// to the encoded logs by the logger's writer.
+func WithRedaction(r *redact.Redactor) Option {
+	return func(o *options) {
+		o.redactor = r
+	}
+}
+
+// WithStackTrace adds the stack trace (see StackFrame) in the StackFieldName field to the logs of the level and
+// the higher levels, e.g. zerolog.ErrorLevel. The stack trace carried by the error logged by Err (see StackTracer)
+// is preferred, the stack of the goroutine writing the log is used otherwise. The stack traces of the errors are added
+// to the logs of all the levels, zerolog.ErrorStackMarshaler is set to MarshalStack for them unless it is already set.
+func WithStackTrace(level zerolog.Level) Option {
+	return func(o *options) {
+		o.stackLevel = level
+	}
+}
+
+// WithFields adds the static fields to all the logs, e.g. the version or the host name.
+func WithFields(fields map[string]interface{}) Option {
+	return func(o *options) {
+		if o.fields == nil {
+			o.fields = make(map[string]interface{}, len(fields))
+		}
+		for k, v := range fields {
+			o.fields[k] = v
+		}
+	}
+}
+
+// WithCallerSkip skips the additional stack frames when the caller field is added, e.g. 1 for the logs written
+// by a helper function to report the caller of the helper.
+func WithCallerSkip(skip int) Option {
+	return func(o *options) {
+		o.callerSkip = skip
+	}
+}
+
+// WithHook adds the hook run for every log after the built-in ones adding the caller and the timestamps.
+func WithHook(hook zerolog.Hook) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hook)
+	}
+}
+
+// WithTimestampFormat sets the format of the time field - a time layout or one of zerolog.TimeFormatUnix,
+// zerolog.TimeFormatUnixMs and zerolog.TimeFormatUnixMicro. TimeFormat is used by default.
+func WithTimestampFormat(format string) Option {
+	return func(o *options) {
+		o.timeFormat = format
+	}
+}
+
+// WithExitFunc sets the function ending the program after a fatal log, os.Exit by default. zerolog exits the program
+// by os.Exit(1) whenever the function returns (or the goroutine ends), so the functions replacing os.Exit in tests
+// should block the goroutine logging the fatal log forever.
+func WithExitFunc(exit func(code int)) Option {
+	return func(o *options) {
+		o.fatal.exit = exit
+	}
+}
+
+// WithExitCode sets the exit code of the program after a fatal log, 10 by default.
+func WithExitCode(code int) Option {
+	return func(o *options) {
+		o.fatal.exitCode = code
+	}
+}
+
+// WithShutdownFunc adds the function run after a fatal log before the buffered logs are written and the program exits,
+// e.g. restoring the terminal or closing the connections. The functions are run in the order they are added
+// and they should return when the context is done (see WithShutdownTimeout).
+func WithShutdownFunc(fn func(ctx context.Context)) Option {
+	return func(o *options) {
+		o.fatal.shutdownFns = append(o.fatal.shutdownFns, fn)
+	}
+}
+
+// WithShutdownTimeout limits the time of running the shutdown functions and writing the buffered logs after a fatal log,
+// the program exits when it passes. It is 5s by default.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout > 0 {
+			o.fatal.shutdownTimeout = timeout
+		}
+	}
+}
+
+// newLogger creates the logger writing to the outputs through their parallelWriters. The caller and the timestamps
+// are added by the logger itself unless they are added by the writer of the logs, see SlogHandler.
+func (o options) newLogger(module string, severity zerolog.Level, ownRecordFields bool) (zerolog.Logger, func()) {
+	outputs := o.outputs
+	if len(outputs) == 0 {
+		outputs = []output{{w: os.Stderr, sinkOptions: sinkOptions{level: zerolog.TraceLevel}}}
+	}
+	wr := &fanOut{fatal: o.fatal, redactor: o.redactor, stackLevel: o.stackLevel}
+	for _, out := range outputs {
+		bufferSize, overflow := o.bufferSize, o.overflow
+		if out.bufferSize > 0 {
+			bufferSize = out.bufferSize
+		}
+		if out.overflow != nil {
+			overflow = *out.overflow
+		}
+		var counters []*DropCounter
+		for _, c := range []*DropCounter{o.drops, out.drops} {
+			if c != nil {
+				counters = append(counters, c)
+			}
+		}
+		pw := newPW(out.w, pwOptions{
+			bufferSize:     bufferSize,
+			overflow:       overflow,
+			counters:       counters,
+			reportInterval: o.dropReport,
+			onError:        out.onError,
+		})
+		wr.sinks = append(wr.sinks, levelSink{parallelWriter: pw, level: out.level})
+		if out.closer != nil {
+			wr.outputs = append(wr.outputs, out.closer)
+		}
+	}
+
+	ctx := zerolog.New(wr).With().Str(ModuleFieldName, module)
+	if len(o.fields) > 0 {
+		ctx = ctx.Fields(o.fields)
+	}
+	if o.stackLevel != zerolog.Disabled {
+		setStackMarshaler()
+		ctx = ctx.Stack()
+	}
+	log := ctx.Logger()
+	if ownRecordFields {
+		log = ctx.CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + o.callerSkip).Logger()
+	}
+	if len(o.sampling) > 0 {
+		summaryLog := ctx.Logger().Hook(timestampHook{format: o.timeFormat}).Hook(nanoTsHook{})
+		wr.sampler = newSampler(o.sampling, o.samplingSummary, summaryLog)
+		log = log.Hook(wr.sampler)
+	}
+	if ownRecordFields {
+		log = log.
+			Hook(timestampHook{format: o.timeFormat}).
+			Hook(nanoTsHook{})
+	}
+	for _, hook := range o.hooks {
+		log = log.Hook(hook)
+	}
+	if o.levels != nil {
+		return log.Level(zerolog.TraceLevel).Sample(levelSampler{c: o.levels, module: module}), wr.Finalize
+	}
+	return log.Level(severity), wr.Finalize
+}
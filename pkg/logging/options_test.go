@@ -0,0 +1,304 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging/redact"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is an output of the tests
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSuffix(b.buf.String(), "\n"), "\n")
+}
+
+// blockingWriter blocks the writes until it is released
+type blockingWriter struct {
+	syncBuffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.syncBuffer.Write(p)
+}
+
+// failingWriter fails the writes until it is repaired
+type failingWriter struct {
+	syncBuffer
+	mu     sync.Mutex
+	broken bool
+}
+
+func (w *failingWriter) setBroken(broken bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.broken = broken
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.broken {
+		return 0, errors.New("no space left on device")
+	}
+	return w.syncBuffer.Write(p)
+}
+
+type fieldHook struct{}
+
+func (fieldHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	e.Bool("hooked", true)
+}
+
+func logFromHelper(log zerolog.Logger) {
+	log.Info().Msg("from helper")
+}
+
+func TestNew_options(t *testing.T) {
+	var out1, out2 syncBuffer
+	log, logFlushFn := New("test", zerolog.InfoLevel,
+		WithWriter(&out1),
+		WithWriter(&out2),
+		WithFields(map[string]interface{}{"version": "1.2.3"}),
+		WithTimestampFormat(zerolog.TimeFormatUnixMs),
+		WithHook(fieldHook{}),
+		WithCallerSkip(1),
+	)
+	log.Debug().Msg("filtered")
+	_, _, line, _ := runtime.Caller(0)
+	logFromHelper(log) // the caller of the helper is reported
+	logFlushFn()
+
+	assert.Equal(t, out1.lines(), out2.lines(), "all the outputs get the logs")
+	lines := out1.lines()
+	require.Len(t, lines, 1)
+	var rec map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, "test", rec[ModuleFieldName])
+	assert.Equal(t, "1.2.3", rec["version"])
+	assert.Equal(t, true, rec["hooked"])
+	assert.IsType(t, float64(0), rec["time"], "unix milliseconds")
+	assert.True(t, strings.HasSuffix(rec["caller"].(string), fmt.Sprintf("options_test.go:%d", line+1)), rec["caller"])
+	assert.True(t, strings.HasPrefix(lines[0], `{"level":"info","module":"test"`), lines[0])
+}
+
+func TestNew_overflowPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      OverflowPolicy
+		blocks      bool // the logging waits for the output
+		wantDropped bool
+		wantLog     string // the log which must not be dropped
+	}{
+		{name: "block", policy: Block, blocks: true},
+		{name: "drop newest", policy: DropNewest, wantDropped: true, wantLog: `"i":0`},
+		{name: "drop oldest", policy: DropOldest, wantDropped: true, wantLog: `"i":9`},
+		{name: "drop below level", policy: DropBelowLevel(zerolog.WarnLevel), blocks: true, wantDropped: true},
+		{name: "block with timeout", policy: BlockWithTimeout(time.Millisecond), wantDropped: true, wantLog: `"i":0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &blockingWriter{release: make(chan struct{})}
+			var drops DropCounter
+			log, logFlushFn := New("test", zerolog.InfoLevel,
+				WithWriter(out), WithBufferSize(1), WithOverflowPolicy(tt.policy), WithDropCounter(&drops))
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 10; i++ {
+					level := zerolog.InfoLevel
+					if i%2 == 1 {
+						level = zerolog.WarnLevel
+					}
+					log.WithLevel(level).Int("i", i).Send()
+				}
+			}()
+			if !tt.blocks {
+				<-done // the logging is not blocked
+			}
+			close(out.release)
+			<-done
+			logFlushFn()
+
+			lines := out.lines()
+			if !tt.wantDropped {
+				assert.Len(t, lines, 10)
+				assert.Zero(t, drops.Total())
+				return
+			}
+			require.NotZero(t, drops.Total())
+			assert.Len(t, lines, 10-int(drops.Total())+1, "the records and the drop report")
+			report := lines[len(lines)-1]
+			assert.Contains(t, report, DroppedMessage)
+			assert.Contains(t, report, fmt.Sprintf(`"dropped_total":%d`, drops.Total()))
+			if tt.wantLog != "" {
+				assert.Contains(t, strings.Join(lines, "\n"), tt.wantLog)
+			}
+			if tt.policy == DropBelowLevel(zerolog.WarnLevel) {
+				assert.Zero(t, drops.Dropped(zerolog.WarnLevel), "the warnings are not dropped")
+			}
+		})
+	}
+}
+
+func TestNew_sinks(t *testing.T) {
+	var console, file syncBuffer
+	slow := &blockingWriter{release: make(chan struct{})}
+	log, logFlushFn := New("test", zerolog.DebugLevel,
+		WithSink(&console, SinkLevel(zerolog.InfoLevel)),
+		WithSink(&file),
+		WithSink(slow, SinkLevel(zerolog.WarnLevel), SinkBufferSize(1), SinkOverflowPolicy(DropNewest)),
+	)
+	for i := 0; i < 5; i++ {
+		log.Debug().Int("i", i).Send()
+		log.Info().Int("i", i).Send()
+		log.Warn().Int("i", i).Send()
+	}
+	log.Trace().Msg("below the severity of the logger")
+	log.Log().Msg("no level")
+
+	require.Eventually(t, func() bool { return len(file.lines()) == 16 }, time.Second, time.Millisecond, "the slow sink does not block the others")
+	close(slow.release)
+	logFlushFn()
+
+	assert.Len(t, file.lines(), 16)
+	assert.Len(t, console.lines(), 11)
+	for _, line := range console.lines()[:10] {
+		assert.NotContains(t, line, `"level":"debug"`)
+	}
+	slowLines := slow.lines()
+	assert.LessOrEqual(t, len(slowLines), 3, "the warnings not fitting in the buffer are dropped")
+	assert.Contains(t, slowLines[0], `"level":"warn"`)
+	assert.Contains(t, slowLines[len(slowLines)-1], DroppedMessage)
+}
+
+func TestNew_sinkWriteError(t *testing.T) {
+	var healthy syncBuffer
+	failing := &failingWriter{broken: true}
+	var (
+		errsMu sync.Mutex
+		errs   []error
+	)
+	var drops DropCounter
+	log, logFlushFn := New("test", zerolog.InfoLevel,
+		WithSink(&healthy),
+		WithSink(failing, SinkDropCounter(&drops), SinkOnError(func(err error) {
+			errsMu.Lock()
+			defer errsMu.Unlock()
+			errs = append(errs, err)
+		})),
+	)
+	log.Info().Msg("lost")
+	log.Warn().Msg("lost")
+	require.Eventually(t, func() bool { return drops.Total() == 2 }, time.Second, time.Millisecond)
+	failing.setBroken(false)
+	log.Info().Msg("written")
+	logFlushFn()
+
+	assert.Len(t, healthy.lines(), 3, "the failing sink does not affect the others")
+	failingLines := failing.lines()
+	require.Len(t, failingLines, 2)
+	assert.Contains(t, failingLines[0], "written")
+	assert.Contains(t, failingLines[1], DroppedMessage)
+	assert.Equal(t, uint64(1), drops.Dropped(zerolog.WarnLevel))
+	errsMu.Lock()
+	defer errsMu.Unlock()
+	require.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "no space left on device")
+}
+
+// fatalInGoroutine logs the fatal log in a goroutine blocked by the exit function and returns the exit code.
+func fatalInGoroutine(t *testing.T, opts ...Option) (int, zerolog.Logger, func()) {
+	t.Helper()
+	exitCh := make(chan int, 1)
+	opts = append(opts, WithExitFunc(func(code int) {
+		exitCh <- code
+		select {} // zerolog would exit the test otherwise
+	}))
+	log, logFlushFn := New("test", zerolog.InfoLevel, opts...)
+	go func() {
+		log.Info().Msg(`a message with {"level":"fatal" does not exit`)
+		log.Fatal().Msg("OH NO!")
+		t.Error("the goroutine logging the fatal log should be blocked")
+	}()
+	select {
+	case code := <-exitCh:
+		return code, log, logFlushFn
+	case <-time.After(5 * time.Second):
+		t.Fatal("the exit function was not called")
+		return 0, log, logFlushFn
+	}
+}
+
+func TestNew_fatal(t *testing.T) {
+	var out syncBuffer
+	var shutdowns []string
+	code, log, logFlushFn := fatalInGoroutine(t,
+		WithWriter(&out),
+		WithExitCode(3),
+		WithShutdownFunc(func(ctx context.Context) { shutdowns = append(shutdowns, "first") }),
+		WithShutdownFunc(func(ctx context.Context) {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			shutdowns = append(shutdowns, "second")
+		}),
+	)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, []string{"first", "second"}, shutdowns)
+	lines := out.lines()
+	require.Len(t, lines, 2, "the buffered logs are written before the exit")
+	assert.Contains(t, lines[1], `"level":"fatal"`)
+
+	log.Info().Msg("after the shutdown")
+	logFlushFn()
+	assert.Len(t, out.lines(), 2, "the logs after the shutdown are dropped")
+}
+
+func TestNew_fatalShutdownTimeout(t *testing.T) {
+	start := time.Now()
+	code, _, _ := fatalInGoroutine(t,
+		WithWriter(io.Discard),
+		WithShutdownTimeout(50*time.Millisecond),
+		WithShutdownFunc(func(ctx context.Context) { select {} }), // never returns
+	)
+	assert.Equal(t, 10, code)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestNew_redaction(t *testing.T) {
+	var out syncBuffer
+	log, logFlushFn := New("test", zerolog.InfoLevel, WithWriter(&out), WithRedaction(redact.Default()))
+	log.Info().Str("password", "hunter2").Str("user", "bob").Msg("login of bob@example.com")
+	logFlushFn()
+
+	lines := out.lines()
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"password":"[REDACTED]","user":"bob"`)
+	assert.Contains(t, lines[0], `"message":"login of [REDACTED]"`)
+}
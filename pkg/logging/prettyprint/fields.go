@@ -1,11 +1,21 @@
 package prettyprint
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"time"
 )
 
+const (
+	levelFldName   = "level"
+	moduleFldName  = "module"
+	callerFldName  = "caller"
+	timeFldName    = "time"
+	messageFldName = "message"
+)
+
 var logFieldNames []string
 
 func init() {
@@ -29,16 +39,29 @@ type LogFields struct {
 type LogItem struct {
 	LogFields
 	Extra map[string]interface{}
+	// Keys holds all the top-level keys of the record (including the LogFields ones)
+	// in the order in which they first appeared in the JSON input.
+	Keys []string
 }
 
 func (l *LogItem) UnmarshalJSON(bytes []byte) error {
-	var logFields LogFields
+	return l.unmarshal(bytes, FieldMapping{})
+}
 
-	if err := json.Unmarshal(bytes, &logFields); err != nil {
+// unmarshal decodes the log record renaming its keys according to the mapping first.
+func (l *LogItem) unmarshal(data []byte, mapping FieldMapping) error {
+	keys, extra, err := decodeOrdered(data)
+	if err != nil {
 		return err
 	}
-	extra := make(map[string]interface{})
-	if err := json.Unmarshal(bytes, &extra); err != nil {
+	if !mapping.IsZero() {
+		keys = mapping.rename(keys, extra)
+		if data, err = json.Marshal(extra); err != nil {
+			return err
+		}
+	}
+	var logFields LogFields
+	if err := json.Unmarshal(data, &logFields); err != nil {
 		return err
 	}
 	for key := range extra {
@@ -51,5 +74,43 @@ func (l *LogItem) UnmarshalJSON(bytes []byte) error {
 
 	l.LogFields = logFields
 	l.Extra = extra
+	l.Keys = keys
 	return nil
 }
+
+// decodeOrdered decodes a JSON object and returns its values together with the list of its keys in the original order.
+// Numbers are kept as json.Number so that e.g. nanosecond timestamps are not rounded.
+// If a key is present multiple times, the last value wins but the position of its first occurrence is kept.
+func decodeOrdered(data []byte) ([]string, map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	t, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if delim, ok := t.(json.Delim); !ok || delim != '{' {
+		return nil, nil, errors.New("log record is not a JSON object")
+	}
+
+	var keys []string
+	values := make(map[string]interface{})
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return nil, nil, errors.New("unexpected JSON object key")
+		}
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	return keys, values, nil
+}
@@ -0,0 +1,95 @@
+package prettyprint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RecordFilter selects the log records which are printed. Its zero value lets all the records through.
+// The level of the records is filtered by the Output itself.
+type RecordFilter struct {
+	// Since and Until limit the timestamps of the records to the interval [Since, Until). The zero values mean no limit.
+	// The records without a timestamp are filtered out if any of the limits is set.
+	Since, Until time.Time
+	// Fields are the required values of the record fields, compared in the same way as the HighlightRule.Value.
+	Fields map[string]string
+	// Pattern is a regular expression searched for in the whole (raw) record.
+	Pattern *regexp.Regexp
+}
+
+// IsZero reports whether the filter lets all the records through.
+func (f RecordFilter) IsZero() bool {
+	return f.Since.IsZero() && f.Until.IsZero() && len(f.Fields) == 0 && f.Pattern == nil
+}
+
+// MatchRaw reports whether a line, which is not a log record, passes the filter.
+// Such lines only pass the filters which do not examine the record fields.
+func (f RecordFilter) MatchRaw(line string) bool {
+	if !f.Since.IsZero() || !f.Until.IsZero() || len(f.Fields) > 0 {
+		return false
+	}
+	return f.Pattern == nil || f.Pattern.MatchString(line)
+}
+
+// Match reports whether the line passes the filter. The keys of the record fields are renamed according to the mapping first.
+// The lines which are not log records are matched by MatchRaw.
+func (f RecordFilter) Match(line string, mapping FieldMapping) bool {
+	var logItem LogItem
+	if err := logItem.unmarshal([]byte(line), mapping); err != nil {
+		return f.MatchRaw(line)
+	}
+	return f.match(line, &logItem)
+}
+
+func (f RecordFilter) match(line string, logItem *LogItem) bool {
+	if f.Pattern != nil && !f.Pattern.MatchString(line) {
+		return false
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		ts := logItem.Timestamp
+		if ts.IsZero() || (!f.Since.IsZero() && ts.Before(f.Since)) || (!f.Until.IsZero() && !ts.Before(f.Until)) {
+			return false
+		}
+	}
+	for fldName, expected := range f.Fields {
+		if value, ok := logItem.fieldString(fldName); !ok || value != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseFilterTime parses the time limit of the RecordFilter - an RFC 3339 time, a date (2006-01-02) or a duration before now (e.g. 1h).
+// An empty value means no limit.
+func ParseFilterTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 time, date (2006-01-02) or duration (e.g. 1h30m)", value)
+}
+
+// ParseFilterFields parses the comma separated list of the field values required by the RecordFilter, e.g. user=bob,status=500.
+func ParseFilterFields(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	fields := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		name, fldValue, ok := strings.Cut(item, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid field filter %q, expected name=value", item)
+		}
+		fields[name] = fldValue
+	}
+	return fields, nil
+}
@@ -1,78 +1,106 @@
 package prettyprint
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
-	"time"
 
-	"github.com/matusvla/logviewer/pkg/logging"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+	"github.com/rs/zerolog"
 )
 
 const (
-	colorBlack = iota + 30
-	colorRed
-	colorGreen
-	colorYellow
-	colorMagenta
-	colorBlue
-	colorCyan
-	colorWhite
-
-	colorBold     = 1
-	colorDarkGray = 90
-)
-
-const (
-	callerSeparatorMark = "\x1b[36m > \x1b[0m"
-	noTimeString        = "--:--:--.---_---_---"
+	callerSeparatorMark = " > "
 	secondTimeFormat    = "15:04:05"
 )
 
-func formatTS(ts interface{}) (result string) {
-	defer func() { // format result - color gray
-		result = fmt.Sprintf("\x1b[%dm%v\x1b[0m", colorDarkGray, result)
-	}()
+// formatTS renders the time of the record formatted by the timeFormatter, its padding is not styled
+func formatTS(th theme.Theme, ts interface{}) string {
+	tsStr, _ := ts.(string)
+	text := strings.TrimRight(tsStr, " ")
+	return th.Render(th.Timestamp, text) + tsStr[len(text):]
+}
 
-	tsStr, ok := ts.(string)
-	if !ok {
-		return noTimeString
+func formatLevel(th theme.Theme, level interface{}) string {
+	lvl, _ := level.(string)
+	var label string
+	switch lvl {
+	case zerolog.LevelTraceValue:
+		label = "TRC"
+	case zerolog.LevelDebugValue:
+		label = "DBG"
+	case zerolog.LevelInfoValue:
+		label = "INF"
+	case zerolog.LevelWarnValue:
+		label = "WRN"
+	case zerolog.LevelErrorValue:
+		label = "ERR"
+	case zerolog.LevelFatalValue:
+		label = "FTL"
+	case zerolog.LevelPanicValue:
+		label = "PNC"
+	default:
+		label = "???"
 	}
-	t, err := time.Parse(logging.TimeFormat, tsStr)
-	if err != nil {
-		return tsStr
+	return th.Render(th.Levels.Style(lvl), label)
+}
+
+func formatFieldName(th theme.Theme, fldName string) string {
+	style := th.FieldNameStyle(fldName)
+	if _, ok := th.Fields[fldName]; !ok && fldName == zerolog.ErrorFieldName {
+		style = th.ErrorFieldName
 	}
-	timestamp := t.Format(secondTimeFormat)
-	nsAll := t.Nanosecond()
-	ms := (nsAll / 1_000_000) % 1000
-	us := (nsAll / 1000) % 1000
-	ns := nsAll % 1000
-	return fmt.Sprintf("%s.%03d_%03d_%03d", timestamp, ms, us, ns)
+	return th.Render(style, fldName+"=")
 }
 
-func formatFieldName(ts interface{}) string {
-	fldName, ok := ts.(string)
-	if !ok {
-		return ""
+func formatFieldValue(th theme.Theme, fldName string, value interface{}, highlight theme.Style) string {
+	style := th.FieldValueStyle(fldName)
+	if _, ok := th.Fields[fldName]; !ok && fldName == zerolog.ErrorFieldName {
+		style = th.ErrorFieldValue
 	}
-	if fldName == logging.ModuleFieldName {
-		return fmt.Sprintf("\x1b[%dm%v=\x1b[0m", colorBlue, fldName)
+	style = style.Merge(highlight)
+	switch v := value.(type) {
+	case string:
+		if needsQuote(v) {
+			return th.Render(style, strconv.Quote(v))
+		}
+		return th.Render(style, v)
+	case json.Number:
+		return th.Render(style, v.String())
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return th.Render(th.ErrorFieldValue, fmt.Sprintf("[error: %v]", err))
+		}
+		return th.Render(style, string(b))
+	}
+}
+
+// needsQuote returns true when the string s should be quoted in output (same rules as zerolog.ConsoleWriter uses)
+func needsQuote(s string) bool {
+	for i := range s {
+		if s[i] < 0x20 || s[i] > 0x7e || s[i] == ' ' || s[i] == '\\' || s[i] == '"' {
+			return true
+		}
 	}
-	return fmt.Sprintf("\x1b[%dm%v=\x1b[0m", colorMagenta, fldName)
+	return false
 }
 
-func formatCaller(caller interface{}, width int) string {
+func formatCaller(th theme.Theme, caller interface{}, width int) string {
 	const placeholderChar = "_"
 	value, ok := caller.(string)
 	if !ok {
 		return strings.Repeat(placeholderChar, width)
 	}
+	separator := th.Render(th.CallerSeparator, callerSeparatorMark)
 	n := len(value)
 	switch {
 	case n < width:
-		return strings.Repeat(placeholderChar, width-n) + value + callerSeparatorMark
+		return th.Render(th.Caller, strings.Repeat(placeholderChar, width-n)+value) + separator
 	case n == width:
-		return value + callerSeparatorMark
+		return th.Render(th.Caller, value) + separator
 	default:
-		return "..." + value[n-width+3:n] + callerSeparatorMark
+		return th.Render(th.Caller, "..."+value[n-width+3:n]) + separator
 	}
 }
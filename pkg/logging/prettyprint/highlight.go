@@ -0,0 +1,220 @@
+package prettyprint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+)
+
+// HighlightRule colors the log records (or their parts) matching a field value or a regular expression.
+type HighlightRule struct {
+	// Field is the name of the matched field. If empty, the rule is matched against the log message.
+	Field string `yaml:"field,omitempty"`
+	// Value is compared with the (string representation of the) field value. It has to match exactly.
+	Value string `yaml:"value,omitempty"`
+	// Pattern is a regular expression searched for in the field value. It is used instead of the Value if set.
+	Pattern string `yaml:"pattern,omitempty"`
+	// Record makes the rule paint the whole record instead of just the matched text.
+	Record bool        `yaml:"record,omitempty"`
+	Style  theme.Style `yaml:"style"`
+}
+
+// Validate checks whether the rule can be applied.
+func (r HighlightRule) Validate() error {
+	if r.Field == "" && r.Pattern == "" && r.Value == "" {
+		return errors.New("highlight rule needs a field, a value or a pattern")
+	}
+	if r.Pattern != "" {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("invalid highlight pattern %q: %w", r.Pattern, err)
+		}
+	}
+	if r.Style.IsZero() {
+		return errors.New("highlight rule does not change the style")
+	}
+	return nil
+}
+
+func (r HighlightRule) String() string {
+	var sb strings.Builder
+	fldName := r.Field
+	if fldName == "" {
+		fldName = messageFldName
+	}
+	sb.WriteString(fldName)
+	switch {
+	case r.Pattern != "":
+		fmt.Fprintf(&sb, " ~ /%s/", r.Pattern)
+	case r.Value != "":
+		fmt.Fprintf(&sb, " = %q", r.Value)
+	default:
+		sb.WriteString(" present")
+	}
+	fmt.Fprintf(&sb, " → fg: %s, bg: %s", r.Style.Fg, r.Style.Bg)
+	if r.Style.Bold {
+		sb.WriteString(", bold")
+	}
+	if r.Record {
+		sb.WriteString(" (whole record)")
+	}
+	return sb.String()
+}
+
+type compiledRule struct {
+	HighlightRule
+	re *regexp.Regexp
+}
+
+// highlighter applies the valid highlight rules, the invalid ones are ignored.
+type highlighter []compiledRule
+
+func newHighlighter(rules []HighlightRule) highlighter {
+	var h highlighter
+	for _, rule := range rules {
+		if rule.Validate() != nil {
+			continue
+		}
+		cr := compiledRule{HighlightRule: rule}
+		if rule.Pattern != "" {
+			cr.re = regexp.MustCompile(rule.Pattern)
+		}
+		h = append(h, cr)
+	}
+	return h
+}
+
+func (cr compiledRule) matches(value string) bool {
+	switch {
+	case cr.re != nil:
+		return cr.re.MatchString(value)
+	case cr.Value != "":
+		return value == cr.Value
+	default:
+		return true
+	}
+}
+
+// recordStyle returns the merged style of all the whole-record rules matching the log item.
+func (h highlighter) recordStyle(logItem *LogItem) theme.Style {
+	var style theme.Style
+	for _, rule := range h {
+		if !rule.Record {
+			continue
+		}
+		if value, ok := logItem.fieldString(rule.Field); ok && rule.matches(value) {
+			style = style.Merge(rule.Style)
+		}
+	}
+	return style
+}
+
+// fieldStyle returns the merged style of all the rules (except for the message pattern ones) matching the field value.
+func (h highlighter) fieldStyle(logItem *LogItem, fldName string) theme.Style {
+	var style theme.Style
+	for _, rule := range h {
+		if rule.Record || rule.Field != fldName || rule.Field == "" {
+			continue
+		}
+		if value, ok := logItem.fieldString(fldName); ok && rule.matches(value) {
+			style = style.Merge(rule.Style)
+		}
+	}
+	return style
+}
+
+// message renders the log message highlighting all the parts matched by the message rules.
+// If multiple rules match the same part of the message, the first one wins.
+func (h highlighter) message(th theme.Theme, msg string) string {
+	styles := make([]int, len(msg)) // index of the rule + 1 for every byte of the message
+	var anyMatch bool
+	for i, rule := range h {
+		if rule.Record || (rule.Field != "" && rule.Field != messageFldName) {
+			continue
+		}
+		var matches [][]int
+		switch {
+		case rule.re != nil:
+			matches = rule.re.FindAllStringIndex(msg, -1)
+		case rule.Value == msg:
+			matches = [][]int{{0, len(msg)}}
+		}
+		for _, match := range matches {
+			for j := match[0]; j < match[1]; j++ {
+				if styles[j] == 0 {
+					styles[j] = i + 1
+					anyMatch = true
+				}
+			}
+		}
+	}
+	if !anyMatch {
+		return th.Render(th.Message, msg)
+	}
+	var sb strings.Builder
+	for start := 0; start < len(msg); {
+		end := start + 1
+		for end < len(msg) && styles[end] == styles[start] {
+			end++
+		}
+		style := th.Message
+		if ruleIndex := styles[start]; ruleIndex > 0 {
+			style = style.Merge(h[ruleIndex-1].Style)
+		}
+		sb.WriteString(th.Render(style, msg[start:end]))
+		start = end
+	}
+	return sb.String()
+}
+
+// fieldString returns the string representation of the field value as it is matched by the highlight rules.
+func (l *LogItem) fieldString(fldName string) (string, bool) {
+	switch fldName {
+	case "", messageFldName:
+		return l.Message, true
+	case levelFldName:
+		return l.Level, l.Level != ""
+	case moduleFldName:
+		return l.Module, l.Module != ""
+	case callerFldName:
+		return l.Caller, l.Caller != ""
+	}
+	value, ok := l.Extra[fldName]
+	if !ok {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(b), true
+	}
+}
+
+// recordWriter paints the whole records written by the zerolog.ConsoleWriter with the style of the current record.
+type recordWriter struct {
+	out   io.Writer
+	mode  theme.ColorMode
+	style theme.Style
+}
+
+func (rw *recordWriter) Write(p []byte) (int, error) {
+	if rw.style.IsZero() {
+		return rw.out.Write(p)
+	}
+	line := strings.TrimSuffix(string(p), "\n")
+	if _, err := io.WriteString(rw.out, rw.style.RenderNested(rw.mode, line)+"\n"); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
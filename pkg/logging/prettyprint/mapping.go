@@ -0,0 +1,118 @@
+package prettyprint
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// FieldMapping maps the special fields of the log records to the keys used by the logging library that produced them,
+// e.g. {Message: "msg", Time: "ts"} for the records written by zap. Empty values mean the default (zerolog) keys.
+type FieldMapping struct {
+	Level   string `yaml:"level,omitempty"`
+	Module  string `yaml:"module,omitempty"`
+	Caller  string `yaml:"caller,omitempty"`
+	Time    string `yaml:"time,omitempty"`
+	Message string `yaml:"message,omitempty"`
+}
+
+// IsZero reports whether the mapping keeps all the default keys.
+func (fm FieldMapping) IsZero() bool {
+	return fm == FieldMapping{}
+}
+
+// LevelKey returns the key of the level field in the mapped records.
+func (fm FieldMapping) LevelKey() string {
+	if fm.Level == "" {
+		return levelFldName
+	}
+	return fm.Level
+}
+
+// TimeKey returns the key of the time field in the mapped records.
+func (fm FieldMapping) TimeKey() string {
+	if fm.Time == "" {
+		return timeFldName
+	}
+	return fm.Time
+}
+
+// MessageKey returns the key of the message field in the mapped records.
+func (fm FieldMapping) MessageKey() string {
+	if fm.Message == "" {
+		return messageFldName
+	}
+	return fm.Message
+}
+
+// CallerKey returns the key of the caller field in the mapped records.
+func (fm FieldMapping) CallerKey() string {
+	if fm.Caller == "" {
+		return callerFldName
+	}
+	return fm.Caller
+}
+
+// pairs returns the mapped keys together with the names of the fields they are mapped to.
+func (fm FieldMapping) pairs() [][2]string {
+	var pairs [][2]string
+	for _, p := range [][2]string{
+		{fm.Level, levelFldName},
+		{fm.Module, moduleFldName},
+		{fm.Caller, callerFldName},
+		{fm.Time, timeFldName},
+		{fm.Message, messageFldName},
+	} {
+		if p[0] != "" && p[0] != p[1] {
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs
+}
+
+// rename moves the values of the mapped keys to the default field names in place and returns the updated list of keys.
+// Numeric timestamps are converted to RFC 3339, the unit (s, ms, µs or ns since epoch) is derived from their magnitude.
+func (fm FieldMapping) rename(keys []string, values map[string]interface{}) []string {
+	for _, p := range fm.pairs() {
+		from, to := p[0], p[1]
+		value, ok := values[from]
+		if !ok {
+			continue
+		}
+		delete(values, from)
+		if to == timeFldName {
+			if n, ok := value.(json.Number); ok {
+				if ts, err := n.Float64(); err == nil {
+					value = unixTime(ts).Format(time.RFC3339Nano)
+				}
+			}
+		}
+		_, replaced := values[to]
+		values[to] = value
+		renamed := keys[:0]
+		for _, key := range keys {
+			switch {
+			case key == to && replaced:
+				continue // the original field is shadowed by the mapped one
+			case key == from:
+				key = to
+			}
+			renamed = append(renamed, key)
+		}
+		keys = renamed
+	}
+	return keys
+}
+
+func unixTime(ts float64) time.Time {
+	switch abs := math.Abs(ts); {
+	case abs < 1e11:
+		return time.Unix(0, int64(ts*1e9))
+	case abs < 1e14:
+		return time.Unix(0, int64(ts*1e6))
+	case abs < 1e17:
+		return time.Unix(0, int64(ts*1e3))
+	default:
+		return time.Unix(0, int64(ts))
+	}
+}
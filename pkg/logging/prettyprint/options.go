@@ -0,0 +1,230 @@
+package prettyprint
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultCallerWidth is the width of the caller column used when no other width is set.
+const DefaultCallerWidth = 30
+
+// Options are the settings of the presentation of the callers and the times of the records, see Output.WithOptions.
+type Options struct {
+	// CallerWidth is the number of characters to which the caller is aligned and shortened, DefaultCallerWidth if not positive.
+	CallerWidth int
+	// CallerBasename shortens the caller to the base name of its file, e.g. options.go:42.
+	CallerBasename bool
+	// TimestampFormat is the layout of the times, TimestampTime by default.
+	TimestampFormat TimestampFormat
+	// Location is the time zone the times are shown in, nil keeps the time zones of the records.
+	Location *time.Location
+	// Relative shows the times relative to the previous record or to the Anchor instead of the times of the records.
+	Relative RelativeTime
+	// Anchor is the time from which the relative times of RelativeToAnchor are computed.
+	Anchor time.Time
+}
+
+// TimestampFormat is a preset of the layout of the record times.
+type TimestampFormat int
+
+const (
+	// TimestampTime shows only the time with the nanoseconds, e.g. 11:59:05.123_456_789.
+	TimestampTime TimestampFormat = iota
+	// TimestampDateTime shows the date and the time with the milliseconds, e.g. 2022-05-22 11:59:05.123.
+	TimestampDateTime
+	// TimestampRFC3339 shows the time in the RFC 3339 format with the nanoseconds and the time zone.
+	TimestampRFC3339
+	// TimestampEpoch shows the seconds since the Unix epoch with the nanoseconds, e.g. 1653220745.123456789.
+	TimestampEpoch
+)
+
+var timestampFormatNames = map[TimestampFormat]string{
+	TimestampTime:     "time",
+	TimestampDateTime: "datetime",
+	TimestampRFC3339:  "rfc3339",
+	TimestampEpoch:    "epoch",
+}
+
+func (tf TimestampFormat) String() string {
+	if name, ok := timestampFormatNames[tf]; ok {
+		return name
+	}
+	return fmt.Sprintf("TimestampFormat(%d)", int(tf))
+}
+
+// ParseTimestampFormat converts the name of the preset ("time", "datetime", "rfc3339" or "epoch") to the TimestampFormat.
+func ParseTimestampFormat(name string) (TimestampFormat, error) {
+	for tf, tfName := range timestampFormatNames {
+		if tfName == name {
+			return tf, nil
+		}
+	}
+	return TimestampTime, fmt.Errorf("unknown timestamp format %q, expected time, datetime, rfc3339 or epoch", name)
+}
+
+// placeholderTime is formatted to the placeholder shown instead of the time of the records without it,
+// its epoch seconds have the same number of digits as the current ones
+var placeholderTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// format returns the time formatted by the preset
+func (tf TimestampFormat) format(t time.Time) string {
+	switch tf {
+	case TimestampDateTime:
+		return t.Format("2006-01-02 15:04:05.000")
+	case TimestampRFC3339:
+		return t.Format("2006-01-02T15:04:05.000000000-07:00")
+	case TimestampEpoch:
+		return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+	default:
+		ns := t.Nanosecond()
+		return fmt.Sprintf("%s.%03d_%03d_%03d", t.Format(secondTimeFormat), ns/1_000_000, ns/1000%1000, ns%1000)
+	}
+}
+
+// placeholder returns the text of the width of the formatted times, e.g. --:--:--.---_---_--- for TimestampTime
+func (tf TimestampFormat) placeholder() string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '-'
+		}
+		return r
+	}, tf.format(placeholderTime))
+}
+
+// RelativeTime determines whether the times of the records are shown relative to another time.
+type RelativeTime int
+
+const (
+	// RelativeOff shows the times of the records.
+	RelativeOff RelativeTime = iota
+	// RelativeToPrevious shows the time elapsed since the previous printed record.
+	RelativeToPrevious
+	// RelativeToAnchor shows the time elapsed since the anchor time, see Options.Anchor.
+	RelativeToAnchor
+)
+
+var relativeTimeNames = map[RelativeTime]string{
+	RelativeOff:        "off",
+	RelativeToPrevious: "previous",
+	RelativeToAnchor:   "anchor",
+}
+
+func (rt RelativeTime) String() string {
+	if name, ok := relativeTimeNames[rt]; ok {
+		return name
+	}
+	return fmt.Sprintf("RelativeTime(%d)", int(rt))
+}
+
+// ParseRelativeTime converts the name of the relative time mode ("off", "previous" or "anchor") to the RelativeTime.
+func ParseRelativeTime(name string) (RelativeTime, error) {
+	for rt, rtName := range relativeTimeNames {
+		if rtName == name {
+			return rt, nil
+		}
+	}
+	return RelativeOff, fmt.Errorf("unknown relative time %q, expected off, previous or anchor", name)
+}
+
+// ParseTimeZone returns the time zone with the name - "local", "UTC", a fixed offset (e.g. +02:00) or a name
+// of the IANA Time Zone database (e.g. Europe/Bratislava). An empty name returns nil, which keeps the time zones
+// of the records.
+func ParseTimeZone(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return nil, nil
+	case "local":
+		return time.Local, nil
+	case "utc", "z":
+		return time.UTC, nil
+	}
+	if name[0] == '+' || name[0] == '-' {
+		t, err := time.Parse("-07:00", name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone offset %q, expected e.g. +02:00", name)
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(name, offset), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// formatDelta formats the elapsed time with the nanoseconds, e.g. +00:00:01.250_000_000
+func formatDelta(d time.Duration) string {
+	sign := '+'
+	if d < 0 {
+		sign, d = '-', -d
+	}
+	h, m, s := int64(d/time.Hour), int64(d/time.Minute%60), int64(d/time.Second%60)
+	ns := int64(d % time.Second)
+	return fmt.Sprintf("%c%02d:%02d:%02d.%03d_%03d_%03d", sign, h, m, s, ns/1_000_000, ns/1000%1000, ns%1000)
+}
+
+// timeFormatter renders the times of the records, it remembers the time of the previous record for RelativeToPrevious
+type timeFormatter struct {
+	options  Options
+	width    int // of the time column, the absolute and the relative times are padded to it
+	previous time.Time
+}
+
+func newTimeFormatter(opts Options) timeFormatter {
+	width := len(opts.TimestampFormat.placeholder())
+	if delta := len(formatDelta(0)); opts.Relative != RelativeOff && width < delta {
+		width = delta
+	}
+	return timeFormatter{options: opts, width: width}
+}
+
+// format returns the rendered time of the record padded to the width of the column, the placeholder for the records
+// without the time
+func (tf *timeFormatter) format(t time.Time) string {
+	return fmt.Sprintf("%-*s", tf.width, tf.text(t))
+}
+
+func (tf *timeFormatter) text(t time.Time) string {
+	if t.IsZero() {
+		return tf.options.TimestampFormat.placeholder()
+	}
+	previous := tf.previous
+	tf.previous = t
+	switch {
+	case tf.options.Relative == RelativeToPrevious && !previous.IsZero():
+		return formatDelta(t.Sub(previous))
+	case tf.options.Relative == RelativeToAnchor && !tf.options.Anchor.IsZero():
+		return formatDelta(t.Sub(tf.options.Anchor))
+	}
+	if tf.options.Location != nil {
+		t = t.In(tf.options.Location)
+	}
+	return tf.options.TimestampFormat.format(t)
+}
+
+// shortenCaller returns the caller shortened according to the options
+func (opts Options) shortenCaller(caller string) string {
+	if opts.CallerBasename && caller != "" {
+		return path.Base(caller)
+	}
+	return caller
+}
+
+// ErrNoTime is returned by RecordTime for the records without the time.
+var ErrNoTime = errors.New("the record has no time")
+
+// RecordTime returns the time of the log record with the keys given by the mapping.
+func RecordTime(line string, mapping FieldMapping) (time.Time, error) {
+	var logItem LogItem
+	if err := logItem.unmarshal([]byte(line), mapping); err != nil {
+		return time.Time{}, err
+	}
+	if logItem.Timestamp.IsZero() {
+		return time.Time{}, ErrNoTime
+	}
+	return logItem.Timestamp, nil
+}
@@ -0,0 +1,78 @@
+package prettyprint
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FieldOrder determines the order in which the extra fields of a log record are printed.
+type FieldOrder int
+
+const (
+	// FieldOrderOriginal keeps the fields in the order in which they appear in the JSON record.
+	FieldOrderOriginal FieldOrder = iota
+	// FieldOrderAlphabetical sorts the fields by their names.
+	FieldOrderAlphabetical
+)
+
+var fieldOrderNames = map[FieldOrder]string{
+	FieldOrderOriginal:     "original",
+	FieldOrderAlphabetical: "alphabetical",
+}
+
+func (fo FieldOrder) String() string {
+	if name, ok := fieldOrderNames[fo]; ok {
+		return name
+	}
+	return fmt.Sprintf("FieldOrder(%d)", int(fo))
+}
+
+// ParseFieldOrder converts the name of the field order ("original" or "alphabetical") to the FieldOrder.
+func ParseFieldOrder(name string) (FieldOrder, error) {
+	for fo, foName := range fieldOrderNames {
+		if foName == name {
+			return fo, nil
+		}
+	}
+	return FieldOrderOriginal, fmt.Errorf("unknown field order %q, expected original or alphabetical", name)
+}
+
+// fieldOrdering describes which of the extra fields are printed and in what order.
+type fieldOrdering struct {
+	order    FieldOrder
+	priority []string
+	hidden   map[string]struct{}
+}
+
+// apply returns the keys that should be printed in the order in which they should be printed.
+// The priority fields go first (in the order they were specified), the rest is ordered according to the FieldOrder.
+func (fo fieldOrdering) apply(keys []string) []string {
+	result := make([]string, 0, len(keys))
+	rest := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := fo.hidden[key]; ok {
+			continue
+		}
+		if fo.priorityIndex(key) >= 0 {
+			result = append(result, key)
+			continue
+		}
+		rest = append(rest, key)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return fo.priorityIndex(result[i]) < fo.priorityIndex(result[j])
+	})
+	if fo.order == FieldOrderAlphabetical {
+		sort.Strings(rest)
+	}
+	return append(result, rest...)
+}
+
+func (fo fieldOrdering) priorityIndex(key string) int {
+	for i, p := range fo.priority {
+		if p == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -2,7 +2,6 @@ package prettyprint
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -11,16 +10,24 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+	"github.com/matusvla/logviewer/pkg/logging/redact"
 	"github.com/rs/zerolog"
 )
 
 func PrintFromFile(filePath string, logLvl zerolog.Level, callerWidth int) {
-	if err := FprintFromFile(os.Stdout, filePath, logLvl, callerWidth); err != nil {
+	out := NewOutput(os.Stdout, logLvl, callerWidth).WithTheme(autoTheme())
+	if err := printFromFile(&out, filePath); err != nil {
 		fmt.Println(err)
 	}
 }
 
 func FprintFromFile(writer io.Writer, filePath string, logLvl zerolog.Level, callerWidth int) error {
+	out := NewOutput(writer, logLvl, callerWidth)
+	return printFromFile(&out, filePath)
+}
+
+func printFromFile(out *Output, filePath string) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
@@ -34,16 +41,6 @@ func FprintFromFile(writer io.Writer, filePath string, logLvl zerolog.Level, cal
 	// see https://stackoverflow.com/questions/21124327/how-to-read-a-text-file-line-by-line-in-go-when-some-lines-are-long-enough-to-ca
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 1024*1024)
-	out := Output{
-		log: zerolog.New(&zerolog.ConsoleWriter{
-			Out:             writer,
-			FormatTimestamp: formatTS,
-			FormatFieldName: formatFieldName,
-			FormatCaller: func(caller interface{}) string {
-				return formatCaller(caller, callerWidth)
-			},
-		}).Level(logLvl),
-	}
 	for scanner.Scan() {
 		if err := out.ProcessLine(scanner.Text()); err != nil {
 			return err
@@ -52,6 +49,13 @@ func FprintFromFile(writer io.Writer, filePath string, logLvl zerolog.Level, cal
 	return scanner.Err()
 }
 
+// autoTheme returns the default theme with the color mode detected from the environment
+func autoTheme() theme.Theme {
+	th := theme.Dark()
+	th.ColorMode = theme.DetectColorMode()
+	return th
+}
+
 func StreamFromPipe(cmdString string, logLvl zerolog.Level, callerWidth int) {
 	signalC := make(chan os.Signal, 1)
 	signal.Notify(signalC, os.Interrupt)
@@ -81,16 +85,7 @@ func StreamFromPipe(cmdString string, logLvl zerolog.Level, callerWidth int) {
 		scanner := bufio.NewScanner(cmdReader)
 		buf := make([]byte, 0, 64*1024)
 		scanner.Buffer(buf, 1024*1024)
-		out := Output{
-			log: zerolog.New(&zerolog.ConsoleWriter{
-				Out:             os.Stdout,
-				FormatTimestamp: formatTS,
-				FormatFieldName: formatFieldName,
-				FormatCaller: func(caller interface{}) string {
-					return formatCaller(caller, callerWidth)
-				},
-			}).Level(logLvl),
-		}
+		out := NewOutput(os.Stdout, logLvl, callerWidth).WithTheme(autoTheme())
 		for scanner.Scan() {
 			line := scanner.Text()
 			err := out.ProcessLine(line)
@@ -114,54 +109,198 @@ func StreamFromPipe(cmdString string, logLvl zerolog.Level, callerWidth int) {
 }
 
 type Output struct {
-	log zerolog.Logger
+	log        zerolog.Logger
+	ordering   fieldOrdering
+	mapping    FieldMapping
+	filter     RecordFilter
+	highlights highlighter
+	redactor   *redact.Redactor
+	record     *recordWriter
+
+	stackModules []string
+
+	writer io.Writer
+	logLvl zerolog.Level
+	times  timeFormatter
+	theme  theme.Theme
 }
 
+// NewOutput prepares an Output pretty-printing the log records to the writer using the dark theme.
+// The other presentation settings than the caller width can be set by WithOptions.
 func NewOutput(writer io.Writer, logLvl zerolog.Level, callerWidth int) Output {
 	return Output{
-		log: zerolog.New(&zerolog.ConsoleWriter{
-			Out: writer,
-			FormatTimestamp: func(ts interface{}) string {
-				return formatTS(ts)
-			},
-			FormatFieldName: func(fldName interface{}) string {
-				return formatFieldName(fldName)
-			},
-			FormatCaller: func(caller interface{}) string {
-				return formatCaller(caller, callerWidth)
-			},
-		}).Level(logLvl),
+		writer: writer,
+		logLvl: logLvl,
+		times:  newTimeFormatter(Options{CallerWidth: callerWidth}),
+		theme:  theme.Dark(),
+	}.withLogger()
+}
+
+// withLogger sets up the underlying zerolog.Logger according to the current settings of the Output.
+func (o Output) withLogger() Output {
+	th, callerWidth := o.theme, o.times.options.CallerWidth
+	if callerWidth <= 0 {
+		callerWidth = DefaultCallerWidth
 	}
+	o.record = &recordWriter{out: o.writer, mode: th.ColorMode}
+	o.log = zerolog.New(&zerolog.ConsoleWriter{
+		Out:     o.record,
+		NoColor: th.ColorMode == theme.ColorModeNone,
+		FormatTimestamp: func(ts interface{}) string {
+			return formatTS(th, ts)
+		},
+		FormatLevel: func(level interface{}) string {
+			return formatLevel(th, level)
+		},
+		FormatCaller: func(caller interface{}) string {
+			return formatCaller(th, caller, callerWidth)
+		},
+	}).Level(o.logLvl)
+	return o
 }
 
-func (o *Output) ProcessLine(line string) error {
-	const (
-		timeFldName   = "time"
-		callerFldName = "caller"
-		moduleFldName = "module"
-	)
+// WithTheme sets the color scheme (and the color mode) used for printing.
+func (o Output) WithTheme(th theme.Theme) Output {
+	o.theme = th
+	return o.withLogger()
+}
+
+// WithOptions sets the presentation of the callers and the times of the records.
+func (o Output) WithOptions(opts Options) Output {
+	o.times = newTimeFormatter(opts)
+	return o.withLogger()
+}
+
+// Options returns the presentation settings of the callers and the times of the records.
+func (o Output) Options() Options {
+	return o.times.options
+}
+
+// WithHighlightRules sets the rules used to highlight the records. The invalid rules (see HighlightRule.Validate) are ignored.
+func (o Output) WithHighlightRules(rules ...HighlightRule) Output {
+	o.highlights = newHighlighter(rules)
+	return o
+}
+
+// WithFieldOrder sets the order in which the extra fields are printed. The default is FieldOrderOriginal.
+func (o Output) WithFieldOrder(order FieldOrder) Output {
+	o.ordering.order = order
+	return o
+}
+
+// WithPriorityFields makes the given fields to be printed first (in the given order) right after the log message.
+func (o Output) WithPriorityFields(fldNames ...string) Output {
+	o.ordering.priority = append([]string(nil), fldNames...)
+	return o
+}
+
+// WithHiddenFields makes the given fields not to be printed at all.
+func (o Output) WithHiddenFields(fldNames ...string) Output {
+	hidden := make(map[string]struct{}, len(fldNames))
+	for _, fldName := range fldNames {
+		hidden[fldName] = struct{}{}
+	}
+	o.ordering.hidden = hidden
+	return o
+}
+
+// WithFieldMapping sets the keys from which the special fields (level, message, ...) of the records are read.
+func (o Output) WithFieldMapping(mapping FieldMapping) Output {
+	o.mapping = mapping
+	return o
+}
+
+// WithRedaction masks the secrets and the personal data of the printed records (see redact.Redactor),
+// nil prints the records as they are.
+func (o Output) WithRedaction(r *redact.Redactor) Output {
+	o.redactor = r
+	return o
+}
+
+// WithStackModules sets the module paths (e.g. github.com/matusvla/logviewer) whose frames are highlighted
+// in the stack traces of the records. The module of the innermost frame of the trace is highlighted by default.
+func (o Output) WithStackModules(modules ...string) Output {
+	o.stackModules = append([]string(nil), modules...)
+	return o
+}
+
+// WithFilter sets the filter of the printed records. The records not passing it are silently skipped.
+func (o Output) WithFilter(filter RecordFilter) Output {
+	o.filter = filter
+	return o
+}
 
+func (o *Output) ProcessLine(line string) error {
 	var logItem LogItem
-	err := json.Unmarshal([]byte(line), &logItem)
-	if err != nil {
+	if err := logItem.unmarshal(o.redactor.Redact([]byte(line)), o.mapping); err != nil {
 		return err
 	}
+	if !o.filter.match(line, &logItem) {
+		return nil
+	}
 	level, _ := zerolog.ParseLevel(logItem.Level) // we ignore the error - it defaults to no level
 
 	logMsg := o.log.
 		WithLevel(level).
-		Str(callerFldName, logItem.Caller)
+		Str(callerFldName, o.times.options.shortenCaller(logItem.Caller))
+	if !logMsg.Enabled() {
+		return nil
+	}
+	logMsg = logMsg.Str(timeFldName, o.times.format(logItem.Timestamp))
+
+	// the extra fields are rendered here and not by the zerolog.ConsoleWriter, because it always sorts them alphabetically
+	o.record.style = o.highlights.recordStyle(&logItem)
+	defer func() {
+		o.record.style = theme.Style{}
+	}()
 
-	if mdl := logItem.Module; mdl != "" {
-		logMsg = logMsg.Str(moduleFldName, mdl)
+	msg := logItem.Message
+	if msg != "" {
+		msg = o.highlights.message(o.theme, msg)
 	}
-	if timestamp := logItem.Timestamp; !timestamp.IsZero() {
-		logMsg = logMsg.Time(timeFldName, logItem.Timestamp)
+	stack := o.takeStack(&logItem) // printed on the lines below the record
+	if fields := o.formatFields(&logItem); fields != "" {
+		if msg != "" {
+			msg += " "
+		}
+		msg += fields
 	}
-	for fldKey, val := range logItem.Extra {
-		logMsg = logMsg.Interface(fldKey, val)
+	if stack != "" {
+		msg += "\n" + stack
 	}
-
-	logMsg.Msg(logItem.Message)
+	logMsg.Msg(msg)
 	return nil
 }
+
+// Observe takes the record into account without printing it, so the time of the following record can be shown
+// relative to it (see RelativeToPrevious), e.g. when the records are printed from the middle of a file.
+func (o *Output) Observe(line string) {
+	if t, err := RecordTime(line, o.mapping); err == nil {
+		o.times.previous = t
+	}
+}
+
+func (o *Output) formatFields(logItem *LogItem) string {
+	var sb strings.Builder
+	for _, key := range o.ordering.apply(logItem.Keys) {
+		var value interface{}
+		switch key {
+		case moduleFldName:
+			if logItem.Module == "" {
+				continue
+			}
+			value = logItem.Module
+		default:
+			var ok bool
+			if value, ok = logItem.Extra[key]; !ok {
+				continue // one of the LogFields printed elsewhere
+			}
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(formatFieldName(o.theme, key))
+		sb.WriteString(formatFieldValue(o.theme, key, value, o.highlights.fieldStyle(logItem, key)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,503 @@
+package prettyprint
+
+import (
+	"bytes"
+	"go/build"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging"
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+	"github.com/matusvla/logviewer/pkg/logging/redact"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutput_ProcessLine_FieldOrder(t *testing.T) {
+	const line = `{"level":"info","zeta":"z","module":"test","alpha":1,"caller":"main.go:1","mid":"m m","message":"msg"}`
+
+	tests := []struct {
+		name   string
+		output func(Output) Output
+		want   string
+	}{
+		{
+			name:   "original order",
+			output: func(o Output) Output { return o },
+			want:   "msg \x1b[34mzeta=\x1b[0mz \x1b[35mmodule=\x1b[0mtest \x1b[34malpha=\x1b[0m1 \x1b[34mmid=\x1b[0m\"m m\"",
+		},
+		{
+			name:   "alphabetical order",
+			output: func(o Output) Output { return o.WithFieldOrder(FieldOrderAlphabetical) },
+			want:   "msg \x1b[34malpha=\x1b[0m1 \x1b[34mmid=\x1b[0m\"m m\" \x1b[35mmodule=\x1b[0mtest \x1b[34mzeta=\x1b[0mz",
+		},
+		{
+			name: "priority fields",
+			output: func(o Output) Output {
+				return o.WithFieldOrder(FieldOrderAlphabetical).WithPriorityFields("module", "zeta", "missing")
+			},
+			want: "msg \x1b[35mmodule=\x1b[0mtest \x1b[34mzeta=\x1b[0mz \x1b[34malpha=\x1b[0m1 \x1b[34mmid=\x1b[0m\"m m\"",
+		},
+		{
+			name:   "hidden fields",
+			output: func(o Output) Output { return o.WithHiddenFields("module", "mid") },
+			want:   "msg \x1b[34mzeta=\x1b[0mz \x1b[34malpha=\x1b[0m1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bb bytes.Buffer
+			out := tt.output(NewOutput(&bb, zerolog.TraceLevel, 10))
+			assert.NoError(t, out.ProcessLine(line))
+			assert.Equal(t, "\x1b[90m--:--:--.---_---_---\x1b[0m \x1b[32mINF\x1b[0m _main.go:1\x1b[36m > \x1b[0m "+tt.want+"\n", bb.String())
+		})
+	}
+}
+
+func TestOutput_ProcessLine_Deterministic(t *testing.T) {
+	const line = `{"level":"debug","b":1,"a":2,"d":3,"c":4,"f":5,"e":6,"message":"msg"}`
+	var first bytes.Buffer
+	out := NewOutput(&first, zerolog.TraceLevel, 10)
+	assert.NoError(t, out.ProcessLine(line))
+	for i := 0; i < 20; i++ {
+		var bb bytes.Buffer
+		out := NewOutput(&bb, zerolog.TraceLevel, 10)
+		assert.NoError(t, out.ProcessLine(line))
+		assert.Equal(t, first.String(), bb.String())
+	}
+}
+
+func TestOutput_ProcessLine_Highlights(t *testing.T) {
+	const line = `{"level":"info","tenant":"acme","caller":"main.go:1","message":"request timeout after timeout"}`
+
+	tests := []struct {
+		name  string
+		rules []HighlightRule
+		want  string
+	}{
+		{
+			name:  "no match",
+			rules: []HighlightRule{{Field: "tenant", Value: "other", Record: true, Style: theme.Style{Fg: theme.Cyan}}},
+			want:  "\x1b[90m--:--:--.---_---_---\x1b[0m \x1b[32mINF\x1b[0m _main.go:1\x1b[36m > \x1b[0m request timeout after timeout \x1b[34mtenant=\x1b[0macme\n",
+		},
+		{
+			name:  "whole record",
+			rules: []HighlightRule{{Field: "tenant", Value: "acme", Record: true, Style: theme.Style{Fg: theme.Cyan}}},
+			want:  "\x1b[36m\x1b[90m--:--:--.---_---_---\x1b[0m\x1b[36m \x1b[32mINF\x1b[0m\x1b[36m _main.go:1\x1b[36m > \x1b[0m\x1b[36m request timeout after timeout \x1b[34mtenant=\x1b[0m\x1b[36macme\x1b[0m\n",
+		},
+		{
+			name:  "message pattern",
+			rules: []HighlightRule{{Pattern: "time(out)?", Style: theme.Style{Bg: theme.Red}}},
+			want:  "\x1b[90m--:--:--.---_---_---\x1b[0m \x1b[32mINF\x1b[0m _main.go:1\x1b[36m > \x1b[0m request \x1b[41mtimeout\x1b[0m after \x1b[41mtimeout\x1b[0m \x1b[34mtenant=\x1b[0macme\n",
+		},
+		{
+			name:  "field value",
+			rules: []HighlightRule{{Field: "tenant", Pattern: "^ac", Style: theme.Style{Bold: true}}, {Pattern: "[", Style: theme.Style{Bold: true}}},
+			want:  "\x1b[90m--:--:--.---_---_---\x1b[0m \x1b[32mINF\x1b[0m _main.go:1\x1b[36m > \x1b[0m request timeout after timeout \x1b[34mtenant=\x1b[0m\x1b[1macme\x1b[0m\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bb bytes.Buffer
+			out := NewOutput(&bb, zerolog.TraceLevel, 10).WithHighlightRules(tt.rules...)
+			assert.NoError(t, out.ProcessLine(line))
+			assert.Equal(t, tt.want, bb.String())
+		})
+	}
+}
+
+func TestOutput_ProcessLine_FieldMapping(t *testing.T) {
+	mapping := FieldMapping{Level: "lvl", Message: "msg", Time: "ts", Caller: "src"}
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "mapped fields",
+			line: `{"lvl":"warn","src":"main.go:1","msg":"hello","user":"bob"}`,
+			want: "\x1b[90m--:--:--.---_---_---\x1b[0m \x1b[31mWRN\x1b[0m _main.go:1\x1b[36m > \x1b[0m hello \x1b[34muser=\x1b[0mbob\n",
+		},
+		{
+			name: "mapped field shadows the default one",
+			line: `{"lvl":"info","message":"original","msg":"mapped"}`,
+			want: "\x1b[90m--:--:--.---_---_---\x1b[0m \x1b[32mINF\x1b[0m __________\x1b[36m > \x1b[0m mapped\n",
+		},
+		{
+			name: "numeric timestamp",
+			line: `{"lvl":"info","ts":1650743275.5,"msg":"epoch"}`,
+			want: time.Unix(1650743275, 5e8).Format("15:04:05.000_000_000"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bb bytes.Buffer
+			out := NewOutput(&bb, zerolog.TraceLevel, 10).WithFieldMapping(mapping)
+			assert.NoError(t, out.ProcessLine(tt.line))
+			assert.Contains(t, bb.String(), tt.want)
+		})
+	}
+}
+
+func TestOutput_ProcessLine_Filter(t *testing.T) {
+	line := `{"level":"info","time":"2022-05-01T10:00:00Z","message":"hello","user":"bob","port":8080}`
+	ts := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		filter  RecordFilter
+		printed bool
+	}{
+		{name: "zero filter", printed: true},
+		{name: "pattern", filter: RecordFilter{Pattern: regexp.MustCompile(`"user":"bob"`)}, printed: true},
+		{name: "pattern not matching", filter: RecordFilter{Pattern: regexp.MustCompile(`alice`)}},
+		{name: "fields", filter: RecordFilter{Fields: map[string]string{"user": "bob", "port": "8080"}}, printed: true},
+		{name: "field value differs", filter: RecordFilter{Fields: map[string]string{"user": "alice"}}},
+		{name: "missing field", filter: RecordFilter{Fields: map[string]string{"host": "bob"}}},
+		{name: "since inclusive", filter: RecordFilter{Since: ts}, printed: true},
+		{name: "until exclusive", filter: RecordFilter{Until: ts}},
+		{name: "interval", filter: RecordFilter{Since: ts.Add(-time.Hour), Until: ts.Add(time.Hour)}, printed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bb bytes.Buffer
+			out := NewOutput(&bb, zerolog.TraceLevel, 10).WithFilter(tt.filter)
+			assert.NoError(t, out.ProcessLine(line))
+			assert.Equal(t, tt.printed, bb.Len() > 0)
+		})
+	}
+}
+
+func TestRecordFilter_MatchRaw(t *testing.T) {
+	assert.True(t, RecordFilter{}.MatchRaw("panic: oops"))
+	assert.True(t, RecordFilter{Pattern: regexp.MustCompile("oops")}.MatchRaw("panic: oops"))
+	assert.False(t, RecordFilter{Pattern: regexp.MustCompile("nope")}.MatchRaw("panic: oops"))
+	assert.False(t, RecordFilter{Fields: map[string]string{"a": "b"}}.MatchRaw("panic: oops"))
+	assert.False(t, RecordFilter{Since: time.Now()}.MatchRaw("panic: oops"))
+}
+
+func TestParseFilterTime(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	ts, err := ParseFilterTime("", now)
+	require.NoError(t, err)
+	assert.True(t, ts.IsZero())
+
+	ts, err = ParseFilterTime("90m", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-90*time.Minute), ts)
+
+	ts, err = ParseFilterTime("2022-04-30T08:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 4, 30, 8, 0, 0, 0, time.UTC), ts.UTC())
+
+	ts, err = ParseFilterTime("2022-04-30", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 4, 30, 0, 0, 0, 0, time.Local), ts)
+
+	_, err = ParseFilterTime("yesterday", now)
+	assert.EqualError(t, err, `invalid time "yesterday", expected RFC 3339 time, date (2006-01-02) or duration (e.g. 1h30m)`)
+}
+
+func TestParseFilterFields(t *testing.T) {
+	fields, err := ParseFilterFields("user=bob,status=500,empty=")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"user": "bob", "status": "500", "empty": ""}, fields)
+
+	_, err = ParseFilterFields("user")
+	assert.EqualError(t, err, `invalid field filter "user", expected name=value`)
+}
+
+func TestOutput_ProcessLine_Redaction(t *testing.T) {
+	const line = `{"level":"info","password":"hunter2","caller":"main.go:1","message":"login of bob@example.com"}`
+	var bb bytes.Buffer
+	out := NewOutput(&bb, zerolog.TraceLevel, 10).WithRedaction(redact.Default())
+	assert.NoError(t, out.ProcessLine(line))
+	assert.Equal(t, "\x1b[90m--:--:--.---_---_---\x1b[0m \x1b[32mINF\x1b[0m _main.go:1\x1b[36m > \x1b[0m login of [REDACTED] \x1b[34mpassword=\x1b[0m[REDACTED]\n", bb.String())
+
+	bb.Reset()
+	out = out.WithRedaction(nil)
+	assert.NoError(t, out.ProcessLine(line))
+	assert.Contains(t, bb.String(), "login of bob@example.com")
+	assert.Contains(t, bb.String(), "hunter2")
+}
+
+func TestOutput_ProcessLine_Stack(t *testing.T) {
+	const stack = `[{"func":"github.com/acme/shop/orders.(*Service).Place","file":"/src/orders/service.go","line":42},` +
+		`{"func":"net/http.HandlerFunc.ServeHTTP","file":"/go/src/net/http/server.go","line":2084}]`
+	tests := []struct {
+		name    string
+		line    string
+		modules []string
+		want    string
+	}{
+		{
+			name: "own module guessed",
+			line: `{"level":"error","caller":"main.go:1","message":"failed","error":"boom","stack":` + stack + `}`,
+			want: "--:--:--.---_---_--- ERR _main.go:1 >  failed error=\x1b[1mboom\x1b[0m\n" +
+				"    \x1b[1mgithub.com/acme/shop/orders.(*Service).Place  /src/orders/service.go:42\x1b[0m\n" +
+				"    net/http.HandlerFunc.ServeHTTP                /go/src/net/http/server.go:2084\n",
+		},
+		{
+			name:    "own modules set",
+			line:    `{"level":"error","caller":"main.go:1","message":"failed","stack":` + stack + `}`,
+			modules: []string{"net/http"},
+			want: "--:--:--.---_---_--- ERR _main.go:1 >  failed\n" +
+				"    github.com/acme/shop/orders.(*Service).Place  /src/orders/service.go:42\n" +
+				"    \x1b[1mnet/http.HandlerFunc.ServeHTTP                /go/src/net/http/server.go:2084\x1b[0m\n",
+		},
+		{
+			name: "pkgerrors frames",
+			line: `{"level":"error","caller":"main.go:1","message":"failed","stack":[{"func":"Place","line":"42","source":"service.go"}]}`,
+			want: "--:--:--.---_---_--- ERR _main.go:1 >  failed\n" +
+				"    \x1b[1mPlace  service.go:42\x1b[0m\n",
+		},
+		{
+			name: "text trace",
+			line: `{"level":"error","caller":"main.go:1","message":"failed","stack":"goroutine 1 [running]:\nmain.main()"}`,
+			want: "--:--:--.---_---_--- ERR _main.go:1 >  failed\n" +
+				"    goroutine 1 [running]:\n" +
+				"    main.main()\n",
+		},
+		{
+			name: "unknown format printed as a field",
+			line: `{"level":"error","caller":"main.go:1","message":"failed","stack":42}`,
+			want: "--:--:--.---_---_--- ERR _main.go:1 >  failed stack=42\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bb bytes.Buffer
+			th := theme.Monochrome()
+			th.Levels.Error = theme.Style{}
+			out := NewOutput(&bb, zerolog.TraceLevel, 10).WithTheme(th).WithStackModules(tt.modules...)
+			require.NoError(t, out.ProcessLine(tt.line))
+			assert.Equal(t, tt.want, bb.String())
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	const record = `{"level":"info","caller":"main.go:1","message":"started","port":8080}` + "\n"
+	const printed = "--:--:--.---_---_--- INF _main.go:1 >  started port=8080\n"
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{
+			name:   "record",
+			writes: []string{record},
+			want:   printed,
+		},
+		{
+			name:   "record split across writes",
+			writes: []string{record[:10], record[10:40], record[40:]},
+			want:   printed,
+		},
+		{
+			name:   "several records in one write",
+			writes: []string{record + record},
+			want:   printed + printed,
+		},
+		{
+			name:   "non-JSON lines passed through",
+			writes: []string{"panic: boom\n", record, "{not json}\n"},
+			want:   "panic: boom\n" + printed + "{not json}\n",
+		},
+		{
+			name:   "incomplete line printed by Flush",
+			writes: []string{record[:len(record)-1]},
+			want:   printed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bb bytes.Buffer
+			w := NewOutputWriter(NewOutput(&bb, zerolog.TraceLevel, 10).WithTheme(theme.Monochrome()))
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				require.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+			require.NoError(t, w.Flush())
+			assert.Equal(t, tt.want, bb.String())
+		})
+	}
+}
+
+func TestOutput_ProcessLine_Options(t *testing.T) {
+	lines := []string{
+		`{"level":"info","time":"2022-05-22T11:59:05.123456789+02:00","caller":"internal/api/server.go:42","message":"first"}`,
+		`{"level":"info","time":"2022-05-22T11:59:06.373456789+02:00","caller":"internal/api/server.go:43","message":"second"}`,
+		`{"level":"info","caller":"internal/api/server.go:44","message":"no time"}`,
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "defaults",
+			opts: Options{CallerWidth: 10},
+			want: []string{
+				"11:59:05.123_456_789 INF ...r.go:42 >  first",
+				"11:59:06.373_456_789 INF ...r.go:43 >  second",
+				"--:--:--.---_---_--- INF ...r.go:44 >  no time",
+			},
+		},
+		{
+			name: "caller basename",
+			opts: Options{CallerWidth: 12, CallerBasename: true},
+			want: []string{
+				"11:59:05.123_456_789 INF server.go:42 >  first",
+				"11:59:06.373_456_789 INF server.go:43 >  second",
+				"--:--:--.---_---_--- INF server.go:44 >  no time",
+			},
+		},
+		{
+			name: "date and time in UTC",
+			opts: Options{CallerWidth: 12, CallerBasename: true, TimestampFormat: TimestampDateTime, Location: time.UTC},
+			want: []string{
+				"2022-05-22 09:59:05.123 INF server.go:42 >  first",
+				"2022-05-22 09:59:06.373 INF server.go:43 >  second",
+				"---------- --:--:--.--- INF server.go:44 >  no time",
+			},
+		},
+		{
+			name: "RFC 3339 in a fixed zone",
+			opts: Options{CallerWidth: 12, CallerBasename: true, TimestampFormat: TimestampRFC3339, Location: time.FixedZone("+01:00", 3600)},
+			want: []string{
+				"2022-05-22T10:59:05.123456789+01:00 INF server.go:42 >  first",
+				"2022-05-22T10:59:06.373456789+01:00 INF server.go:43 >  second",
+				"----------T--:--:--.---------+--:-- INF server.go:44 >  no time",
+			},
+		},
+		{
+			name: "epoch",
+			opts: Options{CallerWidth: 12, CallerBasename: true, TimestampFormat: TimestampEpoch},
+			want: []string{
+				"1653213545.123456789 INF server.go:42 >  first",
+				"1653213546.373456789 INF server.go:43 >  second",
+				"----------.--------- INF server.go:44 >  no time",
+			},
+		},
+		{
+			name: "relative to the previous record",
+			opts: Options{CallerWidth: 12, CallerBasename: true, Relative: RelativeToPrevious},
+			want: []string{
+				"11:59:05.123_456_789  INF server.go:42 >  first",
+				"+00:00:01.250_000_000 INF server.go:43 >  second",
+				"--:--:--.---_---_---  INF server.go:44 >  no time",
+			},
+		},
+		{
+			name: "relative to the anchor",
+			opts: Options{
+				CallerWidth:    12,
+				CallerBasename: true,
+				Relative:       RelativeToAnchor,
+				Anchor:         time.Date(2022, 5, 22, 10, 0, 5, 0, time.UTC),
+			},
+			want: []string{
+				"-00:00:59.876_543_211 INF server.go:42 >  first",
+				"-00:00:58.626_543_211 INF server.go:43 >  second",
+				"--:--:--.---_---_---  INF server.go:44 >  no time",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bb bytes.Buffer
+			out := NewOutput(&bb, zerolog.TraceLevel, 0).WithTheme(theme.Monochrome()).WithOptions(tt.opts)
+			for _, line := range lines {
+				require.NoError(t, out.ProcessLine(line))
+			}
+			assert.Equal(t, strings.Join(tt.want, "\n")+"\n", bb.String())
+		})
+	}
+}
+
+func TestOutput_ProcessLine_RelativeAlignment(t *testing.T) {
+	page := []string{
+		`{"level":"info","time":"2022-05-22T11:59:05.123456789+02:00","message":"first"}`,
+		`{"level":"info","time":"2022-05-22T11:59:06.373456789+02:00","message":"second"}`,
+		`{"level":"info","message":"no time"}`,
+		`{"level":"info","time":"2022-05-22T13:00:00+02:00","message":"later"}`,
+	}
+	for tf := range timestampFormatNames {
+		for _, rt := range []RelativeTime{RelativeOff, RelativeToPrevious, RelativeToAnchor} {
+			t.Run(tf.String()+"/"+rt.String(), func(t *testing.T) {
+				var bb bytes.Buffer
+				out := NewOutput(&bb, zerolog.TraceLevel, 0).WithTheme(theme.Monochrome()).WithOptions(Options{
+					TimestampFormat: tf,
+					Relative:        rt,
+					Anchor:          time.Date(2022, 5, 22, 10, 0, 0, 0, time.UTC),
+				})
+				for _, line := range page {
+					require.NoError(t, out.ProcessLine(line))
+				}
+				printed := strings.Split(strings.TrimSuffix(bb.String(), "\n"), "\n")
+				require.Len(t, printed, len(page))
+				for _, line := range printed {
+					assert.Equal(t, strings.Index(printed[0], " INF "), strings.Index(line, " INF "), line)
+				}
+			})
+		}
+	}
+}
+
+func TestOutput_Observe(t *testing.T) {
+	var bb bytes.Buffer
+	out := NewOutput(&bb, zerolog.TraceLevel, 10).WithTheme(theme.Monochrome()).WithOptions(Options{Relative: RelativeToPrevious})
+	out.Observe(`{"level":"info","time":"2022-05-22T11:59:05Z","message":"not printed"}`)
+	require.NoError(t, out.ProcessLine(`{"level":"info","time":"2022-05-22T11:59:07Z","message":"printed"}`))
+	assert.Equal(t, "+00:00:02.000_000_000 INF ______________________________ >  printed\n", bb.String())
+}
+
+func TestParseTimeZone(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantOffset int
+		wantNil    bool
+		wantErr    bool
+	}{
+		{name: "", wantNil: true},
+		{name: "UTC"},
+		{name: "+02:00", wantOffset: 2 * 3600},
+		{name: "-05:30", wantOffset: -(5*3600 + 30*60)},
+		{name: "+2", wantErr: true},
+		{name: "Mars/Olympus_Mons", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := ParseTimeZone(tt.name)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, loc)
+				return
+			}
+			_, offset := time.Date(2022, 1, 1, 0, 0, 0, 0, loc).Zone()
+			assert.Equal(t, tt.wantOffset, offset)
+		})
+	}
+}
+
+func TestNoLoggerDependency(t *testing.T) {
+	// the renderer must not pull in the logger, e.g. its global zerolog settings
+	for _, dir := range []string{".", "theme"} {
+		pkg, err := build.ImportDir(dir, 0)
+		require.NoError(t, err)
+		assert.NotContains(t, pkg.Imports, "github.com/matusvla/logviewer/pkg/logging", dir)
+	}
+	assert.Equal(t, logging.StackFieldName, stackFldName)
+	assert.Equal(t, logging.ModuleFieldName, moduleFldName)
+}
@@ -0,0 +1,125 @@
+package prettyprint
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+)
+
+// stackFldName is the field holding the stack traces written by the logging package (see logging.StackFieldName),
+// it is not imported from there so the renderer does not depend on the logger.
+const stackFldName = "stack"
+
+const stackIndent = "    "
+
+// stackFrame is a frame of the stack trace of a record
+type stackFrame struct {
+	fn, location string
+}
+
+// parseStack reads the stack trace of the record - the frames written by pkg/logging (func, file and line),
+// the frames of zerolog's pkgerrors marshaler (func, source and line) or a plain text trace. It returns false
+// for the values which cannot be rendered as a trace.
+func parseStack(value interface{}) ([]stackFrame, bool) {
+	switch v := value.(type) {
+	case string:
+		var frames []stackFrame
+		for _, line := range strings.Split(strings.TrimRight(v, "\n"), "\n") {
+			frames = append(frames, stackFrame{fn: strings.TrimSpace(line)})
+		}
+		return frames, len(frames) > 1
+	case []interface{}:
+		frames := make([]stackFrame, 0, len(v))
+		for _, item := range v {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			fn, _ := obj["func"].(string)
+			file, _ := obj["file"].(string)
+			if file == "" {
+				file, _ = obj["source"].(string)
+			}
+			location := file
+			switch line := obj["line"].(type) {
+			case json.Number:
+				location += ":" + line.String()
+			case string:
+				location += ":" + line
+			}
+			frames = append(frames, stackFrame{fn: fn, location: location})
+		}
+		return frames, len(frames) > 0
+	default:
+		return nil, false
+	}
+}
+
+// guessModule returns the presumed module path of the function, e.g. github.com/matusvla/logviewer for the functions
+// of its packages or main for the functions of the main package of a program built outside a module
+func guessModule(fn string) string {
+	parts := strings.Split(fn, "/")
+	if !strings.Contains(parts[0], ".") || len(parts) < 3 {
+		return strings.SplitN(parts[0], ".", 2)[0]
+	}
+	return strings.Join(parts[:3], "/")
+}
+
+// isOwnFrame tells whether the function belongs to one of the modules
+func isOwnFrame(fn string, modules []string) bool {
+	for _, m := range modules {
+		if fn == m || strings.HasPrefix(fn, m+"/") || strings.HasPrefix(fn, m+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// formatStack renders the stack trace as indented lines with the locations (file:line) aligned. The frames of
+// the modules are highlighted, if no modules are given, the module of the innermost frame is assumed to be
+// the application's own one.
+func formatStack(th theme.Theme, frames []stackFrame, modules []string) string {
+	if len(modules) == 0 && len(frames) > 0 && frames[0].location != "" {
+		modules = []string{guessModule(frames[0].fn)}
+	}
+	width := 0
+	for _, f := range frames {
+		if f.location != "" && len(f.fn) > width {
+			width = len(f.fn)
+		}
+	}
+	var sb strings.Builder
+	for i, f := range frames {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		text := f.fn
+		if f.location != "" {
+			text = fmt.Sprintf("%-*s  %s", width, f.fn, f.location)
+		}
+		style := th.StackFrame
+		if f.location != "" && isOwnFrame(f.fn, modules) {
+			style = th.StackOwnFrame
+		}
+		sb.WriteString(stackIndent)
+		sb.WriteString(th.Render(style, text))
+	}
+	return sb.String()
+}
+
+// takeStack removes the stack trace from the extra fields of the record and returns it rendered, so it is printed
+// below the record instead of among its fields. An empty string is returned if the record has no stack trace
+// which can be rendered.
+func (o *Output) takeStack(logItem *LogItem) string {
+	if _, hidden := o.ordering.hidden[stackFldName]; hidden {
+		return ""
+	}
+	frames, ok := parseStack(logItem.Extra[stackFldName])
+	if !ok {
+		return ""
+	}
+	delete(logItem.Extra, stackFldName)
+	return formatStack(o.theme, frames, o.stackModules)
+}
@@ -0,0 +1,153 @@
+package theme
+
+// moduleFieldName is the field holding the module of the records (see logging.ModuleFieldName),
+// it is not imported from there so the renderer does not depend on the logger.
+const moduleFieldName = "module"
+
+const (
+	DarkName       = "dark"
+	LightName      = "light"
+	SolarizedName  = "solarized"
+	MonochromeName = "monochrome"
+)
+
+var builtins = map[string]Theme{
+	DarkName:       Dark(),
+	LightName:      Light(),
+	SolarizedName:  Solarized(),
+	MonochromeName: Monochrome(),
+}
+
+// Dark is the default theme designed for terminals with dark background. It only uses the basic 16 colors.
+func Dark() Theme {
+	return Theme{
+		Name:            DarkName,
+		Timestamp:       Style{Fg: BrightBlack},
+		CallerSeparator: Style{Fg: Cyan},
+		FieldName:       Style{Fg: Blue},
+		ErrorFieldName:  Style{Fg: Cyan},
+		ErrorFieldValue: Style{Fg: Red},
+		StackFrame:      Style{Fg: BrightBlack},
+		StackOwnFrame:   Style{Fg: Yellow},
+		Levels: Levels{
+			Trace:   Style{Fg: Magenta},
+			Debug:   Style{Fg: Yellow},
+			Info:    Style{Fg: Green},
+			Warn:    Style{Fg: Red},
+			Error:   Style{Fg: Red, Bold: true},
+			Fatal:   Style{Fg: Red, Bold: true},
+			Panic:   Style{Fg: Red, Bold: true},
+			Unknown: Style{Bold: true},
+		},
+		Fields: map[string]FieldStyle{
+			moduleFieldName: {Name: Style{Fg: Magenta}},
+		},
+		UI: UI{
+			Accent:  Style{Fg: Black, Bg: Green, Bold: true},
+			Muted:   Style{Fg: White, Bold: true},
+			Error:   Style{Fg: Red, Bold: true},
+			Warning: Style{Fg: Yellow, Bold: true},
+			Success: Style{Fg: Green, Bold: true},
+		},
+	}
+}
+
+// Light is a theme for terminals with light background.
+func Light() Theme {
+	return Theme{
+		Name:            LightName,
+		Timestamp:       Style{Fg: Index(244)},
+		CallerSeparator: Style{Fg: Index(30)},
+		FieldName:       Style{Fg: Index(25)},
+		ErrorFieldName:  Style{Fg: Index(30)},
+		ErrorFieldValue: Style{Fg: Index(160)},
+		StackFrame:      Style{Fg: Index(244)},
+		StackOwnFrame:   Style{Fg: Index(130)},
+		Levels: Levels{
+			Trace:   Style{Fg: Index(90)},
+			Debug:   Style{Fg: Index(130)},
+			Info:    Style{Fg: Index(28)},
+			Warn:    Style{Fg: Index(166)},
+			Error:   Style{Fg: Index(160), Bold: true},
+			Fatal:   Style{Fg: Index(160), Bold: true},
+			Panic:   Style{Fg: Index(231), Bg: Index(160), Bold: true},
+			Unknown: Style{Bold: true},
+		},
+		Fields: map[string]FieldStyle{
+			moduleFieldName: {Name: Style{Fg: Index(90)}},
+		},
+		UI: UI{
+			Accent:  Style{Fg: Black, Bg: Index(114)},
+			Muted:   Style{Fg: Index(244)},
+			Error:   Style{Fg: Index(160), Bold: true},
+			Warning: Style{Fg: Index(130), Bold: true},
+			Success: Style{Fg: Index(28), Bold: true},
+		},
+	}
+}
+
+// Solarized is a theme using the accent colors of the Solarized palette (https://ethanschoonover.com/solarized/).
+func Solarized() Theme {
+	const (
+		base01  = 240
+		yellow  = 136
+		orange  = 166
+		red     = 160
+		magenta = 125
+		violet  = 61
+		blue    = 33
+		cyan    = 37
+		green   = 64
+	)
+	return Theme{
+		Name:            SolarizedName,
+		Timestamp:       Style{Fg: Index(base01)},
+		CallerSeparator: Style{Fg: Index(cyan)},
+		FieldName:       Style{Fg: Index(blue)},
+		ErrorFieldName:  Style{Fg: Index(cyan)},
+		ErrorFieldValue: Style{Fg: Index(red)},
+		StackFrame:      Style{Fg: Index(base01)},
+		StackOwnFrame:   Style{Fg: Index(yellow)},
+		Levels: Levels{
+			Trace:   Style{Fg: Index(violet)},
+			Debug:   Style{Fg: Index(yellow)},
+			Info:    Style{Fg: Index(green)},
+			Warn:    Style{Fg: Index(orange)},
+			Error:   Style{Fg: Index(red), Bold: true},
+			Fatal:   Style{Fg: Index(magenta), Bold: true},
+			Panic:   Style{Fg: Index(magenta), Bold: true},
+			Unknown: Style{Bold: true},
+		},
+		Fields: map[string]FieldStyle{
+			moduleFieldName: {Name: Style{Fg: Index(magenta)}},
+		},
+		UI: UI{
+			Accent:  Style{Fg: Index(230), Bg: Index(green)},
+			Muted:   Style{Fg: Index(base01)},
+			Error:   Style{Fg: Index(red), Bold: true},
+			Warning: Style{Fg: Index(yellow), Bold: true},
+			Success: Style{Fg: Index(green), Bold: true},
+		},
+	}
+}
+
+// Monochrome is a theme without any colors, only the important parts are emphasized in bold.
+func Monochrome() Theme {
+	return Theme{
+		Name: MonochromeName,
+		Levels: Levels{
+			Warn:    Style{Bold: true},
+			Error:   Style{Bold: true},
+			Fatal:   Style{Bold: true},
+			Panic:   Style{Bold: true},
+			Unknown: Style{Bold: true},
+		},
+		ErrorFieldValue: Style{Bold: true},
+		StackOwnFrame:   Style{Bold: true},
+		Fields:          map[string]FieldStyle{},
+		UI: UI{
+			Accent: Style{Bold: true},
+			Error:  Style{Bold: true},
+		},
+	}
+}
@@ -0,0 +1,193 @@
+package theme
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ColorMode describes how many colors the output terminal supports.
+type ColorMode int
+
+const (
+	ColorMode256 ColorMode = iota
+	ColorMode16
+	ColorModeNone
+)
+
+// DetectColorMode derives the color mode from the environment.
+// It honors the NO_COLOR convention (https://no-color.org) and falls back to 16 colors for terminals,
+// which do not advertise 256-color support in TERM or COLORTERM.
+func DetectColorMode() ColorMode {
+	if os.Getenv("NO_COLOR") != "" {
+		return ColorModeNone
+	}
+	term := os.Getenv("TERM")
+	switch {
+	case term == "dumb":
+		return ColorModeNone
+	case strings.Contains(term, "256color"):
+		return ColorMode256
+	}
+	switch os.Getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		return ColorMode256
+	}
+	return ColorMode16
+}
+
+// IsTerminal reports whether the file is a terminal (character device). The output redirected to a file or a pipe
+// should not contain any color escape sequences.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Color is a color from the xterm 256-color palette shifted by one, so that the zero value means the default color.
+// This is the same encoding as termbox (and thus gocui) uses in its 256-color output mode.
+type Color int
+
+const (
+	Default Color = iota
+	Black
+	Red
+	Green
+	Yellow
+	Blue
+	Magenta
+	Cyan
+	White
+	BrightBlack
+	BrightRed
+	BrightGreen
+	BrightYellow
+	BrightBlue
+	BrightMagenta
+	BrightCyan
+	BrightWhite
+)
+
+var colorNames = []string{
+	"default",
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"bright-black", "bright-red", "bright-green", "bright-yellow", "bright-blue", "bright-magenta", "bright-cyan", "bright-white",
+}
+
+// ColorNames returns the names of the basic colors which can be used instead of the palette indices.
+func ColorNames() []string {
+	return append([]string(nil), colorNames...)
+}
+
+// Index returns the color with the given index in the xterm 256-color palette.
+func Index(i uint8) Color {
+	return Color(i) + 1
+}
+
+// ParseColor parses either a color name (e.g. "red", "bright-black", "gray") or a palette index 0-255.
+func ParseColor(s string) (Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "gray" || s == "grey" {
+		return BrightBlack, nil
+	}
+	for i, name := range colorNames {
+		if s == name {
+			return Color(i), nil
+		}
+	}
+	i, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return Default, fmt.Errorf("unknown color %q", s)
+	}
+	return Index(uint8(i)), nil
+}
+
+func (c Color) String() string {
+	if c >= 0 && int(c) < len(colorNames) {
+		return colorNames[c]
+	}
+	return strconv.Itoa(int(c) - 1)
+}
+
+func (c *Color) UnmarshalYAML(value *yaml.Node) error {
+	color, err := ParseColor(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*c = color
+	return nil
+}
+
+func (c Color) MarshalYAML() (interface{}, error) {
+	return c.String(), nil
+}
+
+// basicRGB holds the RGB values of the basic 16 colors as used by xterm.
+var basicRGB = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// rgb returns the RGB value of the color in the xterm 256-color palette.
+func (c Color) rgb() [3]int {
+	i := int(c) - 1
+	switch {
+	case i < 16:
+		return basicRGB[i]
+	case i < 232: // 6x6x6 color cube
+		levels := [6]int{0, 95, 135, 175, 215, 255}
+		i -= 16
+		return [3]int{levels[i/36], levels[(i/6)%6], levels[i%6]}
+	default: // grayscale ramp
+		gray := 8 + (i-232)*10
+		return [3]int{gray, gray, gray}
+	}
+}
+
+// Downsample returns the closest color from the basic 16-color palette.
+func (c Color) Downsample() Color {
+	if c <= BrightWhite {
+		return c
+	}
+	rgb := c.rgb()
+	best, bestDistance := Default, -1
+	for i, basic := range basicRGB {
+		var distance int
+		for j := range rgb {
+			d := rgb[j] - basic[j]
+			distance += d * d
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = Index(uint8(i)), distance
+		}
+	}
+	return best
+}
+
+// sgr returns the SGR parameters setting this color as the foreground (or background) color.
+func (c Color) sgr(mode ColorMode, background bool) string {
+	if c == Default || mode == ColorModeNone {
+		return ""
+	}
+	if mode == ColorMode16 {
+		c = c.Downsample()
+	}
+	i := int(c) - 1
+	base := 30
+	if background {
+		base = 40
+	}
+	switch {
+	case i < 8:
+		return strconv.Itoa(base + i)
+	case i < 16:
+		return strconv.Itoa(base + 60 + i - 8)
+	default:
+		return fmt.Sprintf("%d;5;%d", base+8, i)
+	}
+}
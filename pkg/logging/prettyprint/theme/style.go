@@ -0,0 +1,76 @@
+package theme
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Style describes how a piece of text is rendered. The zero value renders the text unchanged.
+type Style struct {
+	Fg   Color `yaml:"fg,omitempty"`
+	Bg   Color `yaml:"bg,omitempty"`
+	Bold bool  `yaml:"bold,omitempty"`
+}
+
+// IsZero reports whether the style changes the rendered text at all.
+func (s Style) IsZero() bool {
+	return s == Style{}
+}
+
+// Render wraps the text into the ANSI escape sequences of the style.
+// Every attribute gets its own sequence, so that the output is understood by the gocui escape interpreter as well.
+func (s Style) Render(mode ColorMode, text string) string {
+	if mode == ColorModeNone {
+		return text
+	}
+	if fg := s.Fg.sgr(mode, false); fg != "" {
+		text = fmt.Sprintf("\x1b[%sm%s\x1b[0m", fg, text)
+	}
+	if bg := s.Bg.sgr(mode, true); bg != "" {
+		text = fmt.Sprintf("\x1b[%sm%s\x1b[0m", bg, text)
+	}
+	if s.Bold {
+		text = fmt.Sprintf("\x1b[1m%s\x1b[0m", text)
+	}
+	return text
+}
+
+// RenderNested renders the text like Render, but it also re-applies the style after every reset sequence inside the text,
+// so that the style is kept for the whole text even if some of its parts are already styled.
+func (s Style) RenderNested(mode ColorMode, text string) string {
+	opening := s.opening(mode)
+	if opening == "" {
+		return text
+	}
+	return opening + strings.ReplaceAll(text, "\x1b[0m", "\x1b[0m"+opening) + "\x1b[0m"
+}
+
+// opening returns the escape sequences switching the style on (in the same order as Render nests them).
+func (s Style) opening(mode ColorMode) string {
+	if mode == ColorModeNone {
+		return ""
+	}
+	var sb strings.Builder
+	if s.Bold {
+		sb.WriteString("\x1b[1m")
+	}
+	if bg := s.Bg.sgr(mode, true); bg != "" {
+		fmt.Fprintf(&sb, "\x1b[%sm", bg)
+	}
+	if fg := s.Fg.sgr(mode, false); fg != "" {
+		fmt.Fprintf(&sb, "\x1b[%sm", fg)
+	}
+	return sb.String()
+}
+
+// Merge returns the style s with all the non-zero attributes of the other style applied on top of it.
+func (s Style) Merge(other Style) Style {
+	if other.Fg != Default {
+		s.Fg = other.Fg
+	}
+	if other.Bg != Default {
+		s.Bg = other.Bg
+	}
+	s.Bold = s.Bold || other.Bold
+	return s
+}
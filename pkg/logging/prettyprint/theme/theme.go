@@ -0,0 +1,180 @@
+// Package theme contains the color schemes shared by the pretty printer and the terminal UI.
+package theme
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Theme is a color scheme used to render the log records and the terminal UI.
+type Theme struct {
+	Name string `yaml:"name,omitempty"`
+	// Base is the name of the built-in theme that a custom theme extends. It is only used when loading a theme file.
+	Base string `yaml:"base,omitempty"`
+	// ColorMode is not part of the scheme, it is derived from the terminal the theme is used in.
+	ColorMode ColorMode `yaml:"-"`
+
+	Timestamp       Style                 `yaml:"timestamp"`
+	Caller          Style                 `yaml:"caller"`
+	CallerSeparator Style                 `yaml:"callerSeparator"`
+	Message         Style                 `yaml:"message"`
+	FieldName       Style                 `yaml:"fieldName"`
+	FieldValue      Style                 `yaml:"fieldValue"`
+	ErrorFieldName  Style                 `yaml:"errorFieldName"`
+	ErrorFieldValue Style                 `yaml:"errorFieldValue"`
+	StackFrame      Style                 `yaml:"stackFrame"`
+	StackOwnFrame   Style                 `yaml:"stackOwnFrame"` // the frames of the application's own module
+	Levels          Levels                `yaml:"levels"`
+	Fields          map[string]FieldStyle `yaml:"fields,omitempty"`
+	UI              UI                    `yaml:"ui"`
+}
+
+// Levels holds the styles of the level labels.
+type Levels struct {
+	Trace   Style `yaml:"trace"`
+	Debug   Style `yaml:"debug"`
+	Info    Style `yaml:"info"`
+	Warn    Style `yaml:"warn"`
+	Error   Style `yaml:"error"`
+	Fatal   Style `yaml:"fatal"`
+	Panic   Style `yaml:"panic"`
+	Unknown Style `yaml:"unknown"`
+}
+
+// Style returns the style of the level with the given name (as used in the "level" field of the log records).
+func (l Levels) Style(level string) Style {
+	switch level {
+	case "trace":
+		return l.Trace
+	case "debug":
+		return l.Debug
+	case "info":
+		return l.Info
+	case "warn":
+		return l.Warn
+	case "error":
+		return l.Error
+	case "fatal":
+		return l.Fatal
+	case "panic":
+		return l.Panic
+	default:
+		return l.Unknown
+	}
+}
+
+// FieldStyle overrides the styles of a field with a specific name.
+type FieldStyle struct {
+	Name  Style `yaml:"name"`
+	Value Style `yaml:"value"`
+}
+
+// UI holds the colors of the terminal UI widgets.
+type UI struct {
+	Accent  Style `yaml:"accent"` // active menu item, selected frame and choices
+	Muted   Style `yaml:"muted"`  // secondary texts, e.g. global keybindings in the help bar
+	Error   Style `yaml:"error"`
+	Warning Style `yaml:"warning"`
+	Success Style `yaml:"success"`
+}
+
+// Render renders the text using the style and the color mode of the theme.
+func (t Theme) Render(style Style, text string) string {
+	return style.Render(t.ColorMode, text)
+}
+
+// FieldNameStyle returns the style of the given field name taking the per-field overrides into account.
+func (t Theme) FieldNameStyle(fldName string) Style {
+	if fs, ok := t.Fields[fldName]; ok && !fs.Name.IsZero() {
+		return fs.Name
+	}
+	return t.FieldName
+}
+
+// FieldValueStyle returns the style of the value of the given field taking the per-field overrides into account.
+func (t Theme) FieldValueStyle(fldName string) Style {
+	if fs, ok := t.Fields[fldName]; ok && !fs.Value.IsZero() {
+		return fs.Value
+	}
+	return t.FieldValue
+}
+
+// clone returns a deep copy of the theme, so that the built-in themes cannot be modified through the returned value.
+func (t Theme) clone() Theme {
+	fields := make(map[string]FieldStyle, len(t.Fields))
+	for k, v := range t.Fields {
+		fields[k] = v
+	}
+	t.Fields = fields
+	return t
+}
+
+// Names returns the names of all the built-in themes.
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the built-in theme with the given name.
+func Get(name string) (Theme, error) {
+	t, ok := builtins[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q, available themes are: %s", name, strings.Join(Names(), ", "))
+	}
+	return t.clone(), nil
+}
+
+// Load reads a custom theme from a YAML file. The theme extends the built-in theme named in its "base" key (dark by default).
+func Load(path string) (Theme, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, err
+	}
+	t, err := Parse(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), b)
+	if err != nil {
+		return Theme{}, fmt.Errorf("theme file %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Parse creates a theme with the given name from its YAML definition. See Load for the details.
+func Parse(name string, data []byte) (Theme, error) {
+	var header struct {
+		Base string `yaml:"base"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return Theme{}, err
+	}
+	if header.Base == "" {
+		header.Base = DarkName
+	}
+	t, err := Get(header.Base)
+	if err != nil {
+		return Theme{}, err
+	}
+	t.Name = name
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return Theme{}, err
+	}
+	return t, nil
+}
+
+// Resolve returns the built-in theme with the given name or, if there is no such theme, loads the theme from the file at the given path.
+func Resolve(nameOrPath string) (Theme, error) {
+	if _, ok := builtins[nameOrPath]; ok {
+		return Get(nameOrPath)
+	}
+	if _, err := os.Stat(nameOrPath); err != nil {
+		return Theme{}, fmt.Errorf("unknown theme %q, available themes are: %s (or a path to a theme file)", nameOrPath, strings.Join(Names(), ", "))
+	}
+	return Load(nameOrPath)
+}
@@ -0,0 +1,94 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStyle_Render(t *testing.T) {
+	tests := []struct {
+		name  string
+		style Style
+		mode  ColorMode
+		want  string
+	}{
+		{name: "zero style", style: Style{}, mode: ColorMode256, want: "text"},
+		{name: "basic color", style: Style{Fg: Red}, mode: ColorMode256, want: "\x1b[31mtext\x1b[0m"},
+		{name: "bright color", style: Style{Fg: BrightBlack}, mode: ColorMode16, want: "\x1b[90mtext\x1b[0m"},
+		{name: "bold", style: Style{Fg: Red, Bold: true}, mode: ColorMode256, want: "\x1b[1m\x1b[31mtext\x1b[0m\x1b[0m"},
+		{name: "256 colors", style: Style{Fg: Index(166), Bg: Index(17)}, mode: ColorMode256, want: "\x1b[48;5;17m\x1b[38;5;166mtext\x1b[0m\x1b[0m"},
+		{name: "256 colors downsampled", style: Style{Fg: Index(160)}, mode: ColorMode16, want: "\x1b[31mtext\x1b[0m"},
+		{name: "no color", style: Style{Fg: Red, Bold: true}, mode: ColorModeNone, want: "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.style.Render(tt.mode, "text"))
+		})
+	}
+}
+
+func TestColor_Downsample(t *testing.T) {
+	assert.Equal(t, Red, Red.Downsample())
+	assert.Equal(t, BrightRed, Index(196).Downsample())
+	assert.Equal(t, Green, Index(64).Downsample())
+	assert.Equal(t, BrightBlack, Index(240).Downsample())
+	assert.Equal(t, White, Index(255).Downsample())
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("bright-black")
+	assert.NoError(t, err)
+	assert.Equal(t, BrightBlack, c)
+	c, err = ParseColor("166")
+	assert.NoError(t, err)
+	assert.Equal(t, Index(166), c)
+	_, err = ParseColor("256")
+	assert.Error(t, err)
+	_, err = ParseColor("pinkish")
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+base: solarized
+timestamp: {fg: gray}
+levels:
+  error: {fg: 196, bold: true}
+fields:
+  tenant:
+    value: {fg: cyan}
+`), 0o600))
+
+	th, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "custom", th.Name)
+	assert.Equal(t, Style{Fg: BrightBlack}, th.Timestamp)
+	assert.Equal(t, Style{Fg: Index(196), Bold: true}, th.Levels.Error)
+	assert.Equal(t, Solarized().Levels.Info, th.Levels.Info)
+	assert.Equal(t, Style{Fg: Cyan}, th.FieldValueStyle("tenant"))
+	assert.Equal(t, Solarized().Fields["module"], th.Fields["module"])
+	_, ok := Solarized().Fields["tenant"]
+	assert.False(t, ok, "built-in theme must not be modified")
+
+	require.NoError(t, os.WriteFile(path, []byte(`timestamp: {fg: pinkish}`), 0o600))
+	_, err = Load(path)
+	assert.Error(t, err)
+}
+
+func TestDetectColorMode(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("COLORTERM", "")
+	t.Setenv("TERM", "xterm-256color")
+	assert.Equal(t, ColorMode256, DetectColorMode())
+	t.Setenv("TERM", "xterm")
+	assert.Equal(t, ColorMode16, DetectColorMode())
+	t.Setenv("COLORTERM", "truecolor")
+	assert.Equal(t, ColorMode256, DetectColorMode())
+	t.Setenv("NO_COLOR", "1")
+	assert.Equal(t, ColorModeNone, DetectColorMode())
+}
@@ -0,0 +1,96 @@
+package prettyprint
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint/theme"
+	"github.com/rs/zerolog"
+)
+
+// maxLineLength limits the buffered part of a line, the longer lines are passed through as they are
+const maxLineLength = 1024 * 1024
+
+// Writer is an io.Writer pretty-printing the JSON log records written to it by an Output, so it can be used directly
+// as an output of a zerolog.Logger or a slog.Handler, e.g. to get readable logs in development:
+//
+//	log, flushFn := logging.New("api", zerolog.DebugLevel, logging.WithWriter(prettyprint.NewWriter(os.Stderr)))
+//
+// The records can be split across several writes, every line is printed once it is complete. The lines which are
+// not JSON objects are passed through as they are. It is safe for concurrent use.
+type Writer struct {
+	mu      sync.Mutex
+	out     Output
+	pending []byte // the beginning of the line whose end was not written yet
+}
+
+// NewWriter returns the Writer pretty-printing the records of all the levels to the writer by the dark theme.
+// The colors are not used if the writer is a file which is not a terminal or if the environment disables them.
+func NewWriter(w io.Writer) *Writer {
+	th := theme.Dark()
+	th.ColorMode = theme.DetectColorMode()
+	if f, ok := w.(*os.File); ok && !theme.IsTerminal(f) {
+		th.ColorMode = theme.ColorModeNone
+	}
+	return NewOutputWriter(NewOutput(w, zerolog.TraceLevel, DefaultCallerWidth).WithTheme(th))
+}
+
+// NewOutputWriter returns the Writer printing the records by the Output, e.g. with a custom theme or the field mapping
+// of the records written by slog.JSONHandler. The lines which are not JSON objects are passed through to the writer
+// of the Output.
+func NewOutputWriter(out Output) *Writer {
+	return &Writer{out: out}
+}
+
+// Write prints all the complete lines of the written data, the rest is kept until its line is completed.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.printLine(w.pending[:i+1]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[i+1:]
+	}
+	if len(w.pending) > maxLineLength {
+		if _, err := w.out.writer.Write(w.pending); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[:0]
+	}
+	if len(w.pending) == 0 {
+		w.pending = nil // the buffer of the long lines is not retained
+	}
+	return len(p), nil
+}
+
+// Flush prints the incomplete line written last, the records written by zerolog and log/slog always end by a newline.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) == 0 {
+		return nil
+	}
+	err := w.printLine(w.pending)
+	w.pending = nil
+	return err
+}
+
+// printLine pretty-prints the line if it is a log record, it passes the line through otherwise
+func (w *Writer) printLine(line []byte) error {
+	record := bytes.TrimSpace(line)
+	if len(record) > 0 && record[0] == '{' {
+		if err := w.out.ProcessLine(string(record)); err == nil {
+			return nil
+		}
+	}
+	_, err := w.out.writer.Write(line)
+	return err
+}
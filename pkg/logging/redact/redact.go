@@ -0,0 +1,238 @@
+// Package redact masks the secrets and the personal data in the JSON log records - the values of the sensitive fields
+// (e.g. password) and the parts of the string values matching the patterns (e.g. JWTs or emails). It is used by
+// the pkg/logging loggers before the records are written and by prettyprint.Output when they are displayed.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholder replaces the redacted values.
+const Placeholder = "[REDACTED]"
+
+// Pattern matches the sensitive parts of the string values.
+type Pattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+	// valid filters the false positives of the matches, e.g. the numbers which are not credit card numbers
+	valid func(match string) bool
+}
+
+// The built-in patterns.
+var (
+	JWT         = Pattern{Name: "jwt", Regexp: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)}
+	BearerToken = Pattern{Name: "bearer", Regexp: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]+=*`)}
+	CreditCard  = Pattern{Name: "credit_card", Regexp: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: luhn}
+	Email       = Pattern{Name: "email", Regexp: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)}
+)
+
+// Builtin returns the built-in pattern with the name - jwt, bearer, credit_card or email.
+func Builtin(name string) (Pattern, error) {
+	for _, p := range BuiltinPatterns() {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Pattern{}, fmt.Errorf("unknown pattern %q, expected one of jwt, bearer, credit_card, email", name)
+}
+
+// BuiltinPatterns returns all the built-in patterns.
+func BuiltinPatterns() []Pattern {
+	return []Pattern{JWT, BearerToken, CreditCard, Email}
+}
+
+// DefaultFields are the names of the fields whose values are usually secret.
+var DefaultFields = []string{
+	"password", "passwd", "secret", "token", "access_token", "refresh_token", "api_key", "apikey", "authorization", "cookie",
+}
+
+// NewPattern compiles the custom pattern.
+func NewPattern(expr string) (Pattern, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Pattern{}, err
+	}
+	return Pattern{Name: expr, Regexp: re}, nil
+}
+
+// Redactor masks the values of the fields with the sensitive names (compared case-insensitively, in the nested objects
+// too) and the parts of the string values matching the patterns. It is safe for concurrent use.
+type Redactor struct {
+	fields   map[string]struct{}
+	patterns []Pattern
+}
+
+// New returns the Redactor of the fields and the patterns.
+func New(fields []string, patterns ...Pattern) *Redactor {
+	r := &Redactor{fields: make(map[string]struct{}, len(fields)), patterns: patterns}
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = struct{}{}
+	}
+	return r
+}
+
+// Default returns the Redactor of the DefaultFields and all the built-in patterns.
+func Default() *Redactor {
+	return New(DefaultFields, BuiltinPatterns()...)
+}
+
+// Fields returns the names of the redacted fields.
+func (r *Redactor) Fields() []string {
+	fields := make([]string, 0, len(r.fields))
+	for field := range r.fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Redact returns the record with the sensitive values replaced by the Placeholder, the record itself is returned
+// if there is nothing to redact. The order of the fields is kept. A line which is not a JSON object is redacted
+// by the patterns as a plain text.
+func (r *Redactor) Redact(line []byte) []byte {
+	if r == nil || !r.mayContain(line) {
+		return line
+	}
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 || trimmed[0] != '{' || !json.Valid(trimmed) {
+		return []byte(r.RedactString(string(line)))
+	}
+	var out bytes.Buffer
+	out.Grow(len(line))
+	out.Write(line[:bytes.IndexByte(line, '{')])
+	if err := r.value(&out, trimmed); err != nil {
+		return line // cannot happen for a valid JSON
+	}
+	out.Write(line[bytes.LastIndexByte(line, '}')+1:])
+	return out.Bytes()
+}
+
+// RedactString replaces the parts of the text matching the patterns by the Placeholder.
+func (r *Redactor) RedactString(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, p := range r.patterns {
+		s = p.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			if p.valid != nil && !p.valid(match) {
+				return match
+			}
+			return Placeholder
+		})
+	}
+	return s
+}
+
+// mayContain is the fast check whether the line contains anything to redact
+func (r *Redactor) mayContain(line []byte) bool {
+	for _, p := range r.patterns {
+		if p.Regexp.Match(line) {
+			return true
+		}
+	}
+	if len(r.fields) == 0 {
+		return false
+	}
+	lower := bytes.ToLower(line)
+	for field := range r.fields {
+		if bytes.Contains(lower, []byte(`"`+field+`"`)) {
+			return true
+		}
+	}
+	return false
+}
+
+// value writes the redacted JSON value
+func (r *Redactor) value(out *bytes.Buffer, raw []byte) error {
+	switch raw[0] {
+	case '{', '[':
+		return r.container(out, raw)
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		if redacted := r.RedactString(s); redacted != s {
+			writeString(out, redacted)
+			return nil
+		}
+	}
+	out.Write(raw)
+	return nil
+}
+
+// container writes the redacted JSON object or array
+func (r *Redactor) container(out *bytes.Buffer, raw []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	open, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	isObject := open == json.Delim('{')
+	out.WriteByte(raw[0])
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		redacted := false
+		if isObject {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			writeString(out, key.(string))
+			out.WriteByte(':')
+			_, redacted = r.fields[strings.ToLower(key.(string))]
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		if redacted {
+			writeString(out, Placeholder)
+			continue
+		}
+		if err := r.value(out, v); err != nil {
+			return err
+		}
+	}
+	if isObject {
+		out.WriteByte('}')
+	} else {
+		out.WriteByte(']')
+	}
+	return nil
+}
+
+// writeString writes the JSON string without escaping the HTML characters, as zerolog does
+func writeString(out *bytes.Buffer, s string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	out.Truncate(out.Len() - 1) // the newline added by Encode
+}
+
+// luhn checks the digits of the card number by the Luhn algorithm
+func luhn(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
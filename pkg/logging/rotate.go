@@ -0,0 +1,379 @@
+package logging
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// RotationFieldName is the key of the field of the marker records written by the RotatingFileWriter on the rotation.
+	// The last record of the rotated file has the value RotationEnd and the first record of the new file RotationStart.
+	RotationFieldName = "logrotate"
+	RotationEnd       = "end"
+	RotationStart     = "start"
+
+	// backupTimeFormat is the format of the time in the names of the rotated files, it sorts chronologically
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+)
+
+// RotatingFileConfig configures the RotatingFileWriter. Only the Path is required.
+type RotatingFileConfig struct {
+	// Path of the log file. The rotated files are kept next to it, named by the time of the rotation (app-2022-05-22T12-00-00.000.log).
+	Path string
+	// MaxSize is the size in bytes over which the file is rotated, the size is not limited if it is zero.
+	MaxSize int64
+	// Interval rotates the file whenever a multiple of the interval since the zero time passes,
+	// e.g. every hour or at every midnight UTC for 24h. The file is not rotated by time if it is zero.
+	Interval time.Duration
+	// MaxBackups is the number of the rotated files kept, all of them are kept if it is zero.
+	MaxBackups int
+	// MaxAge is the time after which the rotated files are removed, they are not removed by age if it is zero.
+	MaxAge time.Duration
+	// Gzip compresses the rotated files.
+	Gzip bool
+	// OnRotate is called with the path of the rotated file after every rotation.
+	OnRotate func(rotatedPath string)
+	// OnError is called with the errors of the rotation and of the compressing and removing of the rotated files,
+	// they are ignored if it is nil. The records are written to the current file if the rotation fails.
+	OnError func(error)
+}
+
+// RotatingFileWriter writes the records to a file, which is rotated by its size or by time.
+// The file is rotated by an atomic rename, so the tailing tools keep reading the rotated file to its end.
+// The rotation is marked by a record at the end of the rotated file and at the beginning of the new one
+// (see RotationFieldName), the viewer follows the new file when it reads the end marker.
+type RotatingFileWriter struct {
+	cfg    RotatingFileConfig
+	now    func() time.Time
+	create func(path string) (*os.File, error) // opens the log file for appending
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	period   time.Time // start of the time interval of the file
+	closed   bool
+	millCh   chan struct{} // triggers the compressing and removing of the rotated files
+	millDone chan struct{}
+}
+
+// NewRotatingFileWriter opens the log file for appending, it is created with its directory if it does not exist.
+// The writer must be closed to finish the compressing and removing of the rotated files.
+func NewRotatingFileWriter(cfg RotatingFileConfig) (*RotatingFileWriter, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("missing log file path")
+	}
+	if cfg.MaxSize < 0 || cfg.Interval < 0 || cfg.MaxBackups < 0 || cfg.MaxAge < 0 {
+		return nil, errors.New("the rotation limits must not be negative")
+	}
+	rw := &RotatingFileWriter{
+		cfg:      cfg,
+		now:      time.Now,
+		create:   openLogFile,
+		millCh:   make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	if err := rw.openExisting(); err != nil {
+		return nil, err
+	}
+	go rw.mill()
+	rw.millCh <- struct{}{} // the limits might have changed since the last run
+	return rw, nil
+}
+
+// openExisting opens the log file, its time interval is derived from its modification time.
+func (rw *RotatingFileWriter) openExisting() error {
+	if err := os.MkdirAll(filepath.Dir(rw.cfg.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := rw.create(rw.cfg.Path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	rw.file, rw.size, rw.period = f, info.Size(), rw.periodOf(rw.now())
+	if info.Size() > 0 {
+		rw.period = rw.periodOf(info.ModTime())
+	}
+	return nil
+}
+
+func openLogFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+}
+
+func (rw *RotatingFileWriter) periodOf(t time.Time) time.Time {
+	if rw.cfg.Interval == 0 {
+		return time.Time{}
+	}
+	return t.Truncate(rw.cfg.Interval)
+}
+
+// Write writes the record to the file, rotating the file first if the record does not fit in it or its time interval passed.
+func (rw *RotatingFileWriter) Write(b []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.closed {
+		return 0, errors.New("rotating file writer closed")
+	}
+	now := rw.now()
+	bySize := rw.cfg.MaxSize > 0 && rw.size > 0 && rw.size+int64(len(b)) > rw.cfg.MaxSize
+	byTime := rw.cfg.Interval > 0 && !rw.periodOf(now).Equal(rw.period)
+	if bySize || byTime {
+		if err := rw.rotate(now); err != nil {
+			rw.reportErr(err) // the record is written to the current file
+		}
+	}
+	n, err := rw.file.Write(b)
+	rw.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file immediately, e.g. on SIGHUP.
+func (rw *RotatingFileWriter) Rotate() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.closed {
+		return errors.New("rotating file writer closed")
+	}
+	return rw.rotate(rw.now())
+}
+
+// Close closes the file and waits for the compressing and removing of the rotated files.
+func (rw *RotatingFileWriter) Close() error {
+	rw.mu.Lock()
+	if rw.closed {
+		rw.mu.Unlock()
+		return nil
+	}
+	rw.closed = true
+	err := rw.file.Close()
+	close(rw.millCh)
+	rw.mu.Unlock()
+	<-rw.millDone
+	return err
+}
+
+// rotate renames the file, opens a new file starting with the start marker and closes the renamed one with the end marker.
+// If the new file cannot be created, the renamed file is renamed back and the records are still written to it.
+// The caller must hold the lock.
+func (rw *RotatingFileWriter) rotate(now time.Time) error {
+	rotatedPath := rw.backupPath(now)
+	if err := os.Rename(rw.cfg.Path, rotatedPath); err != nil {
+		rw.period = rw.periodOf(now) // the time based rotation is not retried before the next interval
+		return fmt.Errorf("log file rotation failed: %w", err)
+	}
+	f, err := rw.create(rw.cfg.Path)
+	if err != nil {
+		rw.period = rw.periodOf(now)
+		if renameErr := os.Rename(rotatedPath, rw.cfg.Path); renameErr != nil {
+			err = fmt.Errorf("%w, renaming back failed: %s", err, renameErr.Error())
+		}
+		return fmt.Errorf("log file creation failed: %w", err)
+	}
+	// the renamed file is still open
+	_, markerErr := rw.file.Write(rotationMarker(now, RotationEnd, "next", filepath.Base(rw.cfg.Path)))
+	closeErr := rw.file.Close()
+	rw.file, rw.size, rw.period = f, 0, rw.periodOf(now) // the markers do not count to the size
+	_, err = f.Write(rotationMarker(now, RotationStart, "previous", filepath.Base(rotatedPath)))
+
+	if rw.cfg.OnRotate != nil {
+		rw.cfg.OnRotate(rotatedPath)
+	}
+	select {
+	case rw.millCh <- struct{}{}:
+	default: // the mill is triggered already
+	}
+	for _, e := range []error{markerErr, closeErr, err} {
+		if e != nil {
+			return fmt.Errorf("log file rotation failed: %w", e)
+		}
+	}
+	return nil
+}
+
+// backupPath returns the path of the rotated file, e.g. logs/app-2022-05-22T12-00-00.000.log for logs/app.log.
+func (rw *RotatingFileWriter) backupPath(now time.Time) string {
+	dir, name := filepath.Split(rw.cfg.Path)
+	ext := filepath.Ext(name)
+	stamp := now.UTC().Format(backupTimeFormat)
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), stamp, ext))
+	for i := 1; fileExists(path) || fileExists(path+compressSuffix); i++ { // rotated several times within a millisecond
+		path = filepath.Join(dir, fmt.Sprintf("%s-%s.%d%s", strings.TrimSuffix(name, ext), stamp, i, ext))
+	}
+	return path
+}
+
+// rotationMarker returns the marker record with the field pointing to the other file of the rotation.
+func rotationMarker(now time.Time, event, fileKey, file string) []byte {
+	marker := fmt.Sprintf("{%q:%q,%q:%q,%q:%q,%q:%s,%q:%q}\n",
+		zerolog.LevelFieldName, zerolog.LevelInfoValue,
+		zerolog.TimestampFieldName, now.Format(TimeFormat),
+		RotationFieldName, event,
+		fileKey, mustMarshal(file),
+		zerolog.MessageFieldName, "log file rotated",
+	)
+	return []byte(marker)
+}
+
+func mustMarshal(s string) []byte {
+	b, _ := json.Marshal(s) // marshalling a string never fails
+	return b
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// mill compresses the rotated files and removes the ones over the limits whenever it is triggered.
+func (rw *RotatingFileWriter) mill() {
+	defer close(rw.millDone)
+	for range rw.millCh {
+		if err := rw.millOnce(); err != nil {
+			rw.reportErr(err)
+		}
+	}
+}
+
+type backup struct {
+	path string
+	time time.Time
+	seq  int // number of the rotation within the same millisecond
+}
+
+func (rw *RotatingFileWriter) millOnce() error {
+	backups, err := rw.backups()
+	if err != nil {
+		return err
+	}
+	var errs []string
+	var kept []backup
+	var cutoff time.Time
+	if rw.cfg.MaxAge > 0 {
+		cutoff = rw.now().Add(-rw.cfg.MaxAge)
+	}
+	for i, b := range backups { // from the newest
+		if (rw.cfg.MaxBackups > 0 && i >= rw.cfg.MaxBackups) || b.time.Before(cutoff) {
+			if err := os.Remove(b.path); err != nil {
+				errs = append(errs, err.Error())
+			}
+			continue
+		}
+		kept = append(kept, b)
+	}
+	if rw.cfg.Gzip {
+		for _, b := range kept {
+			if strings.HasSuffix(b.path, compressSuffix) {
+				continue
+			}
+			if err := compressFile(b.path); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("rotated log files processing failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// backups returns the rotated files sorted from the newest one.
+func (rw *RotatingFileWriter) backups() ([]backup, error) {
+	dir, name := filepath.Split(rw.cfg.Path)
+	if dir == "" {
+		dir = "."
+	}
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backup
+	for _, e := range entries {
+		stamp := strings.TrimSuffix(e.Name(), compressSuffix)
+		if e.IsDir() || !strings.HasPrefix(stamp, prefix) || !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		stamp = strings.TrimSuffix(strings.TrimPrefix(stamp, prefix), ext)
+		if len(stamp) < len(backupTimeFormat) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, stamp[:len(backupTimeFormat)])
+		if err != nil {
+			continue
+		}
+		var seq int
+		if rest := stamp[len(backupTimeFormat):]; rest != "" {
+			if seq, err = strconv.Atoi(strings.TrimPrefix(rest, ".")); err != nil || rest[0] != '.' {
+				continue
+			}
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, e.Name()), time: t, seq: seq})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].time.Equal(backups[j].time) {
+			return backups[i].time.After(backups[j].time)
+		}
+		return backups[i].seq > backups[j].seq
+	})
+	return backups, nil
+}
+
+// compressFile gzips the file into a temporary file, which is renamed to path.gz once complete, and removes the file.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmpPath := path + compressSuffix + ".tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = dst.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path+compressSuffix); err != nil {
+		return err
+	}
+	_ = src.Close()
+	return os.Remove(path)
+}
+
+func (rw *RotatingFileWriter) reportErr(err error) {
+	if rw.cfg.OnError != nil {
+		rw.cfg.OnError(err)
+	}
+}
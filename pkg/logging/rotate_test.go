@@ -0,0 +1,178 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rotateNow = time.Date(2022, 5, 22, 11, 59, 0, 0, time.UTC)
+
+// newTestRotatingWriter returns the writer with the time advanced by a second on every call
+func newTestRotatingWriter(t *testing.T, cfg RotatingFileConfig) *RotatingFileWriter {
+	t.Helper()
+	rw, err := NewRotatingFileWriter(cfg)
+	require.NoError(t, err)
+	now := rotateNow
+	rw.period = rw.periodOf(now)
+	rw.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return rw
+}
+
+func dirFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, compressSuffix) {
+		r, err = gzip.NewReader(f)
+		require.NoError(t, err)
+	}
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestRotatingFileWriter_size(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	var rotated []string
+	rw := newTestRotatingWriter(t, RotatingFileConfig{
+		Path:     path,
+		MaxSize:  40,
+		OnRotate: func(rotatedPath string) { rotated = append(rotated, rotatedPath) },
+	})
+	for _, msg := range []string{"1", "2", "3"} {
+		_, err := rw.Write([]byte(`{"level":"info","message":"` + msg + `"}` + "\n")) // 32 bytes
+		require.NoError(t, err)
+	}
+	require.NoError(t, rw.Close())
+
+	assert.Equal(t, []string{"app-2022-05-22T11-59-02.000.log", "app-2022-05-22T11-59-03.000.log", "app.log"}, dirFiles(t, dir))
+	assert.Equal(t, []string{filepath.Join(dir, "app-2022-05-22T11-59-02.000.log"), filepath.Join(dir, "app-2022-05-22T11-59-03.000.log")}, rotated)
+	assert.Equal(t, []string{
+		`{"level":"info","message":"1"}`,
+		`{"level":"info","time":"2022-05-22T11:59:02Z","logrotate":"end","next":"app.log","message":"log file rotated"}`,
+	}, readLines(t, rotated[0]))
+	assert.Equal(t, []string{
+		`{"level":"info","time":"2022-05-22T11:59:03Z","logrotate":"start","previous":"app-2022-05-22T11-59-03.000.log","message":"log file rotated"}`,
+		`{"level":"info","message":"3"}`,
+	}, readLines(t, path))
+}
+
+func TestRotatingFileWriter_interval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rw := newTestRotatingWriter(t, RotatingFileConfig{Path: path, Interval: time.Minute})
+	for i := 0; i < 3; i++ { // the first write is in the minute 11:59, the others in 12:00
+		_, err := rw.Write([]byte(`{"level":"info"}` + "\n"))
+		require.NoError(t, err)
+		rw.now = func() time.Time { return rotateNow.Add(time.Minute) }
+	}
+	require.NoError(t, rw.Close())
+	assert.Equal(t, []string{"app-2022-05-22T12-00-00.000.log", "app.log"}, dirFiles(t, dir))
+	assert.Len(t, readLines(t, path), 3, "the start marker and two records")
+}
+
+func TestRotatingFileWriter_creationFailed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	var errs []error
+	rw := newTestRotatingWriter(t, RotatingFileConfig{
+		Path:    path,
+		MaxSize: 40,
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+	rw.create = func(string) (*os.File, error) { return nil, os.ErrPermission }
+	for _, msg := range []string{"1", "2", "3"} {
+		_, err := rw.Write([]byte(`{"level":"info","message":"` + msg + `"}` + "\n"))
+		require.NoError(t, err, "the records are written to the current file")
+	}
+	require.Len(t, errs, 2)
+	assert.ErrorIs(t, errs[0], os.ErrPermission)
+	assert.Equal(t, []string{"app.log"}, dirFiles(t, dir), "the file is renamed back")
+
+	rw.create = openLogFile
+	require.NoError(t, rw.Rotate())
+	require.NoError(t, rw.Close())
+	assert.Equal(t, []string{"app-2022-05-22T11-59-04.000.log", "app.log"}, dirFiles(t, dir))
+	assert.Equal(t, []string{
+		`{"level":"info","message":"1"}`,
+		`{"level":"info","message":"2"}`,
+		`{"level":"info","message":"3"}`,
+		`{"level":"info","time":"2022-05-22T11:59:04Z","logrotate":"end","next":"app.log","message":"log file rotated"}`,
+	}, readLines(t, filepath.Join(dir, "app-2022-05-22T11-59-04.000.log")))
+}
+
+func TestRotatingFileWriter_backups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	// the old rotated file is removed by the age at the start
+	old := filepath.Join(dir, "app-2022-05-01T00-00-00.000.log")
+	require.NoError(t, os.WriteFile(old, []byte("old\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.log"), nil, 0o600))
+
+	rw, err := NewRotatingFileWriter(RotatingFileConfig{Path: path, MaxBackups: 2, MaxAge: 24 * time.Hour, Gzip: true})
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		_, err := rw.Write([]byte(`{"level":"info"}` + "\n"))
+		require.NoError(t, err)
+		require.NoError(t, rw.Rotate())
+	}
+	require.NoError(t, rw.Close())
+
+	files := dirFiles(t, dir)
+	require.Len(t, files, 4, files)
+	assert.Equal(t, []string{"app.log", "other.log"}, files[2:])
+	for _, name := range files[:2] {
+		assert.True(t, strings.HasSuffix(name, ".log.gz"), name)
+		lines := readLines(t, filepath.Join(dir, name))
+		assert.Contains(t, lines[len(lines)-1], `"logrotate":"end"`)
+	}
+}
+
+func TestNewRotatingFileWriter_invalid(t *testing.T) {
+	_, err := NewRotatingFileWriter(RotatingFileConfig{})
+	assert.Error(t, err)
+	_, err = NewRotatingFileWriter(RotatingFileConfig{Path: filepath.Join(t.TempDir(), "app.log"), MaxSize: -1})
+	assert.Error(t, err)
+}
+
+func TestNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	log, logFlushFn, err := NewFile("test", zerolog.InfoLevel, RotatingFileConfig{Path: path})
+	require.NoError(t, err)
+	log.Debug().Msg("filtered")
+	log.Info().Msg("written")
+	logFlushFn()
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"module":"test"`)
+	assert.Contains(t, lines[0], `"message":"written"`)
+}
@@ -0,0 +1,191 @@
+package logging
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SampledMessage is the message of the record summarizing the logs suppressed by the sampling (see WithSampling).
+const SampledMessage = "logs sampled"
+
+const (
+	defaultSamplingInterval = time.Second
+	defaultSamplingSummary  = 10 * time.Second
+	samplingMaxKeys         = 1000 // the messages above it are sampled together by the level
+)
+
+// Sampling configures the sampling of the logs of a level (see WithSampling). The counting (First and Thereafter)
+// and the rate limiting (Burst and Rate) can be combined, a log is written only if it passes both.
+type Sampling struct {
+	// First logs of every Interval are written, then only every Thereafter-th log or none if Thereafter is zero.
+	// The logs are not counted if both are zero.
+	First, Thereafter int
+	// Interval resets the counting, 1s by default.
+	Interval time.Duration
+	// Burst is the capacity of the token bucket limiting the logs, refilled by Rate tokens per second.
+	// Every written log takes a token, the logs are not rate limited if Burst is zero.
+	Burst int
+	Rate  float64
+	// ByMessage samples the logs with different messages separately, e.g. the logs of different hot loops.
+	ByMessage bool
+}
+
+type samplingKey struct {
+	level zerolog.Level
+	msg   string
+}
+
+type samplingState struct {
+	start  time.Time // of the counting interval
+	n      int
+	tokens float64
+	last   time.Time // of the last refill
+}
+
+// sample returns whether the log passes the sampling
+func (st *samplingState) sample(s Sampling, now time.Time) bool {
+	if s.First > 0 || s.Thereafter > 0 {
+		if now.Sub(st.start) >= s.Interval {
+			st.start, st.n = now, 0
+		}
+		st.n++
+		if st.n > s.First && (s.Thereafter == 0 || (st.n-s.First)%s.Thereafter != 0) {
+			return false
+		}
+	}
+	if s.Burst > 0 {
+		st.tokens = math.Min(float64(s.Burst), st.tokens+now.Sub(st.last).Seconds()*s.Rate)
+		st.last = now
+		if st.tokens < 1 {
+			return false
+		}
+		st.tokens--
+	}
+	return true
+}
+
+// sampler is the hook discarding the logs suppressed by the sampling, the suppressed logs are summarized
+// by a record written with the first log after the summary interval passes and by the flush function.
+type sampler struct {
+	rules           map[zerolog.Level]Sampling
+	summaryInterval time.Duration
+	log             zerolog.Logger // writing the summary
+	now             func() time.Time
+
+	mu          sync.Mutex
+	states      map[samplingKey]*samplingState
+	suppressed  map[samplingKey]uint64
+	since, last time.Time
+}
+
+func newSampler(rules map[zerolog.Level]Sampling, summaryInterval time.Duration, log zerolog.Logger) *sampler {
+	withDefaults := make(map[zerolog.Level]Sampling, len(rules))
+	for level, s := range rules {
+		if s.Interval <= 0 {
+			s.Interval = defaultSamplingInterval
+		}
+		withDefaults[level] = s
+	}
+	return &sampler{
+		rules:           withDefaults,
+		summaryInterval: summaryInterval,
+		log:             log,
+		now:             time.Now,
+		states:          make(map[samplingKey]*samplingState),
+		suppressed:      make(map[samplingKey]uint64),
+	}
+}
+
+// Run implements zerolog.Hook, the fatal and panic logs are never sampled.
+func (s *sampler) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	rule, ok := s.rules[level]
+	if !ok || level >= zerolog.FatalLevel {
+		return
+	}
+	now := s.now()
+	key := samplingKey{level: level}
+	if rule.ByMessage {
+		key.msg = msg
+	}
+
+	s.mu.Lock()
+	st, ok := s.states[key]
+	if !ok && len(s.states) >= samplingMaxKeys {
+		key.msg = ""
+		st, ok = s.states[key]
+	}
+	if !ok {
+		st = &samplingState{tokens: float64(rule.Burst), last: now}
+		s.states[key] = st
+	}
+	pass := st.sample(rule, now)
+	if !pass {
+		if s.since.IsZero() {
+			s.since = now
+		}
+		s.last = now
+		s.suppressed[key]++
+	}
+	summary := !s.since.IsZero() && s.summaryInterval > 0 && now.Sub(s.since) >= s.summaryInterval
+	s.mu.Unlock()
+
+	if summary {
+		s.writeSummary()
+	}
+	if !pass {
+		e.Discard()
+	}
+}
+
+// writeSummary writes the record summarizing the logs suppressed since the last summary, if there are any
+func (s *sampler) writeSummary() {
+	s.mu.Lock()
+	suppressed, since, last := s.suppressed, s.since, s.last
+	s.suppressed, s.since, s.last = make(map[samplingKey]uint64), time.Time{}, time.Time{}
+	s.mu.Unlock()
+	if since.IsZero() {
+		return
+	}
+
+	byLevel := make(map[zerolog.Level]uint64)
+	byMessage := make(map[string]uint64)
+	var msgs []string
+	var total uint64
+	for key, count := range suppressed {
+		byLevel[key.level] += count
+		total += count
+		if key.msg != "" {
+			if _, ok := byMessage[key.msg]; !ok {
+				msgs = append(msgs, key.msg)
+			}
+			byMessage[key.msg] += count
+		}
+	}
+	levels := zerolog.Dict()
+	for _, level := range dropLevels {
+		if count, ok := byLevel[level]; ok {
+			levels.Uint64(level.String(), count)
+		}
+	}
+	var messages *zerolog.Event
+	if len(msgs) > 0 {
+		sort.Strings(msgs)
+		messages = zerolog.Dict()
+		for _, msg := range msgs {
+			messages.Uint64(msg, byMessage[msg])
+		}
+	}
+	e := s.log.Info().
+		Dict("sampled", levels).
+		Uint64("sampled_total", total).
+		Str("sampled_since", since.Format(TimeFormat)).
+		Str("sampled_until", last.Format(TimeFormat))
+	if messages != nil {
+		e = e.Dict("sampled_messages", messages)
+	}
+	e.Msg(SampledMessage)
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamplingState_sample(t *testing.T) {
+	tests := []struct {
+		name     string
+		sampling Sampling
+		every    time.Duration // between the logs
+		want     string        // + for the written logs, - for the suppressed ones
+	}{
+		{name: "first", sampling: Sampling{First: 3, Interval: time.Hour}, want: "+++-------"},
+		{name: "first and thereafter", sampling: Sampling{First: 2, Thereafter: 3, Interval: time.Hour}, want: "++--+--+--"},
+		{name: "thereafter", sampling: Sampling{Thereafter: 4, Interval: time.Hour}, want: "---+---+--"},
+		{name: "interval resets the counting", sampling: Sampling{First: 2, Interval: 3 * time.Second}, every: time.Second, want: "++-++-++-+"},
+		{name: "burst", sampling: Sampling{Burst: 4}, want: "++++------"},
+		{name: "burst refilled by the rate", sampling: Sampling{Burst: 2, Rate: 0.5}, every: time.Second, want: "+++-+-+-+-"},
+		{name: "first and burst", sampling: Sampling{First: 3, Interval: time.Hour, Burst: 2}, want: "++--------"},
+		{name: "no limits", sampling: Sampling{Interval: time.Hour}, want: "++++++++++"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := rotateNow
+			st := &samplingState{tokens: float64(tt.sampling.Burst), last: now}
+			var got []byte
+			for i := 0; i < len(tt.want); i++ {
+				if st.sample(tt.sampling, now) {
+					got = append(got, '+')
+				} else {
+					got = append(got, '-')
+				}
+				now = now.Add(tt.every)
+			}
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestNew_sampling(t *testing.T) {
+	var out syncBuffer
+	log, logFlushFn := New("test", zerolog.DebugLevel,
+		WithWriter(&out),
+		WithSampling(zerolog.DebugLevel, Sampling{First: 2, Thereafter: 5, Interval: time.Hour, ByMessage: true}),
+		WithSampling(zerolog.FatalLevel, Sampling{Burst: 1}), // ignored
+	)
+	for i := 0; i < 12; i++ {
+		log.Debug().Int("i", i).Msg("hot")
+	}
+	for i := 0; i < 3; i++ {
+		log.Debug().Int("i", i).Msg("other")
+		log.Info().Int("i", i).Msg("not sampled")
+	}
+	logFlushFn()
+
+	lines := out.lines()
+	require.Len(t, lines, 4+2+3+1, "the sampled logs and the summary")
+	var summary struct {
+		Level           string            `json:"level"`
+		Module          string            `json:"module"`
+		Message         string            `json:"message"`
+		Sampled         map[string]uint64 `json:"sampled"`
+		SampledTotal    uint64            `json:"sampled_total"`
+		SampledMessages map[string]uint64 `json:"sampled_messages"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &summary))
+	assert.Equal(t, "info", summary.Level)
+	assert.Equal(t, "test", summary.Module)
+	assert.Equal(t, SampledMessage, summary.Message)
+	assert.Equal(t, map[string]uint64{"debug": 9}, summary.Sampled)
+	assert.Equal(t, uint64(9), summary.SampledTotal)
+	assert.Equal(t, map[string]uint64{"hot": 8, "other": 1}, summary.SampledMessages)
+}
+
+func TestSampler_summaryInterval(t *testing.T) {
+	var out syncBuffer
+	s := newSampler(map[zerolog.Level]Sampling{zerolog.InfoLevel: {First: 1, Interval: time.Hour}}, 5*time.Second, zerolog.New(&out))
+	now := rotateNow
+	s.now = func() time.Time { return now }
+	log := zerolog.New(&out).Hook(s)
+
+	for i := 0; i < 8; i++ { // the first log is written, the others are suppressed
+		log.Info().Int("i", i).Send()
+		now = now.Add(time.Second)
+	}
+	lines := out.lines()
+	require.Len(t, lines, 2)
+	assert.Equal(t, `{"level":"info","i":0}`, lines[0])
+	assert.Equal(t, `{"level":"info","sampled":{"info":6},"sampled_total":6,`+
+		`"sampled_since":"2022-05-22T11:59:01Z","sampled_until":"2022-05-22T11:59:06Z","message":"logs sampled"}`, lines[1],
+		"the summary is written with the first log after the interval")
+
+	s.writeSummary()
+	assert.Len(t, out.lines(), 3, "the rest is summarized")
+	s.writeSummary()
+	assert.Len(t, out.lines(), 3, "nothing to summarize")
+}
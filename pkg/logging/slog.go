@@ -0,0 +1,211 @@
+//go:build go1.21
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"github.com/rs/zerolog"
+)
+
+// SlogHandler is a slog.Handler writing the records in the same format as the loggers created by New - with the level,
+// module, caller, time and ts fields, so the records written by log/slog and by zerolog can be shown by the viewer
+// together. The attributes are written as the fields and the groups as the nested objects. The records are written
+// through the same buffered outputs as the logs of New, so all the options of New apply to the handler as well.
+type SlogHandler struct {
+	log    zerolog.Logger
+	module string
+	levels *LevelController
+	time   timestampHook
+	goas   []groupOrAttrs
+}
+
+// groupOrAttrs is either a group opened by WithGroup or the attributes added by WithAttrs
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewSlogHandler prepares a new SlogHandler, which like New does not drop any records. The flush function should be
+// deferred immediately after the call, so the program does not finish before all the buffered records are written.
+//
+//	handler, flushFn := logging.NewSlogHandler("api", zerolog.InfoLevel, logging.WithWriter(os.Stdout))
+//	defer flushFn()
+//	slog.SetDefault(slog.New(handler))
+func NewSlogHandler(module string, severity zerolog.Level, opts ...Option) (*SlogHandler, func()) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	log, flushFn := o.newLogger(module, severity, false)
+	return &SlogHandler{
+		log:    log,
+		module: module,
+		levels: o.levels,
+		time:   timestampHook{format: o.timeFormat},
+	}, flushFn
+}
+
+// NewDroppingSlogHandler is the variant of NewSlogHandler dropping the records of the full buffers like NewDropping.
+func NewDroppingSlogHandler(module string, severity zerolog.Level, opts ...Option) (*SlogHandler, func()) {
+	return NewSlogHandler(module, severity, append([]Option{WithOverflowPolicy(DropNewest)}, opts...)...)
+}
+
+// zerologLevel converts the slog level to the nearest lower zerolog level. The levels above slog.LevelError are
+// converted to zerolog.ErrorLevel, as the fatal logs shut the program down.
+func zerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
+		return zerolog.DebugLevel
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
+
+// Enabled reports whether the records of the level are written - the level must not be lower than the severity
+// of the handler (or the level of its module in the LevelController) and zerolog's global level.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	l := zerologLevel(level)
+	if l < h.log.GetLevel() || l < zerolog.GlobalLevel() {
+		return false
+	}
+	return h.levels == nil || l >= h.levels.ModuleLevel(h.module)
+}
+
+// Handle writes the record. The caller is taken from the program counter of the record and the time fields
+// are omitted for the records without the time.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	e := h.log.WithLevel(zerologLevel(r.Level))
+	if e == nil {
+		return nil
+	}
+	addAttrs(e, h.goas, r)
+	if r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.Str(zerolog.CallerFieldName, zerolog.CallerMarshalFunc(f.File, f.Line))
+	}
+	if !r.Time.IsZero() {
+		h.time.add(e, r.Time)
+		nanoTsHook{}.add(e, r.Time)
+	}
+	e.Msg(r.Message)
+	return nil
+}
+
+// WithAttrs returns the handler adding the attributes to all its records.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup returns the handler nesting the attributes added afterwards in the group.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *SlogHandler) with(goa groupOrAttrs) *SlogHandler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas), len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas = append(h2.goas, goa)
+	return &h2
+}
+
+// addAttrs adds the attributes of the handler and of the record to the event, the groups are added as the nested
+// dictionaries unless they are empty
+func addAttrs(e *zerolog.Event, goas []groupOrAttrs, r slog.Record) {
+	for i, goa := range goas {
+		if goa.group == "" {
+			for _, a := range goa.attrs {
+				addAttr(e, a)
+			}
+			continue
+		}
+		if !hasAttrs(goas[i+1:], r) {
+			return
+		}
+		d := zerolog.Dict()
+		addAttrs(d, goas[i+1:], r)
+		e.Dict(goa.group, d)
+		return
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(e, a)
+		return true
+	})
+}
+
+func hasAttrs(goas []groupOrAttrs, r slog.Record) bool {
+	if r.NumAttrs() > 0 {
+		return true
+	}
+	for _, goa := range goas {
+		if len(goa.attrs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func addAttr(e *zerolog.Event, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+		if a.Key == "" { // inlined group
+			for _, ga := range attrs {
+				addAttr(e, ga)
+			}
+			return
+		}
+		d := zerolog.Dict()
+		for _, ga := range attrs {
+			addAttr(d, ga)
+		}
+		e.Dict(a.Key, d)
+	case slog.KindString:
+		e.Str(a.Key, a.Value.String())
+	case slog.KindInt64:
+		e.Int64(a.Key, a.Value.Int64())
+	case slog.KindUint64:
+		e.Uint64(a.Key, a.Value.Uint64())
+	case slog.KindFloat64:
+		e.Float64(a.Key, a.Value.Float64())
+	case slog.KindBool:
+		e.Bool(a.Key, a.Value.Bool())
+	case slog.KindDuration:
+		e.Dur(a.Key, a.Value.Duration())
+	case slog.KindTime:
+		e.Time(a.Key, a.Value.Time())
+	default:
+		switch v := a.Value.Any().(type) {
+		case error:
+			if a.Key == zerolog.ErrorFieldName {
+				e.Err(v) // with the stack trace, see WithStackTrace
+			} else {
+				e.AnErr(a.Key, v)
+			}
+		default:
+			e.Interface(a.Key, v)
+		}
+	}
+}
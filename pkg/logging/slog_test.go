@@ -0,0 +1,104 @@
+//go:build go1.21
+
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogHandler_slogtest(t *testing.T) {
+	var out syncBuffer
+	h, flushFn := NewSlogHandler("test", zerolog.TraceLevel, WithWriter(&out))
+	results := func() []map[string]any {
+		flushFn()
+		recs := records(t, &out)
+		for _, rec := range recs {
+			// slogtest expects the message under the key of slog
+			rec[slog.MessageKey] = rec[zerolog.MessageFieldName]
+			delete(rec, zerolog.MessageFieldName)
+		}
+		return recs
+	}
+	assert.NoError(t, slogtest.TestHandler(h, results))
+}
+
+func TestSlogHandler_compatible(t *testing.T) {
+	var zlOut, slogOut syncBuffer
+	log, zlFlushFn := New("test", zerolog.TraceLevel, WithWriter(&zlOut))
+	h, slogFlushFn := NewSlogHandler("test", zerolog.TraceLevel, WithWriter(&slogOut))
+
+	log.Warn().
+		Str("user", "bob").
+		Int("attempt", 3).
+		Dict("req", zerolog.Dict().Str("method", "GET").Dur("latency", 1500*time.Millisecond)).
+		Err(errors.New("boom")).
+		Msg("retrying")
+	slog.New(h).WithGroup("req").With("method", "GET").Warn("retrying",
+		slog.Duration("latency", 1500*time.Millisecond),
+	)
+	slog.New(h).Warn("retrying", "user", "bob", "attempt", 3,
+		slog.Group("req", "method", "GET", slog.Duration("latency", 1500*time.Millisecond)),
+		"error", errors.New("boom"),
+	)
+	zlFlushFn()
+	slogFlushFn()
+
+	// the time fields and the line of the caller differ
+	varying := regexp.MustCompile(`"(time|ts)":("[^"]*"|\d+)|"caller":"[^"]*"`)
+	normalize := func(line string) string {
+		return varying.ReplaceAllString(line, `"$1":_`)
+	}
+	slogLines := slogOut.lines()
+	require.Len(t, slogLines, 2)
+	assert.Equal(t, normalize(zlOut.lines()[0]), normalize(slogLines[1]))
+	assert.Regexp(t, `^\{"level":"warn","module":"test","req":\{"method":"GET","latency":1500\},"caller":"[^"]*slog_test.go:\d+","time":"[^"]+","ts":\d+,"message":"retrying"\}$`, slogLines[0])
+}
+
+func TestSlogHandler_Enabled(t *testing.T) {
+	levels := NewLevelController(zerolog.InfoLevel)
+	tests := []struct {
+		name     string
+		severity zerolog.Level
+		opts     []Option
+		level    slog.Level
+		want     bool
+	}{
+		{name: "above severity", severity: zerolog.InfoLevel, level: slog.LevelWarn, want: true},
+		{name: "at severity", severity: zerolog.InfoLevel, level: slog.LevelInfo, want: true},
+		{name: "below severity", severity: zerolog.InfoLevel, level: slog.LevelDebug},
+		{name: "below debug is trace", severity: zerolog.TraceLevel, level: slog.LevelDebug - 4, want: true},
+		{name: "above error is error", severity: zerolog.ErrorLevel, level: slog.LevelError + 4, want: true},
+		{name: "level controller", severity: zerolog.TraceLevel, opts: []Option{WithLevelController(levels)}, level: slog.LevelDebug},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			h, flushFn := NewSlogHandler("test", tt.severity, append(tt.opts, WithWriter(&out))...)
+			defer flushFn()
+			assert.Equal(t, tt.want, h.Enabled(context.Background(), tt.level))
+		})
+	}
+}
+
+func TestSlogHandler_stackTrace(t *testing.T) {
+	var out syncBuffer
+	h, flushFn := NewSlogHandler("test", zerolog.TraceLevel, WithWriter(&out), WithStackTrace(zerolog.ErrorLevel))
+	slog.New(h).Error("failed")
+	flushFn()
+
+	recs := records(t, &out)
+	require.Len(t, recs, 1)
+	stack := stackOf(t, recs[0])
+	require.NotEmpty(t, stack)
+	assert.Equal(t, "github.com/matusvla/logviewer/pkg/logging.TestSlogHandler_stackTrace", stack[0].Func)
+}
@@ -0,0 +1,153 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// StackFieldName is the name of the field holding the stack trace of the log, see WithStackTrace.
+const StackFieldName = "stack"
+
+const maxStackDepth = 64
+
+// loggerFuncPrefixes are the prefixes of the functions writing the logs, which are not part of the stack traces
+var loggerFuncPrefixes = []string{
+	"github.com/rs/zerolog.",
+	"github.com/matusvla/logviewer/pkg/logging.(*SlogHandler).",
+	"log/slog.",
+}
+
+func isLoggerFunc(fn string) bool {
+	for _, prefix := range loggerFuncPrefixes {
+		if strings.HasPrefix(fn, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// StackFrame is a frame of the stack trace serialized in the StackFieldName field, the innermost frame is the first one.
+type StackFrame struct {
+	Func string `json:"func"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// StackTracer is implemented by the errors carrying the stack trace of the place where they were created, the program
+// counters are the ones returned by runtime.Callers. The errors of github.com/pkg/errors are supported as well.
+type StackTracer interface {
+	StackTrace() []uintptr
+}
+
+// frames resolves the program counters returned by runtime.Callers to the stack frames
+func frames(pcs []uintptr) []StackFrame {
+	if len(pcs) == 0 {
+		return nil
+	}
+	var result []StackFrame
+	it := runtime.CallersFrames(pcs)
+	for {
+		f, more := it.Next()
+		if f.Function != "" || f.File != "" {
+			result = append(result, StackFrame{Func: f.Function, File: f.File, Line: f.Line})
+		}
+		if !more {
+			return result
+		}
+	}
+}
+
+// callerStack returns the stack of the goroutine writing the log starting by the caller of the logger, i.e. without
+// the frames of the writer, of zerolog and of log/slog.
+func callerStack() []StackFrame {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+	all := frames(pcs[:n])
+	inLogger := false
+	for i, f := range all {
+		switch {
+		case isLoggerFunc(f.Func):
+			inLogger = true
+		case inLogger:
+			return all[i:]
+		}
+	}
+	return all // not written by zerolog, e.g. directly to the writer
+}
+
+// errorStack returns the stack trace carried by the innermost error of the chain supporting it - a StackTracer
+// or an error of github.com/pkg/errors, whose StackTrace method returns a slice of the program counters of its own type.
+func errorStack(err error) []uintptr {
+	var pcs []uintptr
+	for ; err != nil; err = errors.Unwrap(err) {
+		if st, ok := err.(StackTracer); ok {
+			pcs = st.StackTrace()
+			continue
+		}
+		m := reflect.ValueOf(err).MethodByName("StackTrace")
+		if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+			continue
+		}
+		out := m.Type().Out(0)
+		if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+			continue
+		}
+		st := m.Call(nil)[0]
+		pcs = make([]uintptr, st.Len())
+		for i := range pcs {
+			pcs[i] = uintptr(st.Index(i).Uint())
+		}
+	}
+	return pcs
+}
+
+// MarshalStack is the zerolog.ErrorStackMarshaler returning the stack trace carried by the error (see StackTracer)
+// as the StackFrames, nil if it carries none. It is set by WithStackTrace unless another marshaler is already set.
+func MarshalStack(err error) interface{} {
+	if f := frames(errorStack(err)); f != nil {
+		return f
+	}
+	return nil
+}
+
+var setStackMarshalerOnce sync.Once
+
+// setStackMarshaler makes zerolog use MarshalStack for the errors unless another marshaler is already set.
+func setStackMarshaler() {
+	setStackMarshalerOnce.Do(func() {
+		if zerolog.ErrorStackMarshaler == nil {
+			zerolog.ErrorStackMarshaler = MarshalStack
+		}
+	})
+}
+
+var stackKey = []byte(`"` + StackFieldName + `":`)
+
+// appendStack adds the stack of the goroutine writing the log to the encoded log, unless it already has one taken
+// from its error.
+func appendStack(b []byte) []byte {
+	line := bytes.TrimRight(b, "\n")
+	if len(line) < 2 || line[len(line)-1] != '}' || bytes.Contains(line, stackKey) {
+		return b
+	}
+	stack, err := json.Marshal(callerStack())
+	if err != nil {
+		return b
+	}
+	result := make([]byte, 0, len(b)+len(stackKey)+len(stack)+1)
+	result = append(result, line[:len(line)-1]...)
+	if len(line) > 2 {
+		result = append(result, ',')
+	}
+	result = append(result, stackKey...)
+	result = append(result, stack...)
+	result = append(result, '}')
+	return append(result, b[len(line):]...)
+}
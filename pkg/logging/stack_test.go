@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tracedError carries the stack of the place where it was created like the errors of github.com/pkg/errors
+type tracedError struct {
+	msg string
+	pcs []uintptr
+}
+
+func newTracedError(msg string) error {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs)
+	return &tracedError{msg: msg, pcs: pcs[:n]}
+}
+
+func (e *tracedError) Error() string { return e.msg }
+
+func (e *tracedError) StackTrace() []uintptr { return e.pcs }
+
+// pkgErrorsFrame and pkgErrorsError mimic the stack trace of github.com/pkg/errors
+type pkgErrorsFrame uintptr
+
+type pkgErrorsError struct{ pcs []uintptr }
+
+func (e pkgErrorsError) Error() string { return "pkg/errors" }
+
+func (e pkgErrorsError) StackTrace() []pkgErrorsFrame {
+	frames := make([]pkgErrorsFrame, len(e.pcs))
+	for i, pc := range e.pcs {
+		frames[i] = pkgErrorsFrame(pc)
+	}
+	return frames
+}
+
+func stackOf(t *testing.T, rec map[string]interface{}) []StackFrame {
+	t.Helper()
+	raw, ok := rec[StackFieldName]
+	if !ok {
+		return nil
+	}
+	b, err := json.Marshal(raw)
+	require.NoError(t, err)
+	var stack []StackFrame
+	require.NoError(t, json.Unmarshal(b, &stack))
+	return stack
+}
+
+func createdHere() error {
+	return newTracedError("created here")
+}
+
+func TestNew_stackTrace(t *testing.T) {
+	var out syncBuffer
+	log, flushFn := New("test", zerolog.TraceLevel, WithWriter(&out), WithStackTrace(zerolog.ErrorLevel))
+	log.Info().Msg("no stack")
+	log.Error().Msg("goroutine stack")
+	log.Error().Err(fmt.Errorf("wrapped: %w", createdHere())).Msg("error stack")
+	log.Warn().Err(createdHere()).Msg("error stack below the level")
+	flushFn()
+
+	recs := records(t, &out)
+	require.Len(t, recs, 4)
+	assert.Nil(t, stackOf(t, recs[0]))
+
+	stack := stackOf(t, recs[1])
+	require.NotEmpty(t, stack)
+	assert.Equal(t, "github.com/matusvla/logviewer/pkg/logging.TestNew_stackTrace", stack[0].Func)
+	assert.Contains(t, stack[0].File, "stack_test.go")
+	assert.Positive(t, stack[0].Line)
+
+	for _, rec := range recs[2:] {
+		stack := stackOf(t, rec)
+		require.NotEmpty(t, stack)
+		assert.Equal(t, "github.com/matusvla/logviewer/pkg/logging.createdHere", stack[0].Func)
+	}
+}
+
+func TestMarshalStack(t *testing.T) {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(1, pcs)
+	tests := []struct {
+		name      string
+		err       error
+		wantStack bool
+	}{
+		{
+			name: "error without stack",
+			err:  errors.New("plain"),
+		},
+		{
+			name:      "stack tracer",
+			err:       &tracedError{msg: "traced", pcs: pcs[:n]},
+			wantStack: true,
+		},
+		{
+			name:      "wrapped pkg/errors error",
+			err:       fmt.Errorf("wrapped: %w", pkgErrorsError{pcs: pcs[:n]}),
+			wantStack: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stack := MarshalStack(tt.err)
+			if !tt.wantStack {
+				assert.Nil(t, stack)
+				return
+			}
+			frames, ok := stack.([]StackFrame)
+			require.True(t, ok)
+			require.NotEmpty(t, frames)
+			assert.Equal(t, "github.com/matusvla/logviewer/pkg/logging.TestMarshalStack", frames[0].Func)
+		})
+	}
+}
+
+func TestAppendStack(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantStack bool
+	}{
+		{name: "record", line: `{"level":"error"}` + "\n", wantStack: true},
+		{name: "empty record", line: "{}\n", wantStack: true},
+		{name: "record with stack", line: `{"level":"error","stack":"from the error"}` + "\n"},
+		{name: "not a record", line: "plain text\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(appendStack([]byte(tt.line)))
+			if !tt.wantStack {
+				assert.Equal(t, tt.line, result)
+				return
+			}
+			var rec map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(result), &rec), result)
+			assert.Contains(t, rec, StackFieldName)
+			assert.Equal(t, byte('\n'), result[len(result)-1])
+		})
+	}
+}
@@ -1,107 +1,180 @@
 package logging
 
 import (
-	"bytes"
+	"context"
 	"io"
-	"os"
 	"sync"
 	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging/redact"
+	"github.com/rs/zerolog"
 )
 
 type parallelWriter struct {
 	io.Writer
-	writeCh     chan []byte
-	writeOnChFn func([]byte) (int, error)
-	write       func([]byte)
+	writeCh  chan entry
+	overflow OverflowPolicy
+	write    func([]byte) error
+	onError  func(error) // nil if the errors of the writing are ignored
+
+	counters []*DropCounter
+	drops    dropWindow
+	now      func() time.Time
 
-	mu            sync.RWMutex
-	fatalChecking bool
-	closed        bool
-	cancelFn      func()
-	wg            sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
 }
 
-func newPW(w io.Writer, shouldDrop bool) *parallelWriter {
+// entry is a log waiting in the buffer of a parallelWriter
+type entry struct {
+	level zerolog.Level
+	data  []byte
+}
 
-	writeCh := make(chan []byte, 100)
-	writeOnChFn := func(b []byte) (int, error) {
-		writeCh <- b
-		return len(b), nil
-	}
-	if shouldDrop {
-		writeOnChFn = func(b []byte) (int, error) {
-			select {
-			case writeCh <- b:
-				return len(b), nil
-			default:
-				return 0, nil
-			}
-		}
-	}
+type pwOptions struct {
+	bufferSize     int
+	overflow       OverflowPolicy
+	counters       []*DropCounter
+	reportInterval time.Duration // the drops are reported only when the writer is finalized if not positive
+	onError        func(error)
+}
 
-	writeFn := func(logMsg []byte) {
+func newPW(w io.Writer, o pwOptions) *parallelWriter {
+	writeFn := func(logMsg []byte) error {
 		_, err := w.Write(logMsg)
-		if err != nil {
-			panic(err)
-		}
+		return err
 	}
 
 	pw := parallelWriter{
-		Writer:      w,
-		writeCh:     writeCh,
-		write:       writeFn,
-		writeOnChFn: writeOnChFn,
+		Writer:   w,
+		writeCh:  make(chan entry, o.bufferSize),
+		overflow: o.overflow,
+		write:    writeFn,
+		onError:  o.onError,
+		counters: o.counters,
+		now:      time.Now,
 	}
 
 	// start worker - real write
 	pw.wg.Add(1)
 	go func() {
 		defer pw.wg.Done()
-		for logMsg := range pw.writeCh {
-			pw.write(logMsg)
+		var tick <-chan time.Time
+		if o.reportInterval > 0 {
+			ticker := time.NewTicker(o.reportInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case e, ok := <-pw.writeCh:
+				if !ok {
+					pw.reportDrops()
+					return
+				}
+				if err := pw.write(e.data); err != nil {
+					pw.failed(e.level, err)
+				}
+			case <-tick:
+				if len(pw.writeCh) <= cap(pw.writeCh)/2 { // the writer caught up
+					pw.reportDrops()
+				}
+			}
 		}
 	}()
 
 	return &pw
 }
 
-func (pw *parallelWriter) fatalShutdown() {
-	var fatalMsgPrefix = []byte("{\"level\":\"fatal\"")
-	pw.mu.Lock()
-	defer pw.mu.Unlock()
-	prevWriteFn := pw.write
-	pw.write = func(b []byte) {
-		prevWriteFn(b)
-		if bytes.Contains(b, fatalMsgPrefix) {
-			os.Exit(10)
-		}
-	}
-
-	prevWriteOnChFn := pw.writeOnChFn
-	pw.writeOnChFn = func(b []byte) (int, error) {
-		n, err := prevWriteOnChFn(b)
-		if bytes.Contains(b, fatalMsgPrefix) {
-			// this unblocks the lock from the Write method
-			pw.mu.RUnlock()
-			// giving the output writing part of the parallelWriter time to write the messages to output and os.Exit(1)
-			time.Sleep(5 * time.Second)
-			pw.mu.RLock() // just in case
-		}
-		return n, err
-	}
+func (pw *parallelWriter) Write(b []byte) (int, error) {
+	return pw.WriteLevel(zerolog.NoLevel, b)
 }
 
-func (pw *parallelWriter) Write(b []byte) (int, error) {
+// WriteLevel buffers the log, the overflow policy decides what happens when the buffer is full.
+func (pw *parallelWriter) WriteLevel(level zerolog.Level, b []byte) (int, error) {
 	pw.mu.RLock()
 	defer pw.mu.RUnlock()
 	if !pw.closed {
 		bc := make([]byte, len(b))
 		copy(bc, b)
-		return pw.writeOnChFn(bc) // async
+		if pw.enqueue(entry{level: level, data: bc}) { // async
+			return len(b), nil
+		}
 	}
 	return 0, nil
 }
 
+// enqueue puts the log in the buffer applying the overflow policy, it returns false if the log was dropped.
+func (pw *parallelWriter) enqueue(e entry) bool {
+	select {
+	case pw.writeCh <- e:
+		return true
+	default:
+	}
+
+	switch pw.overflow.kind {
+	case overflowDropNewest:
+	case overflowDropOldest:
+		for {
+			select {
+			case pw.writeCh <- e:
+				return true
+			case old := <-pw.writeCh:
+				pw.dropped(old.level)
+			}
+		}
+	case overflowDropBelowLevel:
+		if e.level >= pw.overflow.level {
+			pw.writeCh <- e
+			return true
+		}
+	case overflowBlockWithTimeout:
+		timer := time.NewTimer(pw.overflow.timeout)
+		defer timer.Stop()
+		select {
+		case pw.writeCh <- e:
+			return true
+		case <-timer.C:
+		}
+	default:
+		pw.writeCh <- e
+		return true
+	}
+	pw.dropped(e.level)
+	return false
+}
+
+// dropped counts the dropped log
+func (pw *parallelWriter) dropped(level zerolog.Level) {
+	for _, c := range pw.counters {
+		c.add(level)
+	}
+	pw.drops.add(level, pw.now())
+}
+
+// failed counts the log whose writing failed as dropped and reports the error, the output might recover later,
+// e.g. when some disk space is freed
+func (pw *parallelWriter) failed(level zerolog.Level, err error) {
+	pw.dropped(level)
+	pw.reportErr(err)
+}
+
+func (pw *parallelWriter) reportErr(err error) {
+	if pw.onError != nil {
+		pw.onError(err)
+	}
+}
+
+// reportDrops writes the record reporting the logs dropped since the last report, if there are any
+func (pw *parallelWriter) reportDrops() {
+	if report := pw.drops.report(pw.now()); report != nil {
+		if err := pw.write(report); err != nil {
+			pw.reportErr(err)
+		}
+	}
+}
+
 func (pw *parallelWriter) Finalize() {
 	pw.mu.Lock()
 	pw.closed = true
@@ -109,3 +182,89 @@ func (pw *parallelWriter) Finalize() {
 	close(pw.writeCh)
 	pw.wg.Wait()
 }
+
+// fanOut writes the logs to several outputs, each of them having its own parallelWriter,
+// so that a slow output (e.g. a NetworkWriter) does not hold back the others.
+type fanOut struct {
+	sinks    []levelSink
+	outputs  []io.Closer // closed after the parallelWriters are finalized
+	fatal    fatalOptions
+	sampler  *sampler // summarizes the suppressed logs before the outputs are finalized, nil without the sampling
+	redactor *redact.Redactor
+	// stackLevel is the lowest level of the logs getting the stack trace, zerolog.Disabled without the stack traces
+	stackLevel zerolog.Level
+
+	fatalOnce    sync.Once
+	finalizeOnce sync.Once
+}
+
+// levelSink is an output getting only the logs of its level and the higher levels
+type levelSink struct {
+	*parallelWriter
+	level zerolog.Level
+}
+
+// Write writes the log without a known level to all the outputs.
+func (fo *fanOut) Write(b []byte) (int, error) {
+	return fo.WriteLevel(zerolog.NoLevel, b)
+}
+
+// WriteLevel implements zerolog.LevelWriter, the logs are written only to the outputs with the level lower or equal to the log level.
+// A fatal log shuts the program down once it is written.
+func (fo *fanOut) WriteLevel(level zerolog.Level, b []byte) (int, error) {
+	n := len(b)
+	if level >= fo.stackLevel && level <= zerolog.PanicLevel {
+		b = appendStack(b)
+	}
+	b = fo.redactor.Redact(b)
+	for _, s := range fo.sinks {
+		if level >= s.level { // zerolog.NoLevel is higher than all the levels
+			_, _ = s.WriteLevel(level, b)
+		}
+	}
+	if level == zerolog.FatalLevel {
+		fo.fatalShutdown()
+	}
+	return n, nil
+}
+
+// fatalShutdown runs the shutdown functions, writes all the buffered logs to the outputs and closes them
+// and calls the exit function. The shutdown functions and the writing are limited by the shutdown timeout.
+func (fo *fanOut) fatalShutdown() {
+	fo.fatalOnce.Do(func() {
+		ctx, cancelFn := context.WithTimeout(context.Background(), fo.fatal.shutdownTimeout)
+		defer cancelFn()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for _, fn := range fo.fatal.shutdownFns {
+				fn(ctx)
+			}
+			fo.Finalize()
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		fo.fatal.exit(fo.fatal.exitCode)
+	})
+}
+
+func (fo *fanOut) Finalize() {
+	fo.finalizeOnce.Do(func() {
+		if fo.sampler != nil {
+			fo.sampler.writeSummary()
+		}
+		for _, output := range fo.outputs {
+			if nw, ok := output.(*NetworkWriter); ok {
+				nw.startClosing()
+			}
+		}
+		for _, s := range fo.sinks {
+			s.Finalize()
+		}
+		for _, output := range fo.outputs {
+			_ = output.Close()
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package logstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Follower delivers the records appended to the log file. It is created by the Store.Follow method.
+type Follower struct {
+	// C delivers the new records of the followed level filter. It is closed when the following ends.
+	C <-chan Record
+
+	mu  sync.Mutex
+	err error
+}
+
+// Err returns the error which ended the following, if any. It should be checked after the channel C is closed.
+func (f *Follower) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// Follow checks the log file for the new records every interval and delivers the ones of the level filter
+// until the context is done. The records already indexed are not delivered.
+// If the file is truncated, all the records written to it afterwards are delivered.
+func (s *Store) Follow(ctx context.Context, lvl zerolog.Level, interval time.Duration) *Follower {
+	ch := make(chan Record)
+	f := &Follower{C: ch}
+
+	s.mu.RLock()
+	generation, seen := s.generation, s.indexed
+	s.mu.RUnlock()
+
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := s.Update(); err != nil {
+				f.mu.Lock()
+				f.err = err
+				f.mu.Unlock()
+				return
+			}
+			records, err := s.newRecords(lvl, &generation, &seen)
+			if err != nil {
+				f.mu.Lock()
+				f.err = err
+				f.mu.Unlock()
+				return
+			}
+			for _, rec := range records {
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- rec:
+				}
+			}
+		}
+	}()
+	return f
+}
+
+// newRecords returns the records of the level filter starting at the offset seen or later and moves the offset behind them.
+func (s *Store) newRecords(lvl zerolog.Level, generation *int, seen *int64) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if *generation != s.generation {
+		*generation, *seen = s.generation, 0
+	}
+	spans := s.spans[lvl]
+	i := len(spans)
+	for i > 0 && spans[i-1].start >= *seen {
+		i--
+	}
+	records := make([]Record, 0, len(spans)-i)
+	for _, sp := range spans[i:] {
+		rec, err := s.read(sp)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	*seen = s.indexed
+	return records, nil
+}
@@ -0,0 +1,123 @@
+package logstore
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/rs/zerolog"
+)
+
+// Matcher selects the records returned by the Iterator.
+type Matcher func(Record) bool
+
+// MatchPattern returns the Matcher of the records containing the regular expression.
+func MatchPattern(re *regexp.Regexp) Matcher {
+	return func(rec Record) bool {
+		return re.Match(rec.Data)
+	}
+}
+
+// MatchFilter returns the Matcher of the records passing the filter. The keys of the record fields are renamed according to the mapping.
+func MatchFilter(filter prettyprint.RecordFilter, mapping prettyprint.FieldMapping) Matcher {
+	return func(rec Record) bool {
+		return filter.Match(string(rec.Data), mapping)
+	}
+}
+
+// Iterator goes through the records of a level filter one by one. The records indexed during the iteration
+// are visited as well when iterating forward.
+//
+//	it := store.Iter(zerolog.WarnLevel, 0, false)
+//	for it.Next() {
+//		rec := it.Record()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator struct {
+	store    *Store
+	ctx      context.Context
+	lvl      zerolog.Level
+	next     int
+	backward bool
+	matchers []Matcher
+
+	index  int
+	record Record
+	err    error
+}
+
+// Iter returns the Iterator over the records of the level filter starting with the record with the index from
+// and going towards the newer records (or the older ones if backward is set).
+func (s *Store) Iter(lvl zerolog.Level, from int, backward bool) *Iterator {
+	return &Iterator{store: s, lvl: lvl, next: from, backward: backward}
+}
+
+// WithContext makes the Iterator stop when the context is done. Err returns the context error then.
+func (it *Iterator) WithContext(ctx context.Context) *Iterator {
+	it.ctx = ctx
+	return it
+}
+
+// Filter makes the Iterator skip the records not selected by the matcher.
+func (it *Iterator) Filter(matcher Matcher) *Iterator {
+	it.matchers = append(it.matchers, matcher)
+	return it
+}
+
+// Next moves the Iterator to the next record. It returns false at the end of the records or on an error.
+func (it *Iterator) Next() bool {
+	for it.err == nil {
+		if it.ctx != nil && it.ctx.Err() != nil {
+			it.err = it.ctx.Err()
+			return false
+		}
+		rec, err := it.store.Get(it.lvl, it.next)
+		var noRecordsErr *NoRecordsError
+		if errors.Is(err, ErrOutOfRange) || errors.As(err, &noRecordsErr) {
+			return false
+		}
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.index = it.next
+		if it.backward {
+			it.next--
+		} else {
+			it.next++
+		}
+		if it.matches(rec) {
+			it.record = rec
+			return true
+		}
+	}
+	return false
+}
+
+func (it *Iterator) matches(rec Record) bool {
+	for _, matcher := range it.matchers {
+		if !matcher(rec) {
+			return false
+		}
+	}
+	return true
+}
+
+// Index returns the index of the current record.
+func (it *Iterator) Index() int {
+	return it.index
+}
+
+// Record returns the current record.
+func (it *Iterator) Record() Record {
+	return it.record
+}
+
+// Err returns the error which ended the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,290 @@
+// Package logstore indexes the log files with one JSON record per line (as written by zerolog) by the record levels
+// and provides the access to the records - by their index, by iterating over them, by searching them and by following
+// the records appended to the file.
+//
+// The records are indexed per level filter - the index of the level contains the records of the level and of all
+// the higher levels. The records are numbered from 0 (the oldest indexed record) within every level filter.
+package logstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultLevelKey   = "level"
+	defaultMaxRecords = 5000
+)
+
+var (
+	// ErrOutOfRange is returned when the requested record index is out of the indexed records.
+	ErrOutOfRange = errors.New("record index out of range")
+	// ErrNotFound is returned when no record matches the search.
+	ErrNotFound = errors.New("no matching record found")
+)
+
+// NoRecordsError is returned when there are no records of the requested level.
+type NoRecordsError struct {
+	Level zerolog.Level
+}
+
+func (e *NoRecordsError) Error() string {
+	return fmt.Sprintf("no records for the level %s", e.Level.String())
+}
+
+// Options are the settings of the Store. The zero value uses the defaults.
+type Options struct {
+	// LevelKey is the key of the record field holding the level, "level" by default.
+	LevelKey string
+	// MaxRecords is the number of the most recent records kept in the index of every level, 5000 by default.
+	// When the index grows over this limit, the oldest fifth of it is dropped.
+	MaxRecords int
+}
+
+// rotationEndMarker is a part of the end marker record written by logging.RotatingFileWriter
+var rotationEndMarker = []byte(`"logrotate":"end"`)
+
+// IsRotationEnd reports whether the line is the last record of a file rotated by logging.RotatingFileWriter,
+// after which the file on the path is a new one.
+func IsRotationEnd(line []byte) bool {
+	return bytes.Contains(line, rotationEndMarker)
+}
+
+// Record is a single log record of the file.
+type Record struct {
+	Level zerolog.Level
+	// Offset is the position of the record in the file.
+	Offset int64
+	// Data is the record itself without the trailing newline.
+	Data []byte
+}
+
+type span struct {
+	start, end int64 // end is the offset of the newline
+}
+
+// Store is an index of the records of a log file. The lines without a level (e.g. the ones which are not JSON) are not indexed.
+// It is safe for concurrent use.
+type Store struct {
+	path       string
+	levelRe    *regexp.Regexp
+	maxRecords int
+
+	mu         sync.RWMutex
+	file       *os.File
+	spans      map[zerolog.Level][]span
+	indexed    int64 // size of the indexed part of the file
+	generation int   // incremented whenever the file is indexed from the beginning again
+	rotated    bool  // the end marker of the rotation was indexed, the path is reopened once it is a new file
+}
+
+// Open opens the log file and indexes all its records.
+func Open(path string, opts Options) (*Store, error) {
+	if opts.LevelKey == "" {
+		opts.LevelKey = defaultLevelKey
+	}
+	if opts.MaxRecords <= 0 {
+		opts.MaxRecords = defaultMaxRecords
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{
+		path:       path,
+		levelRe:    regexp.MustCompile(`"` + regexp.QuoteMeta(opts.LevelKey) + `":"(trace|debug|info|warn|error|fatal|panic)"`),
+		maxRecords: opts.MaxRecords,
+		file:       f,
+		spans:      make(map[zerolog.Level][]span),
+	}
+	if _, err := s.Update(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path returns the path of the log file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Close closes the log file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// Update indexes the records appended to the file since the last update and returns the numbers of the new records
+// per level filter. Only the complete lines (terminated by a newline) are indexed.
+// If the file was truncated, it is indexed from the beginning again. The same applies if the file was rotated
+// by logging.RotatingFileWriter - the new file on the path is indexed, once it is created.
+func (s *Store) Update() (map[zerolog.Level]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rotated {
+		reopened, err := s.reopen()
+		if err != nil || !reopened {
+			return map[zerolog.Level]int{}, err
+		}
+	}
+	info, err := s.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < s.indexed {
+		s.spans = make(map[zerolog.Level][]span)
+		s.indexed = 0
+		s.generation++
+	}
+
+	newRecords := make(map[zerolog.Level]int)
+	r := bufio.NewReader(io.NewSectionReader(s.file, s.indexed, info.Size()-s.indexed))
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return newRecords, nil // an incomplete line is indexed once it is finished
+		}
+		if err != nil {
+			return newRecords, err
+		}
+		start := s.indexed
+		s.indexed += int64(len(line))
+		if IsRotationEnd(line) {
+			s.rotated = true
+		}
+		match := s.levelRe.FindSubmatch(line)
+		if match == nil {
+			continue
+		}
+		lvl, _ := zerolog.ParseLevel(string(match[1]))
+		for filterLvl := zerolog.TraceLevel; filterLvl <= lvl; filterLvl++ {
+			spans := append(s.spans[filterLvl], span{start: start, end: s.indexed - 1})
+			if len(spans) > s.maxRecords {
+				spans = append(spans[:0:0], spans[len(spans)-s.maxRecords*4/5:]...)
+			}
+			s.spans[filterLvl] = spans
+			newRecords[filterLvl]++
+		}
+	}
+}
+
+// reopen replaces the rotated file by the new file on the path, it returns false if the new file does not exist yet.
+// The caller holds the lock.
+func (s *Store) reopen() (bool, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	oldInfo, err := s.file.Stat()
+	if err != nil {
+		_ = f.Close()
+		return false, err
+	}
+	newInfo, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return false, err
+	}
+	if os.SameFile(oldInfo, newInfo) { // not renamed yet or the rotated file itself is viewed
+		_ = f.Close()
+		return false, nil
+	}
+	_ = s.file.Close()
+	s.file = f
+	s.spans = make(map[zerolog.Level][]span)
+	s.indexed = 0
+	s.generation++
+	s.rotated = false
+	return true, nil
+}
+
+// Len returns the number of the indexed records of the level filter.
+func (s *Store) Len(lvl zerolog.Level) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.spans[lvl])
+}
+
+// Get returns the i-th record of the level filter.
+func (s *Store) Get(lvl zerolog.Level, i int) (Record, error) {
+	records, err := s.Range(lvl, i, i+1)
+	if err != nil {
+		return Record{}, err
+	}
+	return records[0], nil
+}
+
+// Range returns the records of the level filter with the indices from the interval [from, to).
+func (s *Store) Range(lvl zerolog.Level, from, to int) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	spans := s.spans[lvl]
+	if len(spans) == 0 {
+		return nil, &NoRecordsError{Level: lvl}
+	}
+	if from < 0 || to > len(spans) || from >= to {
+		return nil, ErrOutOfRange
+	}
+	records := make([]Record, 0, to-from)
+	for _, sp := range spans[from:to] {
+		rec, err := s.read(sp)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// read reads the record of the span, the caller holds the lock.
+func (s *Store) read(sp span) (Record, error) {
+	data := make([]byte, sp.end-sp.start)
+	if _, err := s.file.ReadAt(data, sp.start); err != nil {
+		return Record{}, err
+	}
+	rec := Record{Offset: sp.start, Data: data}
+	if match := s.levelRe.FindSubmatch(data); match != nil {
+		rec.Level, _ = zerolog.ParseLevel(string(match[1]))
+	}
+	return rec, nil
+}
+
+// Search returns the index of the nearest record of the level filter matching the pattern, which follows
+// (or precedes if backward is set) the record with the index from. The index might point out of the records,
+// e.g. -1 to search from the first record forward. ErrNotFound is returned if no record matches.
+// The search stops with the context error when the context is done.
+func (s *Store) Search(ctx context.Context, re *regexp.Regexp, lvl zerolog.Level, from int, backward bool) (int, error) {
+	if n := s.Len(lvl); from > n {
+		from = n
+	}
+	if from < -1 {
+		from = -1
+	}
+	start := from + 1
+	if backward {
+		start = from - 1
+	}
+	it := s.Iter(lvl, start, backward).WithContext(ctx).Filter(MatchPattern(re))
+	if it.Next() {
+		return it.Index(), nil
+	}
+	if err := it.Err(); err != nil {
+		return 0, err
+	}
+	return 0, ErrNotFound
+}
@@ -0,0 +1,224 @@
+package logstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/matusvla/logviewer/pkg/logging/prettyprint"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Len(t *testing.T) {
+	s, err := Open("./testdata/test.log", Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	want := map[zerolog.Level]int{
+		zerolog.TraceLevel: 22,
+		zerolog.DebugLevel: 14,
+		zerolog.InfoLevel:  10,
+		zerolog.WarnLevel:  2,
+		zerolog.ErrorLevel: 1,
+		zerolog.FatalLevel: 0,
+		zerolog.PanicLevel: 0,
+	}
+	for lvl, n := range want {
+		assert.Equal(t, n, s.Len(lvl), lvl.String())
+	}
+}
+
+func TestStore_Range(t *testing.T) {
+	s, err := Open("./testdata/test.log", Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		lvl      zerolog.Level
+		from, to int
+		want     []string
+		err      error
+	}{
+		{
+			name: "warn records",
+			lvl:  zerolog.WarnLevel,
+			from: 0,
+			to:   2,
+			want: []string{"turning off gui due to context cancellation", "cui subsystem ended, cancelling context"},
+		},
+		{
+			name: "last record",
+			lvl:  zerolog.TraceLevel,
+			from: 21,
+			to:   22,
+			want: []string{"viewer ended"},
+		},
+		{name: "out of range", lvl: zerolog.TraceLevel, from: 21, to: 23, err: ErrOutOfRange},
+		{name: "negative index", lvl: zerolog.TraceLevel, from: -1, to: 1, err: ErrOutOfRange},
+		{name: "no records", lvl: zerolog.FatalLevel, from: 0, to: 1, err: &NoRecordsError{Level: zerolog.FatalLevel}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := s.Range(tt.lvl, tt.from, tt.to)
+			assert.Equal(t, tt.err, err)
+			require.Len(t, records, len(tt.want))
+			for i, rec := range records {
+				assert.Contains(t, string(rec.Data), `"message":"`+tt.want[i]+`"`)
+				assert.GreaterOrEqual(t, rec.Level, tt.lvl)
+			}
+		})
+	}
+}
+
+func TestStore_Search(t *testing.T) {
+	s, err := Open("./testdata/test.log", Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		pattern  string
+		lvl      zerolog.Level
+		from     int
+		backward bool
+		want     int
+		err      error
+	}{
+		{name: "forward", pattern: `"window":"About"`, lvl: zerolog.TraceLevel, from: 0, want: 3},
+		{name: "forward from before the first record", pattern: `"window":"About"`, lvl: zerolog.TraceLevel, from: -5, want: 3},
+		{name: "backward", pattern: `"window":"About"`, lvl: zerolog.TraceLevel, from: 21, backward: true, want: 14},
+		{name: "backward from behind the last record", pattern: `"window":"About"`, lvl: zerolog.TraceLevel, from: 100, backward: true, want: 14},
+		{name: "level filter", pattern: `"component":"backend"`, lvl: zerolog.WarnLevel, from: -1, want: 1},
+		{name: "not found", pattern: `"window":"About"`, lvl: zerolog.TraceLevel, from: 14, err: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, err := s.Search(context.Background(), regexp.MustCompile(tt.pattern), tt.lvl, tt.from, tt.backward)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.want, i)
+		})
+	}
+}
+
+func TestStore_Search_Cancelled(t *testing.T) {
+	s, err := Open("./testdata/test.log", Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.Search(ctx, regexp.MustCompile(`"window":"About"`), zerolog.TraceLevel, -1, false)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestIterator(t *testing.T) {
+	s, err := Open("./testdata/test.log", Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	filter := prettyprint.RecordFilter{Fields: map[string]string{"component": "cui"}}
+	it := s.Iter(zerolog.InfoLevel, s.Len(zerolog.InfoLevel)-1, true).Filter(MatchFilter(filter, prettyprint.FieldMapping{}))
+	var indices []int
+	for it.Next() {
+		assert.Contains(t, string(it.Record().Data), `"component":"cui"`)
+		indices = append(indices, it.Index())
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []int{4, 3}, indices)
+}
+
+func TestStore_Update(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"info","message":"a"}`+"\n"+`{"level":"warn","message":"b"`), 0o600))
+	s, err := Open(path, Options{MaxRecords: 5})
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, 1, s.Len(zerolog.TraceLevel), "incomplete line is not indexed")
+
+	appendFile(t, path, "}\nnot a record\n")
+	newRecords, err := s.Update()
+	require.NoError(t, err)
+	assert.Equal(t, map[zerolog.Level]int{zerolog.TraceLevel: 1, zerolog.DebugLevel: 1, zerolog.InfoLevel: 1, zerolog.WarnLevel: 1}, newRecords)
+	rec, err := s.Get(zerolog.WarnLevel, 0)
+	require.NoError(t, err)
+	assert.Equal(t, Record{Level: zerolog.WarnLevel, Offset: 31, Data: []byte(`{"level":"warn","message":"b"}`)}, rec)
+
+	for i := 0; i < 4; i++ {
+		appendFile(t, path, `{"level":"debug","message":"c"}`+"\n")
+	}
+	_, err = s.Update()
+	require.NoError(t, err)
+	assert.Equal(t, 4, s.Len(zerolog.TraceLevel), "oldest records are dropped")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"error","message":"d"}`+"\n"), 0o600))
+	_, err = s.Update()
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.Len(zerolog.TraceLevel), "truncated file is indexed again")
+}
+
+func TestStore_Follow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"info","message":"old"}`+"\n"), 0o600))
+	s, err := Open(path, Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := s.Follow(ctx, zerolog.WarnLevel, 10*time.Millisecond)
+	appendFile(t, path, `{"level":"debug","message":"skipped"}`+"\n"+`{"level":"error","message":"new"}`+"\n")
+
+	select {
+	case rec := <-f.C:
+		assert.Equal(t, `{"level":"error","message":"new"}`, string(rec.Data))
+	case <-time.After(time.Second):
+		t.Fatal("no record delivered")
+	}
+	cancel()
+	for range f.C {
+	}
+	assert.NoError(t, f.Err())
+}
+
+func TestStore_Update_rotated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"info","message":"old"}`+"\n"), 0o600))
+	s, err := Open(path, Options{})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// the file is rotated as by logging.RotatingFileWriter
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path+".1", `{"level":"info","logrotate":"end","next":"app.log","message":"log file rotated"}`+"\n")
+	newRecords, err := s.Update()
+	require.NoError(t, err)
+	assert.Equal(t, 1, newRecords[zerolog.TraceLevel], "the end marker is indexed")
+
+	newRecords, err = s.Update()
+	require.NoError(t, err)
+	assert.Empty(t, newRecords, "the new file does not exist yet")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"level":"info","logrotate":"start","previous":"app.log.1","message":"log file rotated"}`+"\n"+
+		`{"level":"warn","message":"new"}`+"\n"), 0o600))
+	newRecords, err = s.Update()
+	require.NoError(t, err)
+	assert.Equal(t, map[zerolog.Level]int{zerolog.TraceLevel: 2, zerolog.DebugLevel: 2, zerolog.InfoLevel: 2, zerolog.WarnLevel: 1}, newRecords)
+	rec, err := s.Get(zerolog.WarnLevel, 0)
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"warn","message":"new"}`, string(rec.Data))
+	assert.Equal(t, 2, s.Len(zerolog.TraceLevel), "only the new file is indexed")
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}